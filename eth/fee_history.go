@@ -0,0 +1,41 @@
+package eth
+
+// FeeHistory is the result of eth_feeHistory, describing the fees paid in a range of recent blocks.
+type FeeHistory struct {
+	// OldestBlock is the number of the first block in the range
+	OldestBlock Quantity `json:"oldestBlock"`
+
+	// BaseFeePerGas holds the base fee of every block in the range, followed by the base fee of the
+	// block after the newest one, so it has one more entry than GasUsedRatio
+	BaseFeePerGas []Quantity `json:"baseFeePerGas"`
+
+	// GasUsedRatio is gasUsed/gasLimit of every block in the range
+	GasUsedRatio []float64 `json:"gasUsedRatio"`
+
+	// Reward holds, for every block, the priority fee at each of the requested reward percentiles,
+	// weighted by gas used.  It is only present if percentiles were requested.
+	Reward [][]Quantity `json:"reward,omitempty"`
+
+	// EIP-4844 BaseFeePerBlobGas and BlobGasUsedRatio, which follow the same layout as their
+	// execution gas counterparts and are only returned by Cancun aware nodes
+	BaseFeePerBlobGas []Quantity `json:"baseFeePerBlobGas,omitempty"`
+	BlobGasUsedRatio  []float64  `json:"blobGasUsedRatio,omitempty"`
+}
+
+// NextBaseFeePerGas returns the base fee of the block following the range, or nil if there is none.
+func (f *FeeHistory) NextBaseFeePerGas() *Quantity {
+	if len(f.BaseFeePerGas) == 0 {
+		return nil
+	}
+
+	return &f.BaseFeePerGas[len(f.BaseFeePerGas)-1]
+}
+
+// NextBaseFeePerBlobGas returns the blob base fee of the block following the range, or nil if the node did not report one.
+func (f *FeeHistory) NextBaseFeePerBlobGas() *Quantity {
+	if len(f.BaseFeePerBlobGas) == 0 {
+		return nil
+	}
+
+	return &f.BaseFeePerBlobGas[len(f.BaseFeePerBlobGas)-1]
+}
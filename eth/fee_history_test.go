@@ -0,0 +1,43 @@
+package eth_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+)
+
+func TestFeeHistory_UnmarshalJSON(t *testing.T) {
+	payload := `{
+		"oldestBlock": "0x13f2a6e",
+		"baseFeePerGas": ["0x3b9aca00", "0x3b9aca01", "0x4190ab00"],
+		"gasUsedRatio": [0.5, 0.99],
+		"reward": [["0x5f5e100", "0x77359400"], ["0x0", "0x3b9aca00"]],
+		"baseFeePerBlobGas": ["0x1", "0x1", "0x2"],
+		"blobGasUsedRatio": [0.5, 1]
+	}`
+
+	history := eth.FeeHistory{}
+	require.NoError(t, json.Unmarshal([]byte(payload), &history))
+
+	require.Equal(t, uint64(0x13f2a6e), history.OldestBlock.UInt64())
+	require.Equal(t, []float64{0.5, 0.99}, history.GasUsedRatio)
+	require.Equal(t, uint64(2000000000), history.Reward[0][1].UInt64())
+	require.Equal(t, []float64{0.5, 1}, history.BlobGasUsedRatio)
+	require.Equal(t, eth.MustQuantity("0x4190ab00"), history.NextBaseFeePerGas())
+	require.Equal(t, eth.MustQuantity("0x2"), history.NextBaseFeePerBlobGas())
+
+	// pre-Cancun nodes leave out the blob fields, and the reward is omitted if no percentiles were requested
+	history = eth.FeeHistory{}
+	require.NoError(t, json.Unmarshal([]byte(`{"oldestBlock":"0x1","baseFeePerGas":["0x7","0x8"],"gasUsedRatio":[0]}`), &history))
+	require.Nil(t, history.Reward)
+	require.Nil(t, history.NextBaseFeePerBlobGas())
+
+	b, err := json.Marshal(&history)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"oldestBlock":"0x1","baseFeePerGas":["0x7","0x8"],"gasUsedRatio":[0]}`, string(b))
+
+	require.Nil(t, (&eth.FeeHistory{}).NextBaseFeePerGas())
+}
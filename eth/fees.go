@@ -0,0 +1,196 @@
+package eth
+
+import (
+	"errors"
+	"math/big"
+)
+
+// GasPerBlob is the blob gas used by every blob of an EIP-4844 transaction.
+const GasPerBlob = 1 << 17
+
+var (
+	// ErrNoBaseFee is returned when computing fees from a block that predates EIP-1559.
+	ErrNoBaseFee = errors.New("block has no base fee")
+
+	// ErrNoBlobGas is returned when computing blob fees from a block that predates EIP-4844.
+	ErrNoBlobGas = errors.New("block has no blob gas fields")
+)
+
+// FeeParams are the parameters of the EIP-1559 and EIP-4844 fee markets, which change between forks.
+type FeeParams struct {
+	// ElasticityMultiplier is the ratio of the gas limit to the gas target
+	ElasticityMultiplier uint64
+
+	// BaseFeeChangeDenominator bounds how much the base fee changes from one block to the next
+	BaseFeeChangeDenominator uint64
+
+	// TargetBlobGasPerBlock is the blob gas a block is expected to use, above which the blob base fee rises
+	TargetBlobGasPerBlock uint64
+
+	// BlobBaseFeeUpdateFraction controls how quickly the blob base fee reacts to excess blob gas
+	BlobBaseFeeUpdateFraction uint64
+
+	// MinBlobBaseFee is the lowest blob base fee, in wei
+	MinBlobBaseFee uint64
+}
+
+var (
+	// CancunFeeParams are the fee parameters of mainnet from the Cancun fork, with a target of 3 blobs per block.
+	CancunFeeParams = FeeParams{
+		ElasticityMultiplier:      2,
+		BaseFeeChangeDenominator:  8,
+		TargetBlobGasPerBlock:     3 * GasPerBlob,
+		BlobBaseFeeUpdateFraction: 3338477,
+		MinBlobBaseFee:            1,
+	}
+
+	// PragueFeeParams are the fee parameters of mainnet from the Prague fork, which raised the target to 6 blobs
+	// per block with EIP-7691.
+	PragueFeeParams = FeeParams{
+		ElasticityMultiplier:      2,
+		BaseFeeChangeDenominator:  8,
+		TargetBlobGasPerBlock:     6 * GasPerBlob,
+		BlobBaseFeeUpdateFraction: 5007716,
+		MinBlobBaseFee:            1,
+	}
+)
+
+// CalcNextBaseFee returns the base fee of the block following one with the given base fee, gas used and gas limit.
+func CalcNextBaseFee(baseFee *big.Int, gasUsed, gasLimit uint64, p FeeParams) *big.Int {
+	target := gasLimit / p.ElasticityMultiplier
+	if gasUsed == target || target == 0 {
+		return new(big.Int).Set(baseFee)
+	}
+
+	// the change is proportional to how far gas used was from the target, by at most 1/BaseFeeChangeDenominator
+	var delta uint64
+	if gasUsed > target {
+		delta = gasUsed - target
+	} else {
+		delta = target - gasUsed
+	}
+
+	change := new(big.Int).Mul(baseFee, new(big.Int).SetUint64(delta))
+	change.Quo(change, new(big.Int).SetUint64(target))
+	change.Quo(change, new(big.Int).SetUint64(p.BaseFeeChangeDenominator))
+
+	if gasUsed > target {
+		// an increase is always at least 1 wei
+		if change.Sign() == 0 {
+			change.SetUint64(1)
+		}
+		return change.Add(baseFee, change)
+	}
+
+	next := new(big.Int).Sub(baseFee, change)
+	if next.Sign() < 0 {
+		next.SetUint64(0)
+	}
+	return next
+}
+
+// CalcExcessBlobGas returns the excess blob gas of the block following one with the given excess blob gas and blob gas used.
+func CalcExcessBlobGas(excessBlobGas, blobGasUsed uint64, p FeeParams) uint64 {
+	if excessBlobGas+blobGasUsed < p.TargetBlobGasPerBlock {
+		return 0
+	}
+
+	return excessBlobGas + blobGasUsed - p.TargetBlobGasPerBlock
+}
+
+// CalcBlobBaseFee returns the blob base fee of a block with the given excess blob gas.
+func CalcBlobBaseFee(excessBlobGas uint64, p FeeParams) *big.Int {
+	return fakeExponential(
+		new(big.Int).SetUint64(p.MinBlobBaseFee),
+		new(big.Int).SetUint64(excessBlobGas),
+		new(big.Int).SetUint64(p.BlobBaseFeeUpdateFraction),
+	)
+}
+
+// fakeExponential approximates factor * e ** (numerator / denominator) using a Taylor expansion, as specified by EIP-4844
+func fakeExponential(factor, numerator, denominator *big.Int) *big.Int {
+	output := new(big.Int)
+	accum := new(big.Int).Mul(factor, denominator)
+	for i := int64(1); accum.Sign() > 0; i++ {
+		output.Add(output, accum)
+
+		accum.Mul(accum, numerator)
+		accum.Quo(accum, denominator)
+		accum.Quo(accum, big.NewInt(i))
+	}
+
+	return output.Quo(output, denominator)
+}
+
+// NextBaseFee returns the base fee of the block following b.
+func (b *Block) NextBaseFee(p FeeParams) (*big.Int, error) {
+	return nextBaseFee(b.BaseFeePerGas, b.GasUsed, b.GasLimit, p)
+}
+
+// NextExcessBlobGas returns the excess blob gas of the block following b, using the parameters of the fork of that block.
+func (b *Block) NextExcessBlobGas(p FeeParams) (uint64, error) {
+	return nextExcessBlobGas(b.ExcessBlobGas, b.BlobGasUsed, p)
+}
+
+// BlobBaseFee returns the blob base fee paid by blob transactions in b.
+func (b *Block) BlobBaseFee(p FeeParams) (*big.Int, error) {
+	return blobBaseFee(b.ExcessBlobGas, p)
+}
+
+// NextBlobBaseFee returns the blob base fee of the block following b, using the parameters of the fork of that block.
+func (b *Block) NextBlobBaseFee(p FeeParams) (*big.Int, error) {
+	return nextBlobBaseFee(b.ExcessBlobGas, b.BlobGasUsed, p)
+}
+
+// NextBaseFee returns the base fee of the block following nh.
+func (nh *NewHeadsResult) NextBaseFee(p FeeParams) (*big.Int, error) {
+	return nextBaseFee(nh.BaseFeePerGas, nh.GasUsed, nh.GasLimit, p)
+}
+
+// NextExcessBlobGas returns the excess blob gas of the block following nh, using the parameters of the fork of that block.
+func (nh *NewHeadsResult) NextExcessBlobGas(p FeeParams) (uint64, error) {
+	return nextExcessBlobGas(nh.ExcessBlobGas, nh.BlobGasUsed, p)
+}
+
+// BlobBaseFee returns the blob base fee paid by blob transactions in nh.
+func (nh *NewHeadsResult) BlobBaseFee(p FeeParams) (*big.Int, error) {
+	return blobBaseFee(nh.ExcessBlobGas, p)
+}
+
+// NextBlobBaseFee returns the blob base fee of the block following nh, using the parameters of the fork of that block.
+func (nh *NewHeadsResult) NextBlobBaseFee(p FeeParams) (*big.Int, error) {
+	return nextBlobBaseFee(nh.ExcessBlobGas, nh.BlobGasUsed, p)
+}
+
+func nextBaseFee(baseFee *Quantity, gasUsed, gasLimit Quantity, p FeeParams) (*big.Int, error) {
+	if baseFee == nil {
+		return nil, ErrNoBaseFee
+	}
+
+	return CalcNextBaseFee(baseFee.Big(), gasUsed.UInt64(), gasLimit.UInt64(), p), nil
+}
+
+func nextExcessBlobGas(excessBlobGas, blobGasUsed *Quantity, p FeeParams) (uint64, error) {
+	if excessBlobGas == nil || blobGasUsed == nil {
+		return 0, ErrNoBlobGas
+	}
+
+	return CalcExcessBlobGas(excessBlobGas.UInt64(), blobGasUsed.UInt64(), p), nil
+}
+
+func blobBaseFee(excessBlobGas *Quantity, p FeeParams) (*big.Int, error) {
+	if excessBlobGas == nil {
+		return nil, ErrNoBlobGas
+	}
+
+	return CalcBlobBaseFee(excessBlobGas.UInt64(), p), nil
+}
+
+func nextBlobBaseFee(excessBlobGas, blobGasUsed *Quantity, p FeeParams) (*big.Int, error) {
+	excess, err := nextExcessBlobGas(excessBlobGas, blobGasUsed, p)
+	if err != nil {
+		return nil, err
+	}
+
+	return CalcBlobBaseFee(excess, p), nil
+}
@@ -0,0 +1,180 @@
+package eth_test
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+)
+
+func TestCalcNextBaseFee(t *testing.T) {
+	// vectors from the EIP-1559 tests of go-ethereum
+	tests := []struct {
+		baseFee  int64
+		gasLimit uint64
+		gasUsed  uint64
+		expected int64
+	}{
+		{1000000000, 20000000, 10000000, 1000000000}, // usage == target
+		{1000000000, 20000000, 9000000, 987500000},   // usage below target
+		{1000000000, 20000000, 11000000, 1012500000}, // usage above target
+		{1000000000, 30000000, 0, 875000000},         // empty block
+		{1000000000, 30000000, 30000000, 1125000000}, // full block
+		{7, 30000000, 15000001, 8},                   // an increase is at least 1 wei
+		{7, 30000000, 14999999, 7},                   // while a decrease may round to nothing
+	}
+
+	for _, test := range tests {
+		next := eth.CalcNextBaseFee(big.NewInt(test.baseFee), test.gasUsed, test.gasLimit, eth.CancunFeeParams)
+		require.Equal(t, big.NewInt(test.expected), next, "base fee %d gas used %d of %d", test.baseFee, test.gasUsed, test.gasLimit)
+	}
+}
+
+func TestCalcBlobBaseFee(t *testing.T) {
+	// vectors from the EIP-4844 tests of go-ethereum
+	tests := []struct {
+		excessBlobGas uint64
+		expected      int64
+	}{
+		{0, 1},
+		{2314057, 1},
+		{2314058, 2},
+		{10 * 1024 * 1024, 23},
+	}
+
+	for _, test := range tests {
+		require.Equal(t, big.NewInt(test.expected), eth.CalcBlobBaseFee(test.excessBlobGas, eth.CancunFeeParams), "excess %d", test.excessBlobGas)
+	}
+
+	// Prague's larger update fraction makes the same excess cheaper
+	require.Equal(t, big.NewInt(8), eth.CalcBlobBaseFee(10*1024*1024, eth.PragueFeeParams))
+
+	// the fake exponential of EIP-4844, with the factor and denominator taken from the params
+	exponentials := []struct {
+		factor, numerator, denominator uint64
+		expected                       int64
+	}{
+		{1, 0, 1, 1},
+		{38493, 0, 1000, 38493},
+		{0, 1234, 2345, 0},
+		{1, 2, 1, 6},
+		{1, 4, 2, 6},
+		{1, 3, 1, 16},
+		{1, 6, 2, 18},
+		{1, 4, 1, 49},
+		{1, 8, 2, 50},
+		{10, 8, 2, 542},
+		{11, 8, 2, 596},
+		{1, 5, 1, 136},
+		{1, 5, 2, 11},
+		{2, 5, 2, 23},
+		{1, 50000000, 2225652, 5709098764},
+	}
+
+	for _, e := range exponentials {
+		p := eth.FeeParams{MinBlobBaseFee: e.factor, BlobBaseFeeUpdateFraction: e.denominator}
+		require.Equal(t, big.NewInt(e.expected), eth.CalcBlobBaseFee(e.numerator, p), "fake_exponential(%d, %d, %d)", e.factor, e.numerator, e.denominator)
+	}
+}
+
+func TestCalcExcessBlobGas(t *testing.T) {
+	// below the target the excess is used up
+	require.Equal(t, uint64(0), eth.CalcExcessBlobGas(0, 2*eth.GasPerBlob, eth.CancunFeeParams))
+	require.Equal(t, uint64(0), eth.CalcExcessBlobGas(eth.GasPerBlob, eth.GasPerBlob, eth.CancunFeeParams))
+
+	// a block with 6 blobs is 3 over the Cancun target but on the Prague one
+	require.Equal(t, uint64(3*eth.GasPerBlob), eth.CalcExcessBlobGas(0, 6*eth.GasPerBlob, eth.CancunFeeParams))
+	require.Equal(t, uint64(0), eth.CalcExcessBlobGas(0, 6*eth.GasPerBlob, eth.PragueFeeParams))
+	require.Equal(t, uint64(5*eth.GasPerBlob), eth.CalcExcessBlobGas(2*eth.GasPerBlob, 9*eth.GasPerBlob, eth.PragueFeeParams))
+}
+
+func TestBlock_NextFees(t *testing.T) {
+	// a made-up Cancun header with round numbers, 3 blobs right on the target and an excess left over from earlier
+	// blocks, so each expected value can be worked out by hand
+	raw := `{"baseFeePerGas":"0x3b9aca00","blobGasUsed":"0x60000","excessBlobGas":"0x200000","gasLimit":"0x1c9c380","gasUsed":"0x1312d00","number":"0x393f0","transactions":[],"uncles":[]}`
+
+	block := eth.Block{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &block))
+	head := eth.NewHeadsResult{}
+	require.NoError(t, json.Unmarshal([]byte(raw), &head))
+
+	for _, parent := range []interface {
+		NextBaseFee(eth.FeeParams) (*big.Int, error)
+		NextExcessBlobGas(eth.FeeParams) (uint64, error)
+		BlobBaseFee(eth.FeeParams) (*big.Int, error)
+		NextBlobBaseFee(eth.FeeParams) (*big.Int, error)
+	}{&block, &head} {
+		// 20M of 30M gas used is a third over the target
+		next, err := parent.NextBaseFee(eth.CancunFeeParams)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1041666666), next)
+
+		excess, err := parent.NextExcessBlobGas(eth.CancunFeeParams)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0x200000), excess)
+
+		// under Prague the same 3 blobs are half the target, which eats into the excess
+		excess, err = parent.NextExcessBlobGas(eth.PragueFeeParams)
+		require.NoError(t, err)
+		require.Equal(t, uint64(0x200000-3*eth.GasPerBlob), excess)
+
+		fee, err := parent.BlobBaseFee(eth.CancunFeeParams)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1), fee)
+
+		fee, err = parent.NextBlobBaseFee(eth.CancunFeeParams)
+		require.NoError(t, err)
+		require.Equal(t, big.NewInt(1), fee)
+	}
+
+	legacy := eth.Block{}
+	_, err := legacy.NextBaseFee(eth.CancunFeeParams)
+	require.Equal(t, eth.ErrNoBaseFee, err)
+	_, err = legacy.NextBlobBaseFee(eth.CancunFeeParams)
+	require.Equal(t, eth.ErrNoBlobGas, err)
+	_, err = (&eth.NewHeadsResult{}).BlobBaseFee(eth.PragueFeeParams)
+	require.Equal(t, eth.ErrNoBlobGas, err)
+}
+
+func TestBlock_NextFees_Mainnet(t *testing.T) {
+	// consecutive mainnet blocks from well inside each fork, so that parent and child share the fee parameters
+	forks := []struct {
+		name   string
+		params eth.FeeParams
+		first  uint64
+	}{
+		{"cancun", eth.CancunFeeParams, 19426600},
+		{"cancun", eth.CancunFeeParams, 20000000},
+		{"prague", eth.PragueFeeParams, 22431100},
+		{"prague", eth.PragueFeeParams, 22800000},
+	}
+
+	for _, fork := range forks {
+		blocks := make([]eth.Block, 5)
+		for i := range blocks {
+			mainnetRequest(t, &blocks[i], "eth_getBlockByNumber", eth.QuantityFromUInt64(fork.first+uint64(i)), false)
+		}
+
+		for i := 1; i < len(blocks); i++ {
+			parent, child := &blocks[i-1], &blocks[i]
+			require.Equal(t, *parent.Hash, child.ParentHash, "%s block %d", fork.name, child.Number.UInt64())
+
+			next, err := parent.NextBaseFee(fork.params)
+			require.NoError(t, err)
+			require.Equal(t, child.BaseFeePerGas.Big(), next, "%s block %d base fee", fork.name, child.Number.UInt64())
+
+			excess, err := parent.NextExcessBlobGas(fork.params)
+			require.NoError(t, err)
+			require.Equal(t, child.ExcessBlobGas.UInt64(), excess, "%s block %d excess blob gas", fork.name, child.Number.UInt64())
+
+			expected, err := child.BlobBaseFee(fork.params)
+			require.NoError(t, err)
+			fee, err := parent.NextBlobBaseFee(fork.params)
+			require.NoError(t, err)
+			require.Equal(t, expected, fee, "%s block %d blob base fee", fork.name, child.Number.UInt64())
+		}
+	}
+}
@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 
 	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/rlp"
 )
 
 type Log struct {
@@ -23,6 +25,26 @@ type Log struct {
 	Type       *string   `json:"type,omitempty"`
 }
 
+// RLP returns the consensus encoding of the log as it appears in a transaction receipt.
+func (l *Log) RLP() rlp.Value {
+	// log = [address, [topic_0, ...], data]
+	topics := rlp.Value{List: make([]rlp.Value, len(l.Topics))}
+	for i := range l.Topics {
+		topics.List[i] = l.Topics[i].RLP()
+	}
+
+	return rlp.Value{List: []rlp.Value{
+		l.Address.RLP(),
+		topics,
+		l.Data.RLP(),
+	}}
+}
+
+type LogNotificationParams struct {
+	Subscription string `json:"subscription"`
+	Result       Log    `json:"result"`
+}
+
 type addrOrArray []Address
 
 func (a *addrOrArray) UnmarshalJSON(data []byte) error {
@@ -0,0 +1,40 @@
+package eth_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// mainnetRequest sends method to the mainnet node at ETHLIBS_TEST_MAINNET_URL and decodes its result into result.
+func mainnetRequest(t *testing.T, result interface{}, method string, params ...interface{}) {
+	// These tests require a mainnet HTTP URL to test with, for example http://localhost:8545 or https://mainnet.infura.io/v3/:YOUR_PROJECT_ID
+	url := os.Getenv("ETHLIBS_TEST_MAINNET_URL")
+	if url == "" {
+		t.Skip("ETHLIBS_TEST_MAINNET_URL not set, skipping test.  Set to a valid HTTP URL to execute this test.")
+	}
+
+	body, err := json.Marshal(jsonrpc.Request{
+		JSONRPC: "2.0",
+		ID:      jsonrpc.ID{Num: 1},
+		Method:  method,
+		Params:  jsonrpc.MustParams(params...),
+	})
+	require.NoError(t, err)
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	response := jsonrpc.RawResponse{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&response))
+	require.Nil(t, response.Error, "%s should not fail", method)
+	require.NoError(t, json.Unmarshal(response.Result, result))
+}
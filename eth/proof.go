@@ -0,0 +1,28 @@
+package eth
+
+// AccountProof is the result of eth_getProof, an account and some of its storage slots along with the
+// Merkle-Patricia proofs that link them to the state root of a block.
+type AccountProof struct {
+	Address Address `json:"address"`
+
+	// AccountProof holds the RLP encoded nodes of the state trie on the path from the state root to the account
+	AccountProof []Data `json:"accountProof"`
+
+	Balance     Quantity `json:"balance"`
+	CodeHash    Hash     `json:"codeHash"`
+	Nonce       Quantity `json:"nonce"`
+	StorageHash Hash     `json:"storageHash"`
+
+	StorageProof []StorageProof `json:"storageProof"`
+}
+
+// StorageProof is the value of a storage slot along with the proof that links it to the storage root of its account.
+type StorageProof struct {
+	// Key is the slot as it was requested
+	Key Data `json:"key"`
+
+	Value Quantity `json:"value"`
+
+	// Proof holds the RLP encoded nodes of the storage trie on the path from the storage root to the slot
+	Proof []Data `json:"proof"`
+}
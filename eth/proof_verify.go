@@ -0,0 +1,316 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+
+	"github.com/justinwongcn/go-ethlibs/rlp"
+)
+
+const (
+	// EmptyRootHash is the root of a trie without any entries, such as the storage of an account without storage.
+	EmptyRootHash = Hash("0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// EmptyCodeHash is the hash of the code of an account without code.
+	EmptyCodeHash = Hash("0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470")
+)
+
+// Account is the state of an account as it is stored in the state trie.
+type Account struct {
+	Nonce       Quantity
+	Balance     Quantity
+	StorageRoot Hash
+	CodeHash    Hash
+}
+
+// ProofError is returned when a Merkle-Patricia proof does not verify, naming the node at fault.
+type ProofError struct {
+	// Node is the index in the proof of the node where verification failed, which is len(proof) if the
+	// proof ended too early
+	Node int
+
+	// Reason describes what was wrong with the node
+	Reason string
+}
+
+func (e *ProofError) Error() string {
+	return fmt.Sprintf("proof node %d: %s", e.Node, e.Reason)
+}
+
+// VerifyProof walks the RLP encoded trie nodes of proof from root along the path of the keccak256 hash of key,
+// as done for the state trie and storage tries.  It returns the value stored at key, or nil if the proof shows
+// that there is no such key.
+func VerifyProof(root Hash, key []byte, proof []Data) ([]byte, error) {
+	return verifyProof(root, keybytesToNibbles(keccak(key)), proof)
+}
+
+// Verify checks the account proof against the state root of a block and returns the proven account.  The account
+// fields in p must match the proven ones.  An account that does not exist is proven as an empty one.
+func (p *AccountProof) Verify(stateRoot Hash) (*Account, error) {
+	address, err := hex.DecodeString(strings.TrimPrefix(string(p.Address), "0x"))
+	if err != nil || len(address) != 20 {
+		return nil, fmt.Errorf("invalid address %s", p.Address)
+	}
+
+	value, err := VerifyProof(stateRoot, address, p.AccountProof)
+	if err != nil {
+		return nil, err
+	}
+
+	account := Account{
+		Nonce:       QuantityFromUInt64(0),
+		Balance:     QuantityFromUInt64(0),
+		StorageRoot: EmptyRootHash,
+		CodeHash:    EmptyCodeHash,
+	}
+
+	if value != nil {
+		decoded, err := rlp.From("0x" + hex.EncodeToString(value))
+		if err != nil || !decoded.IsList() || len(decoded.List) != 4 {
+			return nil, fmt.Errorf("proven account is not a list of 4 items")
+		}
+
+		fields := make([][]byte, 4)
+		for i, item := range decoded.List {
+			if fields[i], err = rlpBytes(item); err != nil {
+				return nil, fmt.Errorf("invalid account field %d: %v", i, err)
+			}
+		}
+
+		if len(fields[2]) != 32 || len(fields[3]) != 32 {
+			return nil, fmt.Errorf("proven account has invalid storage root or code hash")
+		}
+
+		account.Nonce = QuantityFromBigInt(new(big.Int).SetBytes(fields[0]))
+		account.Balance = QuantityFromBigInt(new(big.Int).SetBytes(fields[1]))
+		account.StorageRoot = Hash("0x" + hex.EncodeToString(fields[2]))
+		account.CodeHash = Hash("0x" + hex.EncodeToString(fields[3]))
+	}
+
+	switch {
+	case p.Nonce.Big().Cmp(account.Nonce.Big()) != 0:
+		return nil, fmt.Errorf("nonce %s does not match proven %s", p.Nonce.String(), account.Nonce.String())
+	case p.Balance.Big().Cmp(account.Balance.Big()) != 0:
+		return nil, fmt.Errorf("balance %s does not match proven %s", p.Balance.String(), account.Balance.String())
+	case value != nil && !strings.EqualFold(string(p.StorageHash), string(account.StorageRoot)):
+		return nil, fmt.Errorf("storage hash %s does not match proven %s", p.StorageHash, account.StorageRoot)
+	case value != nil && !strings.EqualFold(string(p.CodeHash), string(account.CodeHash)):
+		return nil, fmt.Errorf("code hash %s does not match proven %s", p.CodeHash, account.CodeHash)
+	}
+
+	return &account, nil
+}
+
+// Verify checks the storage proof against the storage root of its account and returns the proven value, which
+// is zero for a slot that is not set.  The value in s must match the proven one.
+func (s *StorageProof) Verify(storageRoot Hash) (*Quantity, error) {
+	slot, err := hex.DecodeString(padHex(strings.TrimPrefix(string(s.Key), "0x")))
+	if err != nil || len(slot) > 32 {
+		return nil, fmt.Errorf("invalid storage key %s", s.Key)
+	}
+
+	// slots are stored under the hash of their 32 byte big-endian form
+	key := make([]byte, 32)
+	copy(key[32-len(slot):], slot)
+
+	value, err := VerifyProof(storageRoot, key, s.Proof)
+	if err != nil {
+		return nil, err
+	}
+
+	proven := QuantityFromUInt64(0)
+	if value != nil {
+		decoded, err := rlp.From("0x" + hex.EncodeToString(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage value: %v", err)
+		}
+
+		b, err := rlpBytes(*decoded)
+		if err != nil {
+			return nil, fmt.Errorf("invalid storage value: %v", err)
+		}
+		proven = QuantityFromBigInt(new(big.Int).SetBytes(b))
+	}
+
+	if s.Value.Big().Cmp(proven.Big()) != 0 {
+		return nil, fmt.Errorf("storage value %s does not match proven %s", s.Value.String(), proven.String())
+	}
+
+	return &proven, nil
+}
+
+// verifyProof walks proof along the nibbles of path, starting at the node hashing to root
+func verifyProof(root Hash, path []byte, proof []Data) ([]byte, error) {
+	expected, err := hex.DecodeString(strings.TrimPrefix(string(root), "0x"))
+	if err != nil || len(expected) != 32 {
+		return nil, fmt.Errorf("invalid root %s", root)
+	}
+
+	if len(proof) == 0 {
+		if strings.EqualFold(string(root), string(EmptyRootHash)) {
+			return nil, nil
+		}
+		return nil, &ProofError{Node: 0, Reason: "proof is empty but the trie is not"}
+	}
+
+	i := 0
+	var node *rlp.Value
+	for {
+		if node == nil {
+			// the next node is referenced by its hash, so it has to come from the proof
+			if i >= len(proof) {
+				return nil, &ProofError{Node: i, Reason: fmt.Sprintf("proof ends before node 0x%x", expected)}
+			}
+
+			raw, err := hex.DecodeString(strings.TrimPrefix(string(proof[i]), "0x"))
+			if err != nil {
+				return nil, &ProofError{Node: i, Reason: fmt.Sprintf("invalid hex: %v", err)}
+			}
+
+			if actual := keccak(raw); !bytes.Equal(actual, expected) {
+				return nil, &ProofError{Node: i, Reason: fmt.Sprintf("hash 0x%x does not match expected 0x%x", actual, expected)}
+			}
+
+			node, err = rlp.From(strings.ToLower(string(proof[i])))
+			if err != nil {
+				return nil, &ProofError{Node: i, Reason: fmt.Sprintf("invalid RLP: %v", err)}
+			}
+			i++
+		}
+
+		// the node that failed is the last one taken from the proof, embedded nodes belong to it
+		at := i - 1
+		var next rlp.Value
+
+		switch {
+		case node.IsList() && len(node.List) == 17:
+			if len(path) == 0 {
+				value, err := rlpBytes(node.List[16])
+				if err != nil {
+					return nil, &ProofError{Node: at, Reason: fmt.Sprintf("invalid branch value: %v", err)}
+				}
+				return finish(value, i, proof)
+			}
+
+			next = node.List[path[0]]
+			path = path[1:]
+
+		case node.IsList() && len(node.List) == 2:
+			encoded, err := rlpBytes(node.List[0])
+			if err != nil || len(encoded) == 0 {
+				return nil, &ProofError{Node: at, Reason: "invalid path"}
+			}
+
+			nibbles, leaf := compactToNibbles(encoded)
+			if len(path) < len(nibbles) || !bytes.Equal(path[:len(nibbles)], nibbles) {
+				// the path diverges from the key, which proves it is absent
+				return finish(nil, i, proof)
+			}
+			path = path[len(nibbles):]
+
+			if leaf {
+				if len(path) != 0 {
+					return finish(nil, i, proof)
+				}
+
+				value, err := rlpBytes(node.List[1])
+				if err != nil {
+					return nil, &ProofError{Node: at, Reason: fmt.Sprintf("invalid leaf value: %v", err)}
+				}
+				return finish(value, i, proof)
+			}
+
+			next = node.List[1]
+
+		default:
+			return nil, &ProofError{Node: at, Reason: "not a branch, extension or leaf node"}
+		}
+
+		// the child is either empty, the hash of a node, or a node of less than 32 bytes embedded in its parent
+		if next.IsList() {
+			embedded := next
+			node = &embedded
+			continue
+		}
+
+		child, err := rlpBytes(next)
+		if err != nil {
+			return nil, &ProofError{Node: at, Reason: fmt.Sprintf("invalid child reference: %v", err)}
+		}
+
+		switch len(child) {
+		case 0:
+			return finish(nil, i, proof)
+		case 32:
+			expected = child
+			node = nil
+		default:
+			return nil, &ProofError{Node: at, Reason: fmt.Sprintf("invalid child reference of %d bytes", len(child))}
+		}
+	}
+}
+
+// finish returns value once the walk has ended after used nodes, as long as the proof has no nodes left over
+func finish(value []byte, used int, proof []Data) ([]byte, error) {
+	if used < len(proof) {
+		return nil, &ProofError{Node: used, Reason: "unexpected node after the end of the path"}
+	}
+
+	if len(value) == 0 {
+		return nil, nil
+	}
+
+	return value, nil
+}
+
+// rlpBytes returns the bytes of an RLP string
+func rlpBytes(v rlp.Value) ([]byte, error) {
+	if v.IsList() {
+		return nil, fmt.Errorf("expected a string, got a list")
+	}
+
+	return hex.DecodeString(strings.TrimPrefix(v.String, "0x"))
+}
+
+// compactToNibbles decodes the hex-prefix encoded path of a leaf or extension node
+func compactToNibbles(compact []byte) ([]byte, bool) {
+	nibbles := keybytesToNibbles(compact)
+	leaf := nibbles[0] >= 2
+
+	// an odd flag means the first nibble belongs to the path, otherwise it is followed by a padding nibble
+	if nibbles[0]&1 == 1 {
+		return nibbles[1:], leaf
+	}
+
+	return nibbles[2:], leaf
+}
+
+func keybytesToNibbles(b []byte) []byte {
+	nibbles := make([]byte, len(b)*2)
+	for i, v := range b {
+		nibbles[i*2] = v >> 4
+		nibbles[i*2+1] = v & 0x0f
+	}
+
+	return nibbles
+}
+
+// padHex makes a hex string of odd length, such as a quantity, decodable
+func padHex(s string) string {
+	if len(s)%2 == 1 {
+		return "0" + s
+	}
+
+	return s
+}
+
+func keccak(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
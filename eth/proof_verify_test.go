@@ -0,0 +1,306 @@
+package eth_test
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/sha3"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/rlp"
+)
+
+// The tries in these tests are put together node by node, the same way a node would when answering eth_getProof.
+
+func keccak(b []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(b)
+	return h.Sum(nil)
+}
+
+func rlpString(b []byte) rlp.Value {
+	return rlp.Value{String: "0x" + hex.EncodeToString(b)}
+}
+
+func encodeNode(t *testing.T, v rlp.Value) []byte {
+	encoded, err := v.Encode()
+	require.NoError(t, err)
+	b, err := hex.DecodeString(encoded[2:])
+	require.NoError(t, err)
+	return b
+}
+
+func nibbles(key []byte) []byte {
+	n := make([]byte, 0, len(key)*2)
+	for _, b := range key {
+		n = append(n, b>>4, b&0x0f)
+	}
+	return n
+}
+
+// compact hex-prefix encodes a path of nibbles
+func compact(path []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+
+	if len(path)%2 == 1 {
+		path = append([]byte{flag + 1}, path...)
+	} else {
+		path = append([]byte{flag, 0}, path...)
+	}
+
+	b := make([]byte, len(path)/2)
+	for i := range b {
+		b[i] = path[i*2]<<4 | path[i*2+1]
+	}
+	return b
+}
+
+func leafNode(path []byte, value []byte) rlp.Value {
+	return rlp.Value{List: []rlp.Value{rlpString(compact(path, true)), rlpString(value)}}
+}
+
+func extensionNode(t *testing.T, path []byte, child rlp.Value) rlp.Value {
+	return rlp.Value{List: []rlp.Value{rlpString(compact(path, false)), rlpString(keccak(encodeNode(t, child)))}}
+}
+
+func branchNode(t *testing.T, children map[byte]rlp.Value) rlp.Value {
+	items := make([]rlp.Value, 17)
+	for i := range items {
+		items[i] = rlpString(nil)
+	}
+	for nibble, child := range children {
+		items[nibble] = rlpString(keccak(encodeNode(t, child)))
+	}
+	return rlp.Value{List: items}
+}
+
+func proofOf(t *testing.T, nodes ...rlp.Value) []eth.Data {
+	proof := make([]eth.Data, len(nodes))
+	for i, node := range nodes {
+		proof[i] = eth.Data("0x" + hex.EncodeToString(encodeNode(t, node)))
+	}
+	return proof
+}
+
+func rootOf(t *testing.T, node rlp.Value) eth.Hash {
+	return eth.Hash("0x" + hex.EncodeToString(keccak(encodeNode(t, node))))
+}
+
+func accountValue(t *testing.T, nonce, balance int64, storageRoot, codeHash eth.Hash) []byte {
+	return encodeNode(t, rlp.Value{List: []rlp.Value{
+		rlpString(big.NewInt(nonce).Bytes()),
+		rlpString(big.NewInt(balance).Bytes()),
+		rlpString(storageRoot.Bytes()),
+		rlpString(codeHash.Bytes()),
+	}})
+}
+
+func TestAccountProof_Verify(t *testing.T) {
+	alice := eth.Address("0x00000000000000000000000000000000000000a1")
+	bob := eth.Address("0x00000000000000000000000000000000000000b0")
+	aliceKey := nibbles(keccak(alice.Bytes()))
+	bobKey := nibbles(keccak(bob.Bytes()))
+	require.NotEqual(t, aliceKey[0], bobKey[0])
+
+	storageRoot := eth.Hash("0x1111111111111111111111111111111111111111111111111111111111111111")
+	codeHash := eth.Hash("0x2222222222222222222222222222222222222222222222222222222222222222")
+	aliceLeaf := leafNode(aliceKey[1:], accountValue(t, 5, 1000000000000000000, storageRoot, codeHash))
+	bobLeaf := leafNode(bobKey[1:], accountValue(t, 0, 7, eth.EmptyRootHash, eth.EmptyCodeHash))
+	branch := branchNode(t, map[byte]rlp.Value{aliceKey[0]: aliceLeaf, bobKey[0]: bobLeaf})
+	root := rootOf(t, branch)
+
+	p := eth.AccountProof{
+		Address:      alice,
+		AccountProof: proofOf(t, branch, aliceLeaf),
+		Balance:      eth.QuantityFromInt64(1000000000000000000),
+		CodeHash:     codeHash,
+		Nonce:        eth.QuantityFromInt64(5),
+		StorageHash:  storageRoot,
+	}
+
+	account, err := p.Verify(root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(5), account.Nonce.UInt64())
+	require.Equal(t, int64(1000000000000000000), account.Balance.Int64())
+	require.Equal(t, storageRoot, account.StorageRoot)
+	require.Equal(t, codeHash, account.CodeHash)
+
+	// the node claims a different balance than it proves
+	lying := p
+	lying.Balance = eth.QuantityFromInt64(1)
+	_, err = lying.Verify(root)
+	require.EqualError(t, err, "balance 0x1 does not match proven 0xde0b6b3a7640000")
+
+	// a single leaf can be the whole trie
+	full := leafNode(bobKey, accountValue(t, 0, 7, eth.EmptyRootHash, eth.EmptyCodeHash))
+	single := eth.AccountProof{
+		Address:      bob,
+		AccountProof: proofOf(t, full),
+		Balance:      eth.QuantityFromInt64(7),
+		CodeHash:     eth.EmptyCodeHash,
+		StorageHash:  eth.EmptyRootHash,
+	}
+	account, err = single.Verify(rootOf(t, full))
+	require.NoError(t, err)
+	require.Equal(t, uint64(7), account.Balance.UInt64())
+
+	// an account whose path ends at an empty branch slot does not exist
+	var carol eth.Address
+	for i := int64(0); ; i++ {
+		carol = eth.Address("0x" + hex.EncodeToString(big.NewInt(i).FillBytes(make([]byte, 20))))
+		if n := nibbles(keccak(carol.Bytes()))[0]; n != aliceKey[0] && n != bobKey[0] {
+			break
+		}
+	}
+
+	absent := eth.AccountProof{Address: carol, AccountProof: proofOf(t, branch)}
+	account, err = absent.Verify(root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), account.Nonce.UInt64())
+	require.Equal(t, eth.EmptyCodeHash, account.CodeHash)
+
+	absent.Nonce = eth.QuantityFromInt64(1)
+	_, err = absent.Verify(root)
+	require.Error(t, err)
+}
+
+func TestAccountProof_VerifyFailures(t *testing.T) {
+	alice := eth.Address("0x00000000000000000000000000000000000000a1")
+	key := nibbles(keccak(alice.Bytes()))
+	leaf := leafNode(key[1:], accountValue(t, 1, 1, eth.EmptyRootHash, eth.EmptyCodeHash))
+	branch := branchNode(t, map[byte]rlp.Value{key[0]: leaf})
+	root := rootOf(t, branch)
+
+	p := eth.AccountProof{Address: alice, Nonce: eth.QuantityFromInt64(1), Balance: eth.QuantityFromInt64(1), StorageHash: eth.EmptyRootHash, CodeHash: eth.EmptyCodeHash}
+
+	tests := []struct {
+		name  string
+		root  eth.Hash
+		proof []eth.Data
+		node  int
+	}{
+		{"wrong root", rootOf(t, leaf), proofOf(t, branch, leaf), 0},
+		{"tampered leaf", root, proofOf(t, branch, leafNode(key[1:], accountValue(t, 1, 2, eth.EmptyRootHash, eth.EmptyCodeHash))), 1},
+		{"truncated", root, proofOf(t, branch), 1},
+		{"extra node", root, proofOf(t, branch, leaf, leaf), 2},
+		{"empty", root, nil, 0},
+	}
+
+	for _, test := range tests {
+		p.AccountProof = test.proof
+		_, err := p.Verify(test.root)
+		require.IsType(t, &eth.ProofError{}, err, test.name)
+		require.Equal(t, test.node, err.(*eth.ProofError).Node, test.name)
+	}
+
+	p.AccountProof = proofOf(t, branch, leaf)
+	_, err := p.Verify(root)
+	require.NoError(t, err)
+
+	// the error names the node that failed
+	p.AccountProof = proofOf(t, branch)
+	_, err = p.Verify(root)
+	require.Contains(t, err.Error(), "proof node 1: proof ends before node 0x")
+}
+
+func TestStorageProof_Verify(t *testing.T) {
+	// find two slots whose hashed keys share their first nibble, so the trie starts with an extension
+	slotKey := func(slot int64) []byte {
+		return nibbles(keccak(big.NewInt(slot).FillBytes(make([]byte, 32))))
+	}
+
+	first, second := int64(0), int64(1)
+	for ; slotKey(first)[0] != slotKey(second)[0] || slotKey(first)[1] == slotKey(second)[1]; second++ {
+	}
+
+	a, b := slotKey(first), slotKey(second)
+	leafA := leafNode(a[2:], encodeNode(t, rlpString([]byte{0x2a})))
+	leafB := leafNode(b[2:], encodeNode(t, rlpString(big.NewInt(1000000).Bytes())))
+	branch := branchNode(t, map[byte]rlp.Value{a[1]: leafA, b[1]: leafB})
+	extension := extensionNode(t, a[:1], branch)
+	root := rootOf(t, extension)
+
+	s := eth.StorageProof{Key: "0x0", Value: eth.QuantityFromInt64(42), Proof: proofOf(t, extension, branch, leafA)}
+	value, err := s.Verify(root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(42), value.UInt64())
+
+	s = eth.StorageProof{
+		Key:   eth.Data(eth.QuantityFromInt64(second).String()),
+		Value: eth.QuantityFromInt64(1000000),
+		Proof: proofOf(t, extension, branch, leafB),
+	}
+	value, err = s.Verify(root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000000), value.UInt64())
+
+	// 32 byte keys work as well, and a slot diverging from the extension is proven unset
+	for slot := second + 1; ; slot++ {
+		if k := slotKey(slot); k[0] != a[0] {
+			s = eth.StorageProof{Key: eth.Data("0x" + hex.EncodeToString(big.NewInt(slot).FillBytes(make([]byte, 32)))), Proof: proofOf(t, extension)}
+			break
+		}
+	}
+
+	value, err = s.Verify(root)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), value.UInt64())
+
+	// an empty storage trie needs no nodes at all
+	value, err = (&eth.StorageProof{Key: "0x1"}).Verify(eth.EmptyRootHash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), value.UInt64())
+}
+
+func TestAccountProof_Verify_Mainnet(t *testing.T) {
+	// WETH, whose name and symbol are stored in its first two slots
+	weth := eth.Address("0xC02aaA39b223FE8D0A0e5C4F27eAD9083C756Cc2")
+	number := eth.QuantityFromUInt64(20000000)
+
+	block := eth.Block{}
+	mainnetRequest(t, &block, "eth_getBlockByNumber", number, false)
+	p := eth.AccountProof{}
+	mainnetRequest(t, &p, "eth_getProof", weth, []string{"0x0", "0x1"}, number)
+	require.True(t, len(p.AccountProof) > 1)
+	require.Len(t, p.StorageProof, 2)
+
+	account, err := p.Verify(block.StateRoot)
+	require.NoError(t, err)
+	require.Equal(t, p.StorageHash, account.StorageRoot)
+
+	for _, s := range p.StorageProof {
+		value, err := s.Verify(account.StorageRoot)
+		require.NoError(t, err, "slot %s", s.Key)
+		require.NotEqual(t, 0, value.Big().Sign(), "slot %s", s.Key)
+	}
+
+	// flipping the last nibble of a node breaks its hash, which the node before it commits to
+	tamper := func(proof []eth.Data, i int) []eth.Data {
+		tampered := append([]eth.Data{}, proof...)
+		s := string(proof[i])
+		last := "0"
+		if s[len(s)-1] == '0' {
+			last = "1"
+		}
+		tampered[i] = eth.Data(s[:len(s)-1] + last)
+		return tampered
+	}
+
+	tampered := p
+	tampered.AccountProof = tamper(p.AccountProof, 1)
+	_, err = tampered.Verify(block.StateRoot)
+	require.Error(t, err)
+	require.Equal(t, 1, err.(*eth.ProofError).Node)
+
+	s := p.StorageProof[0]
+	last := len(s.Proof) - 1
+	s.Proof = tamper(s.Proof, last)
+	_, err = s.Verify(account.StorageRoot)
+	require.Error(t, err)
+	require.Equal(t, last, err.(*eth.ProofError).Node)
+}
@@ -0,0 +1,114 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// SyncStatus is the result of eth_syncing, as well as the "result" payload of a syncing subscription notification.
+// When the node is not syncing only .Syncing is set.
+type SyncStatus struct {
+	Syncing       bool
+	StartingBlock *Quantity
+	CurrentBlock  *Quantity
+	HighestBlock  *Quantity
+}
+
+type SyncingNotificationParams struct {
+	Subscription string     `json:"subscription"`
+	Result       SyncStatus `json:"result"`
+}
+
+func (s *SyncStatus) UnmarshalJSON(data []byte) error {
+	*s = SyncStatus{}
+
+	// when the node isn't syncing (or a subscription reports that syncing has finished) the result is just `false`
+	if b, err := strconv.ParseBool(string(bytes.TrimSpace(data))); err == nil {
+		s.Syncing = b
+		return nil
+	}
+
+	fields := make(map[string]json.RawMessage)
+	err := json.Unmarshal(data, &fields)
+	if err != nil {
+		return err
+	}
+
+	// eth_syncing returns the progress object directly, while the syncing subscription
+	// wraps it as {"syncing": true, "status": {...}} with geth's un-tagged field names
+	s.Syncing = true
+	if raw, ok := fields["syncing"]; ok {
+		err = json.Unmarshal(raw, &s.Syncing)
+		if err != nil {
+			return errors.Wrap(err, "invalid syncing flag")
+		}
+	}
+
+	if raw, ok := fields["status"]; ok {
+		fields = make(map[string]json.RawMessage)
+		err = json.Unmarshal(raw, &fields)
+		if err != nil {
+			return errors.Wrap(err, "invalid sync status")
+		}
+	}
+
+	for key, raw := range fields {
+		var dst **Quantity
+		switch strings.ToLower(key) {
+		case "startingblock":
+			dst = &s.StartingBlock
+		case "currentblock":
+			dst = &s.CurrentBlock
+		case "highestblock":
+			dst = &s.HighestBlock
+		default:
+			continue
+		}
+
+		q, err := syncQuantity(raw)
+		if err != nil {
+			return errors.Wrapf(err, "invalid %s", key)
+		}
+		*dst = q
+	}
+
+	return nil
+}
+
+func (s SyncStatus) MarshalJSON() ([]byte, error) {
+	if !s.Syncing {
+		return []byte("false"), nil
+	}
+
+	status := struct {
+		StartingBlock *Quantity `json:"startingBlock,omitempty"`
+		CurrentBlock  *Quantity `json:"currentBlock,omitempty"`
+		HighestBlock  *Quantity `json:"highestBlock,omitempty"`
+	}{
+		StartingBlock: s.StartingBlock,
+		CurrentBlock:  s.CurrentBlock,
+		HighestBlock:  s.HighestBlock,
+	}
+
+	return json.Marshal(&status)
+}
+
+// syncQuantity decodes a block number that is either a hex quantity or a plain JSON number.
+func syncQuantity(raw json.RawMessage) (*Quantity, error) {
+	q := Quantity{}
+	if err := json.Unmarshal(raw, &q); err == nil {
+		return &q, nil
+	}
+
+	n, err := strconv.ParseUint(string(bytes.TrimSpace(raw)), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	q = QuantityFromUInt64(n)
+	return &q, nil
+}
@@ -0,0 +1,77 @@
+package eth_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+)
+
+func TestSyncStatus_UnmarshalJSON(t *testing.T) {
+	type TestCase struct {
+		Message  string
+		Payload  string
+		Expected eth.SyncStatus
+	}
+
+	tests := []TestCase{
+		{
+			Message:  "not syncing",
+			Payload:  `false`,
+			Expected: eth.SyncStatus{},
+		},
+		{
+			Message: "eth_syncing result",
+			Payload: `{"startingBlock":"0x384","currentBlock":"0x386","highestBlock":"0x454"}`,
+			Expected: eth.SyncStatus{
+				Syncing:       true,
+				StartingBlock: eth.MustQuantity("0x384"),
+				CurrentBlock:  eth.MustQuantity("0x386"),
+				HighestBlock:  eth.MustQuantity("0x454"),
+			},
+		},
+		{
+			Message: "geth syncing subscription result",
+			Payload: `{"syncing":true,"status":{"StartingBlock":900,"CurrentBlock":902,"HighestBlock":1108,"PulledStates":0,"KnownStates":0}}`,
+			Expected: eth.SyncStatus{
+				Syncing:       true,
+				StartingBlock: eth.MustQuantity("0x384"),
+				CurrentBlock:  eth.MustQuantity("0x386"),
+				HighestBlock:  eth.MustQuantity("0x454"),
+			},
+		},
+		{
+			Message:  "geth syncing subscription finished",
+			Payload:  `{"syncing":false}`,
+			Expected: eth.SyncStatus{},
+		},
+	}
+
+	for _, test := range tests {
+		actual := eth.SyncStatus{}
+		err := json.Unmarshal([]byte(test.Payload), &actual)
+		require.NoError(t, err, test.Message)
+		require.Equal(t, test.Expected, actual, test.Message)
+	}
+
+	actual := eth.SyncStatus{}
+	err := json.Unmarshal([]byte(`{"startingBlock":"nope"}`), &actual)
+	require.Error(t, err)
+}
+
+func TestSyncStatus_MarshalJSON(t *testing.T) {
+	b, err := json.Marshal(eth.SyncStatus{})
+	require.NoError(t, err)
+	require.Equal(t, `false`, string(b))
+
+	b, err = json.Marshal(eth.SyncStatus{
+		Syncing:       true,
+		StartingBlock: eth.MustQuantity("0x384"),
+		CurrentBlock:  eth.MustQuantity("0x386"),
+		HighestBlock:  eth.MustQuantity("0x454"),
+	})
+	require.NoError(t, err)
+	require.JSONEq(t, `{"startingBlock":"0x384","currentBlock":"0x386","highestBlock":"0x454"}`, string(b))
+}
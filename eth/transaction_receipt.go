@@ -1,5 +1,11 @@
 package eth
 
+import (
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/rlp"
+)
+
 type TransactionReceipt struct {
 	Type              *Quantity `json:"type,omitempty"`
 	TransactionHash   Hash      `json:"transactionHash"`
@@ -30,3 +36,56 @@ func (t *TransactionReceipt) TransactionType() int64 {
 
 	return t.Type.Int64()
 }
+
+// RLP returns the consensus encoding of the receipt as it is stored in the receipts trie of its block, without
+// the EIP-2718 type prefix of typed receipts.
+func (t *TransactionReceipt) RLP() (rlp.Value, error) {
+	// receipt = [post_state_or_status, cumulative_gas_used, logs_bloom, logs]
+	var outcome rlp.Value
+	switch {
+	case t.Root != nil:
+		// receipts from before EIP-658 carry the intermediate state root
+		outcome = t.Root.RLP()
+	case t.Status != nil:
+		outcome = t.Status.RLP()
+	default:
+		return rlp.Value{}, errors.New("receipt has neither a status nor a root")
+	}
+
+	logs := rlp.Value{List: make([]rlp.Value, len(t.Logs))}
+	for i := range t.Logs {
+		logs.List[i] = t.Logs[i].RLP()
+	}
+
+	return rlp.Value{List: []rlp.Value{
+		outcome,
+		t.CumulativeGasUsed.RLP(),
+		t.LogsBloom.RLP(),
+		logs,
+	}}, nil
+}
+
+// RawRepresentation returns the receipt encoded as it is stored in the receipts trie, which for typed receipts is
+// the type byte followed by the RLP encoding.
+func (t *TransactionReceipt) RawRepresentation() (*Data, error) {
+	v, err := t.RLP()
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := v.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	if t.TransactionType() == TransactionTypeLegacy {
+		return NewData(encoded)
+	}
+
+	typePrefix, err := t.Type.RLP().Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return NewData(typePrefix + encoded[2:])
+}
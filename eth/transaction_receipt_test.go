@@ -2,6 +2,7 @@ package eth_test
 
 import (
 	"encoding/json"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -239,3 +240,33 @@ func TestTransactionReceipt_4844(t *testing.T) {
 	require.NoError(t, err)
 	require.JSONEq(t, raw, string(b))
 }
+
+func TestTransactionReceipt_RawRepresentation(t *testing.T) {
+	bloom := strings.Repeat("00", 256)
+	receipt := eth.TransactionReceipt{
+		Status:            eth.OptionalQuantityFromInt(1),
+		CumulativeGasUsed: eth.QuantityFromInt64(21000),
+		LogsBloom:         eth.Data256("0x" + bloom),
+		Logs: []eth.Log{{
+			Address: "0x7F0d15C7FAae65896648C8273B6d7E43f58Fa842",
+			Topics:  []eth.Topic{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+			Data:    "0x2a",
+		}},
+	}
+
+	raw, err := receipt.RawRepresentation()
+	require.NoError(t, err)
+	require.Equal(t, "0xf9014301825208b90100"+bloom+"f83af838947f0d15c7faae65896648c8273b6d7e43f58fa842e1a0ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef2a", raw.String())
+
+	// a failed typed receipt has an empty status and is prefixed with its type
+	receipt.Type = eth.OptionalQuantityFromInt(int(eth.TransactionTypeDynamicFee))
+	receipt.Status = eth.OptionalQuantityFromInt(0)
+	receipt.Logs = nil
+	raw, err = receipt.RawRepresentation()
+	require.NoError(t, err)
+	require.Equal(t, "0x02f9010880825208b90100"+bloom+"c0", raw.String())
+
+	receipt.Status = nil
+	_, err = receipt.RawRepresentation()
+	require.Error(t, err)
+}
@@ -14,6 +14,8 @@ type RawResponse struct {
 	Error   *json.RawMessage `json:"error,omitempty"`
 }
 
+type BatchRawResponse []*RawResponse
+
 // MarshalJSON implements json.Marshaler and adds the "jsonrpc":"2.0"
 // property.
 func (r RawResponse) MarshalJSON() ([]byte, error) {
@@ -0,0 +1,122 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+var ErrEmptyBatch = errors.New("batch must contain at least one request")
+
+// proxyBatch returns a copy of the batch where every request ID has been replaced by its index in the batch.
+// Callers often reuse the same ID for every request (node.Client always sends ID 1), so the original IDs
+// can't be used to match responses, which backends are free to return in any order.
+func proxyBatch(batch jsonrpc.BatchRequest) (jsonrpc.BatchRequest, error) {
+	if len(batch) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	proxied := make(jsonrpc.BatchRequest, len(batch))
+	for i, r := range batch {
+		if r == nil {
+			return nil, errors.Errorf("batch request %d is nil", i)
+		}
+
+		proxy := *r
+		proxy.ID = jsonrpc.ID{Num: uint64(i)}
+		proxied[i] = &proxy
+	}
+
+	return proxied, nil
+}
+
+// matchBatch puts the responses to a batch produced by proxyBatch back into request order and
+// patches each response to carry the ID of the request it answers.
+func matchBatch(batch jsonrpc.BatchRequest, responses []*jsonrpc.RawResponse) (jsonrpc.BatchRawResponse, error) {
+	matched := make(jsonrpc.BatchRawResponse, len(batch))
+	for _, r := range responses {
+		if r == nil || r.ID.IsString || r.ID.Num >= uint64(len(batch)) {
+			// most likely an error response with a null ID, which can't be attributed to a request
+			continue
+		}
+
+		patchedResponse := *r
+		patchedResponse.ID = batch[r.ID.Num].ID
+		matched[r.ID.Num] = &patchedResponse
+	}
+
+	for i := range matched {
+		if matched[i] == nil {
+			return nil, errors.Errorf("no response for batch request %d (method %s id %s)", i, batch[i].Method, batch[i].ID.String())
+		}
+	}
+
+	return matched, nil
+}
+
+// requestBatchConcurrently emulates a batch by sending every request through r at the same time
+// and collecting the responses in request order.  Requests that fail are answered with an internal error
+// response of their own, like a backend would for a batch, unless all of them fail.
+func requestBatchConcurrently(ctx context.Context, r Requester, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	if len(batch) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	for i := range batch {
+		if batch[i] == nil {
+			return nil, errors.Errorf("batch request %d is nil", i)
+		}
+	}
+
+	responses := make(jsonrpc.BatchRawResponse, len(batch))
+	errs := make([]error, len(batch))
+	wg := sync.WaitGroup{}
+	for i := range batch {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			responses[i], errs[i] = r.Request(ctx, batch[i])
+		}(i)
+	}
+	wg.Wait()
+
+	failed := 0
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		failed++
+		if failed == len(batch) {
+			// nothing got through at all, which is the transport failing rather than the requests
+			return nil, errors.Wrap(errs[0], "batch request 0 failed")
+		}
+
+		response, err := errorResponse(batch[i], err)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+
+	return responses, nil
+}
+
+// errorResponse answers request with an internal error carrying the message of err
+func errorResponse(request *jsonrpc.Request, err error) (*jsonrpc.RawResponse, error) {
+	b, err := json.Marshal(jsonrpc.InternalError(err.Error()))
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode error response")
+	}
+
+	raw := json.RawMessage(b)
+	return &jsonrpc.RawResponse{
+		JSONRPC: "2.0",
+		ID:      request.ID,
+		Error:   &raw,
+	}, nil
+}
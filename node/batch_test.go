@@ -0,0 +1,160 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func newTestBatch() jsonrpc.BatchRequest {
+	return jsonrpc.BatchRequest{
+		jsonrpc.MustRequest(1, "eth_blockNumber"),
+		jsonrpc.MustRequest(1, "eth_chainId"),
+		jsonrpc.MustRequest(1, "eth_getBalance", "0x0000000000000000000000000000000000000000", "latest"),
+	}
+}
+
+func newTestHTTPTransport(t *testing.T, handler http.HandlerFunc) *httpTransport {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	parsed, err := url.Parse(server.URL)
+	require.NoError(t, err)
+
+	tr, err := newHTTPTransport(context.Background(), parsed, newClientOptions())
+	require.NoError(t, err)
+	return tr.(*httpTransport)
+}
+
+func TestHTTPTransport_RequestBatch(t *testing.T) {
+	tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		require.NoError(t, err)
+
+		batch := jsonrpc.BatchRequest{}
+		require.NoError(t, json.Unmarshal(body, &batch))
+		require.Len(t, batch, 3)
+
+		// respond out of order, with an error for the last element
+		_, _ = w.Write([]byte(`[` +
+			`{"jsonrpc":"2.0","id":` + batch[2].ID.String() + `,"error":{"code":-32000,"message":"header not found"}},` +
+			`{"jsonrpc":"2.0","id":` + batch[1].ID.String() + `,"result":"0x1"},` +
+			`{"jsonrpc":"2.0","id":` + batch[0].ID.String() + `,"result":"0x10"}` +
+			`]`))
+	})
+
+	responses, err := tr.RequestBatch(context.Background(), newTestBatch())
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+
+	require.Equal(t, jsonrpc.ID{Num: 1}, responses[0].ID)
+	require.Equal(t, `"0x10"`, string(responses[0].Result))
+	require.Equal(t, `"0x1"`, string(responses[1].Result))
+	require.Nil(t, responses[1].Error)
+	require.NotNil(t, responses[2].Error)
+	require.Contains(t, string(*responses[2].Error), "header not found")
+}
+
+func TestHTTPTransport_RequestBatch_Errors(t *testing.T) {
+	t.Run("rejected", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32005,"message":"batch too large"}}`))
+		})
+
+		_, err := tr.RequestBatch(context.Background(), newTestBatch())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "batch too large")
+	})
+
+	t.Run("missing response", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":0,"result":"0x10"}]`))
+		})
+
+		_, err := tr.RequestBatch(context.Background(), newTestBatch())
+		require.Error(t, err)
+		require.Contains(t, err.Error(), "eth_chainId")
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			t.Fatal("empty batch should not be sent")
+		})
+
+		_, err := tr.RequestBatch(context.Background(), jsonrpc.BatchRequest{})
+		require.Equal(t, ErrEmptyBatch, err)
+	})
+}
+
+func TestLoopingTransport_RequestBatch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr, backend := newPipeTransport(t, ctx)
+
+	go func() {
+		received := make(map[string]*jsonrpc.Request)
+		for i := 0; i < 3; i++ {
+			r := backend.next()
+			received[r.Method] = r
+		}
+
+		// answer in the reverse order of the batch
+		backend.send(&jsonrpc.RawResponse{
+			ID:    received["eth_getBalance"].ID,
+			Error: rawError(`{"code":-32000,"message":"header not found"}`),
+		})
+		backend.respond(received["eth_chainId"], "0x1")
+		backend.respond(received["eth_blockNumber"], "0x10")
+	}()
+
+	responses, err := tr.RequestBatch(ctx, newTestBatch())
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+
+	require.Equal(t, jsonrpc.ID{Num: 1}, responses[0].ID)
+	require.Equal(t, `"0x10"`, string(responses[0].Result))
+	require.Equal(t, `"0x1"`, string(responses[1].Result))
+	require.NotNil(t, responses[2].Error)
+}
+
+func TestRequestBatchConcurrently_PartialErrors(t *testing.T) {
+	r := requesterFunc(func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		if r.Method == "eth_chainId" {
+			return nil, errors.New("connection reset")
+		}
+		return &jsonrpc.RawResponse{JSONRPC: "2.0", ID: r.ID, Result: json.RawMessage(`"0x10"`)}, nil
+	})
+
+	// the other requests still get their responses
+	responses, err := requestBatchConcurrently(context.Background(), r, newTestBatch())
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	require.Equal(t, `"0x10"`, string(responses[0].Result))
+	require.Equal(t, `"0x10"`, string(responses[2].Result))
+
+	require.Equal(t, jsonrpc.ID{Num: 1}, responses[1].ID)
+	require.NotNil(t, responses[1].Error)
+	e := jsonrpc.Error{}
+	require.NoError(t, json.Unmarshal(*responses[1].Error, &e))
+	require.Equal(t, jsonrpc.ErrorCode(jsonrpc.ErrCodeInternalError), e.Code)
+	require.Equal(t, "connection reset", e.Message)
+
+	// but if none got through the batch failed as a whole
+	_, err = requestBatchConcurrently(context.Background(), r, jsonrpc.BatchRequest{jsonrpc.MustRequest(1, "eth_chainId")})
+	require.EqualError(t, err, "batch request 0 failed: connection reset")
+}
+
+func rawError(s string) *json.RawMessage {
+	raw := json.RawMessage(s)
+	return &raw
+}
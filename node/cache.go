@@ -0,0 +1,475 @@
+package node
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+const (
+	defaultCacheMaxBytes      = 32 << 20
+	defaultCacheFinalityDepth = 64
+	defaultCacheHeadRefresh   = 12 * time.Second
+
+	// cacheHeadTimeout bounds a head lookup, which is shared by every request waiting for it
+	cacheHeadTimeout = 10 * time.Second
+)
+
+// CacheConfig controls what a caching Requester keeps and for how long.
+type CacheConfig struct {
+	// MaxBytes bounds the size of the cached results, after which the least recently used are evicted.  Defaults to 32MiB.
+	MaxBytes int
+
+	// FinalityDepth is how many blocks below the head a block has to be before results that depend on its number
+	// are cached, since until then a reorg could change them.  Defaults to 64.
+	FinalityDepth uint64
+
+	// HeadRefresh is how often the head is looked up with eth_blockNumber to decide what is final.  Defaults to 12s.
+	HeadRefresh time.Duration
+}
+
+// CacheStats counts how a caching Requester handled requests.
+type CacheStats struct {
+	// Hits were answered from the cache
+	Hits uint64
+	// Misses could have been answered from the cache, but weren't in it yet
+	Misses uint64
+	// Bypassed requests were for results that can change, such as anything at the latest block
+	Bypassed uint64
+
+	// Entries and Bytes describe what is in the cache right now
+	Entries int
+	Bytes   int
+}
+
+type cacheRule int
+
+const (
+	// cacheAlways results never change, e.g. anything looked up by block hash
+	cacheAlways cacheRule = iota + 1
+	// cacheBlockParam results are fixed if the block parameter is a hash or a final block number
+	cacheBlockParam
+	// cacheResultBlock results are fixed once the block number in the result is final
+	cacheResultBlock
+)
+
+type cachePolicy struct {
+	rule cacheRule
+	// param is the position of the block parameter for cacheBlockParam
+	param int
+}
+
+// cacheableMethods are the methods whose results may be cached, and under which circumstances
+var cacheableMethods = map[string]cachePolicy{
+	"eth_chainId":                             {rule: cacheAlways},
+	"net_version":                             {rule: cacheAlways},
+	"eth_getBlockByHash":                      {rule: cacheAlways},
+	"eth_getBlockTransactionCountByHash":      {rule: cacheAlways},
+	"eth_getTransactionByBlockHashAndIndex":   {rule: cacheAlways},
+	"eth_getUncleByBlockHashAndIndex":         {rule: cacheAlways},
+	"eth_getUncleCountByBlockHash":            {rule: cacheAlways},
+	"eth_getBlockByNumber":                    {rule: cacheBlockParam, param: 0},
+	"eth_getBlockTransactionCountByNumber":    {rule: cacheBlockParam, param: 0},
+	"eth_getTransactionByBlockNumberAndIndex": {rule: cacheBlockParam, param: 0},
+	"eth_getUncleByBlockNumberAndIndex":       {rule: cacheBlockParam, param: 0},
+	"eth_getUncleCountByBlockNumber":          {rule: cacheBlockParam, param: 0},
+	"eth_getBlockReceipts":                    {rule: cacheBlockParam, param: 0},
+	"eth_getBalance":                          {rule: cacheBlockParam, param: 1},
+	"eth_getCode":                             {rule: cacheBlockParam, param: 1},
+	"eth_getTransactionCount":                 {rule: cacheBlockParam, param: 1},
+	"eth_call":                                {rule: cacheBlockParam, param: 1},
+	"eth_getStorageAt":                        {rule: cacheBlockParam, param: 2},
+	"eth_getProof":                            {rule: cacheBlockParam, param: 2},
+	"eth_getTransactionByHash":                {rule: cacheResultBlock},
+	"eth_getTransactionReceipt":               {rule: cacheResultBlock},
+}
+
+// CachingRequester answers requests for data that can no longer change from an LRU cache, and passes
+// everything else through to the Requester it wraps.  Pass it to NewCustomClient to cache a client's requests,
+// batches made through it are split into individual requests so each of them can be cached.
+type CachingRequester struct {
+	requester Requester
+	config    CacheConfig
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+	bytes   int
+	stats   CacheStats
+
+	headMu     sync.Mutex
+	head       uint64
+	headAt     time.Time
+	headKnown  bool
+	headLookup *headLookup
+}
+
+// headLookup is an eth_blockNumber in flight on behalf of every request that needs the head
+type headLookup struct {
+	done chan struct{}
+	head uint64
+	err  error
+}
+
+type cacheEntry struct {
+	key    string
+	result json.RawMessage
+}
+
+// NewCachingRequester wraps requester with a cache configured by config.
+func NewCachingRequester(requester Requester, config CacheConfig) *CachingRequester {
+	if config.MaxBytes <= 0 {
+		config.MaxBytes = defaultCacheMaxBytes
+	}
+
+	if config.FinalityDepth == 0 {
+		config.FinalityDepth = defaultCacheFinalityDepth
+	}
+
+	if config.HeadRefresh <= 0 {
+		config.HeadRefresh = defaultCacheHeadRefresh
+	}
+
+	return &CachingRequester{
+		requester: requester,
+		config:    config,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}
+}
+
+// Stats returns a snapshot of how requests were handled so far.
+func (c *CachingRequester) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := c.stats
+	stats.Entries = c.lru.Len()
+	stats.Bytes = c.bytes
+	return stats
+}
+
+// Purge empties the cache, leaving the stats alone.
+func (c *CachingRequester) Purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[string]*list.Element)
+	c.lru.Init()
+	c.bytes = 0
+}
+
+func (c *CachingRequester) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	policy, ok := cacheableMethods[r.Method]
+	if ok && policy.rule == cacheBlockParam && !c.fixedBlockParam(r, policy.param) {
+		ok = false
+	}
+
+	if !ok {
+		c.mu.Lock()
+		c.stats.Bypassed++
+		c.mu.Unlock()
+		return c.requester.Request(ctx, r)
+	}
+
+	key, err := cacheKey(r, policy)
+	if err != nil {
+		return c.requester.Request(ctx, r)
+	}
+
+	if result, ok := c.get(key); ok {
+		// callers own what they get back, so the cached result is not shared with them
+		return &jsonrpc.RawResponse{
+			JSONRPC: "2.0",
+			ID:      r.ID,
+			Result:  append(json.RawMessage(nil), result...),
+		}, nil
+	}
+
+	response, err := c.requester.Request(ctx, r)
+	if err != nil || response == nil || response.Error != nil {
+		return response, err
+	}
+
+	if len(response.Result) == 0 || bytes.Equal(response.Result, json.RawMessage(`null`)) {
+		// not found yet, but it may well be later
+		return response, nil
+	}
+
+	store := false
+	switch policy.rule {
+	case cacheAlways:
+		store = true
+	case cacheBlockParam:
+		store = c.finalBlockParam(ctx, r, policy.param)
+	case cacheResultBlock:
+		store = c.finalResult(ctx, response.Result)
+	}
+
+	if store {
+		c.put(key, append(json.RawMessage(nil), response.Result...))
+	}
+
+	return response, nil
+}
+
+// cacheKey identifies a request like requestKey does, but with hex strings such as checksummed addresses
+// lower-cased and a block number written in the same way however it was sent
+func cacheKey(r *jsonrpc.Request, policy cachePolicy) (string, error) {
+	rewritten := *r
+	rewritten.Params = append(jsonrpc.Params(nil), r.Params...)
+	if policy.rule == cacheBlockParam {
+		if b, valid := blockParam(r, policy.param); valid && b != nil {
+			canonical, err := json.Marshal(b)
+			if err != nil {
+				return "", err
+			}
+			rewritten.Params[policy.param] = canonical
+		}
+	}
+
+	for i, p := range rewritten.Params {
+		d := json.NewDecoder(bytes.NewReader(p))
+		d.UseNumber()
+
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return "", err
+		}
+
+		b, err := json.Marshal(lowerHex(v))
+		if err != nil {
+			return "", err
+		}
+		rewritten.Params[i] = b
+	}
+
+	return requestKey(&rewritten)
+}
+
+// lowerHex returns v with every 0x prefixed hex string in it lower-cased, such as checksummed addresses
+func lowerHex(v interface{}) interface{} {
+	switch value := v.(type) {
+	case string:
+		if isHex(value) {
+			return strings.ToLower(value)
+		}
+	case []interface{}:
+		for i := range value {
+			value[i] = lowerHex(value[i])
+		}
+	case map[string]interface{}:
+		for k := range value {
+			value[k] = lowerHex(value[k])
+		}
+	}
+
+	return v
+}
+
+func isHex(s string) bool {
+	if len(s) < 2 || (s[:2] != "0x" && s[:2] != "0X") {
+		return false
+	}
+
+	for _, c := range s[2:] {
+		if !strings.ContainsRune("0123456789abcdefABCDEF", c) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (c *CachingRequester) get(key string) (json.RawMessage, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.stats.Hits++
+	c.lru.MoveToFront(e)
+	return e.Value.(*cacheEntry).result, true
+}
+
+func (c *CachingRequester) put(key string, result json.RawMessage) {
+	size := len(key) + len(result)
+	if size > c.config.MaxBytes {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, result: result})
+	c.bytes += size
+
+	for c.bytes > c.config.MaxBytes {
+		oldest := c.lru.Back()
+		entry := oldest.Value.(*cacheEntry)
+		c.lru.Remove(oldest)
+		delete(c.entries, entry.key)
+		c.bytes -= len(entry.key) + len(entry.result)
+	}
+}
+
+// blockParam decodes the block parameter at pos, which is either a number or tag, or an EIP-1898 object
+// naming a block by hash or number.  A block hash is returned as a nil number, and valid is false if there
+// is no usable block parameter.
+func blockParam(r *jsonrpc.Request, pos int) (number *eth.BlockNumberOrTag, valid bool) {
+	if pos >= len(r.Params) {
+		// an omitted block parameter means latest
+		return nil, false
+	}
+
+	raw := bytes.TrimSpace(r.Params[pos])
+	if len(raw) > 0 && raw[0] == '{' {
+		obj := struct {
+			BlockHash   *eth.Hash     `json:"blockHash"`
+			BlockNumber *eth.Quantity `json:"blockNumber"`
+		}{}
+
+		if json.Unmarshal(raw, &obj) != nil {
+			return nil, false
+		}
+
+		if obj.BlockHash != nil {
+			return nil, true
+		}
+
+		if obj.BlockNumber != nil {
+			return eth.MustBlockNumberOrTag(obj.BlockNumber.String()), true
+		}
+
+		return nil, false
+	}
+
+	b := eth.BlockNumberOrTag{}
+	if json.Unmarshal(raw, &b) != nil {
+		return nil, false
+	}
+
+	return &b, true
+}
+
+// fixedBlockParam returns true if the block parameter could refer to a block that is final, without looking at the head
+func (c *CachingRequester) fixedBlockParam(r *jsonrpc.Request, pos int) bool {
+	b, valid := blockParam(r, pos)
+	if !valid {
+		return false
+	}
+
+	if b == nil {
+		return true
+	}
+
+	if tag, ok := b.Tag(); ok {
+		return tag == eth.TagEarliest
+	}
+
+	return true
+}
+
+// finalBlockParam returns true if the block parameter is a hash, earliest, or a block number that is final
+func (c *CachingRequester) finalBlockParam(ctx context.Context, r *jsonrpc.Request, pos int) bool {
+	b, valid := blockParam(r, pos)
+	if !valid {
+		return false
+	}
+
+	if b == nil {
+		return true
+	}
+
+	if tag, ok := b.Tag(); ok {
+		return tag == eth.TagEarliest
+	}
+
+	q, _ := b.Quantity()
+	return c.final(ctx, q.UInt64())
+}
+
+// finalResult returns true if the result belongs to a block that is final
+func (c *CachingRequester) finalResult(ctx context.Context, result json.RawMessage) bool {
+	obj := struct {
+		BlockNumber *eth.Quantity `json:"blockNumber"`
+	}{}
+
+	if json.Unmarshal(result, &obj) != nil || obj.BlockNumber == nil {
+		// pending transactions have no block yet
+		return false
+	}
+
+	return c.final(ctx, obj.BlockNumber.UInt64())
+}
+
+// final returns true if block number is at least FinalityDepth blocks below the head
+func (c *CachingRequester) final(ctx context.Context, number uint64) bool {
+	head, ok := c.currentHead(ctx)
+	if !ok {
+		// without a head we can't tell, so play it safe
+		return false
+	}
+
+	return number+c.config.FinalityDepth <= head
+}
+
+// currentHead returns the head, looking it up if it isn't known yet.  A head older than HeadRefresh is refreshed
+// in the background, since it can only make fewer blocks final than the real one would.
+func (c *CachingRequester) currentHead(ctx context.Context) (uint64, bool) {
+	c.headMu.Lock()
+	head, known, fresh := c.head, c.headKnown, time.Since(c.headAt) <= c.config.HeadRefresh
+	lookup := c.headLookup
+	if !fresh && lookup == nil {
+		lookup = &headLookup{done: make(chan struct{})}
+		c.headLookup = lookup
+		go c.lookupHead(lookup)
+	}
+	c.headMu.Unlock()
+
+	if known {
+		return head, true
+	}
+
+	select {
+	case <-lookup.done:
+		return lookup.head, lookup.err == nil
+	case <-ctx.Done():
+		return 0, false
+	}
+}
+
+// lookupHead asks for the head on behalf of everyone waiting on lookup, so it isn't bound by any of their contexts
+func (c *CachingRequester) lookupHead(lookup *headLookup) {
+	ctx, cancel := context.WithTimeout(context.Background(), cacheHeadTimeout)
+	defer cancel()
+
+	cl := client{transport: &customTransport{requester: c.requester}}
+	lookup.head, lookup.err = cl.BlockNumber(ctx)
+
+	c.headMu.Lock()
+	if lookup.err == nil {
+		c.head, c.headAt, c.headKnown = lookup.head, time.Now(), true
+	}
+	c.headLookup = nil
+	c.headMu.Unlock()
+
+	close(lookup.done)
+}
+
+var _ Requester = (*CachingRequester)(nil)
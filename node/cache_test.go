@@ -0,0 +1,205 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// countingBackend answers every method with a canned result, counting how often each was asked for
+type countingBackend struct {
+	mu      sync.Mutex
+	head    uint64
+	results map[string]string
+	calls   map[string]int
+
+	// headGate, if set, holds up eth_blockNumber until it is closed
+	headGate chan struct{}
+}
+
+func (b *countingBackend) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	if r.Method == "eth_blockNumber" && b.headGate != nil {
+		<-b.headGate
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.calls[r.Method]++
+	result := b.results[r.Method]
+	if r.Method == "eth_blockNumber" {
+		result = `"` + eth.QuantityFromUInt64(b.head).String() + `"`
+	}
+
+	if result == "" {
+		result = `null`
+	}
+
+	return &jsonrpc.RawResponse{JSONRPC: "2.0", ID: r.ID, Result: json.RawMessage(result)}, nil
+}
+
+func (b *countingBackend) count(method string) int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.calls[method]
+}
+
+func TestCachingRequester(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{
+		head: 1000,
+		results: map[string]string{
+			"eth_chainId":               `"0x1"`,
+			"eth_getCode":               `"0x6000"`,
+			"eth_getTransactionReceipt": `{"blockNumber":"0x3e8","status":"0x1"}`,
+			"eth_getTransactionByHash":  `{"blockNumber":"0x10"}`,
+		},
+		calls: make(map[string]int),
+	}
+
+	cache := NewCachingRequester(backend, CacheConfig{FinalityDepth: 10})
+	c, err := NewCustomClient(cache, nil)
+	require.NoError(t, err)
+
+	request := func(method string, params ...interface{}) {
+		response, err := c.Request(ctx, jsonrpc.MustRequest(7, method, params...))
+		require.NoError(t, err)
+		require.Equal(t, jsonrpc.ID{Num: 7}, response.ID)
+	}
+
+	address := "0x0000000000000000000000000000000000000001"
+	for i := 0; i < 3; i++ {
+		request("eth_chainId")
+		request("eth_getCode", address, "0x10")
+		request("eth_getCode", address, map[string]string{"blockHash": "0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd"})
+		request("eth_getCode", address, "latest")
+		request("eth_getCode", address, "0x3e8")
+		request("eth_getTransactionReceipt", "0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd")
+		request("eth_getTransactionByHash", "0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd")
+		request("eth_getBlockByHash", "0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd", false)
+		request("eth_gasPrice")
+	}
+
+	require.Equal(t, 1, backend.count("eth_chainId"))
+	// final number and hash are cached, latest and the unfinalized head are not
+	require.Equal(t, 2+3+3, backend.count("eth_getCode"))
+	// the receipt is in a block that isn't final yet, the transaction is
+	require.Equal(t, 3, backend.count("eth_getTransactionReceipt"))
+	require.Equal(t, 1, backend.count("eth_getTransactionByHash"))
+	// null results are never cached
+	require.Equal(t, 3, backend.count("eth_getBlockByHash"))
+	require.Equal(t, 3, backend.count("eth_gasPrice"))
+	// the head is only looked up once within HeadRefresh
+	require.Equal(t, 1, backend.count("eth_blockNumber"))
+
+	stats := cache.Stats()
+	require.Equal(t, uint64(2+2+2+2), stats.Hits)
+	require.Equal(t, 4, stats.Entries)
+	require.Equal(t, uint64(3+3), stats.Bypassed)
+	require.Equal(t, uint64(4+3+3+3), stats.Misses)
+
+	cache.Purge()
+	require.Equal(t, 0, cache.Stats().Entries)
+	request("eth_chainId")
+	require.Equal(t, 2, backend.count("eth_chainId"))
+}
+
+func TestCachingRequester_Keys(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{
+		head:    1000,
+		results: map[string]string{"eth_getCode": `"0x6000"`},
+		calls:   make(map[string]int),
+	}
+
+	cache := NewCachingRequester(backend, CacheConfig{FinalityDepth: 10})
+	checksummed := "0x7F0d15C7FAae65896648C8273B6d7E43f58Fa842"
+
+	// the same block and address, however they are written, are the same entry
+	for _, params := range [][]interface{}{
+		{strings.ToLower(checksummed), "0x1a"},
+		{checksummed, "0x01a"},
+		{strings.ToLower(checksummed), "0x1A"},
+	} {
+		response, err := cache.Request(ctx, jsonrpc.MustRequest(1, "eth_getCode", params...))
+		require.NoError(t, err)
+		require.Equal(t, json.RawMessage(`"0x6000"`), response.Result)
+
+		// what a caller does with its result does not reach the cache
+		response.Result[1] = 'X'
+	}
+
+	require.Equal(t, 1, backend.count("eth_getCode"))
+	require.Equal(t, uint64(2), cache.Stats().Hits)
+}
+
+func TestCachingRequester_SlowHead(t *testing.T) {
+	backend := &countingBackend{
+		head:     1000,
+		results:  map[string]string{"eth_getCode": `"0x6000"`},
+		calls:    make(map[string]int),
+		headGate: make(chan struct{}),
+	}
+
+	cache := NewCachingRequester(backend, CacheConfig{FinalityDepth: 10})
+	request := func(ctx context.Context) error {
+		_, err := cache.Request(ctx, jsonrpc.MustRequest(1, "eth_getCode", "0x0000000000000000000000000000000000000001", "0x10"))
+		return err
+	}
+
+	// the first request waits for the head without a deadline of its own
+	first := make(chan error, 1)
+	go func() {
+		first <- request(context.Background())
+	}()
+
+	// while a caller in a hurry gets its response without waiting for the same lookup
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	require.NoError(t, request(ctx))
+	require.True(t, time.Since(start) < time.Second, "request took %s", time.Since(start))
+
+	// and its deadline does not stop the head from being found for everyone else
+	close(backend.headGate)
+	require.NoError(t, <-first)
+	require.NoError(t, request(context.Background()))
+
+	require.Equal(t, 1, backend.count("eth_blockNumber"))
+	require.Equal(t, 2, backend.count("eth_getCode"))
+	require.Equal(t, uint64(1), cache.Stats().Hits)
+}
+
+func TestCachingRequester_Eviction(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{
+		results: map[string]string{"eth_getBlockByHash": `"` + strings.Repeat("a", 100) + `"`},
+		calls:   make(map[string]int),
+	}
+
+	cache := NewCachingRequester(backend, CacheConfig{MaxBytes: 500})
+	hashes := []string{
+		"0x0000000000000000000000000000000000000000000000000000000000000001",
+		"0x0000000000000000000000000000000000000000000000000000000000000002",
+		"0x0000000000000000000000000000000000000000000000000000000000000003",
+	}
+
+	for _, hash := range append(hashes, hashes[0], hashes[2]) {
+		_, err := cache.Request(ctx, jsonrpc.MustRequest(1, "eth_getBlockByHash", hash, false))
+		require.NoError(t, err)
+	}
+
+	// only two entries fit, so the third evicted the first, and fetching it again evicted the second
+	require.Equal(t, 2, cache.Stats().Entries)
+	require.True(t, cache.Stats().Bytes <= 500)
+	require.Equal(t, 4, backend.count("eth_getBlockByHash"))
+}
@@ -0,0 +1,382 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// ErrNoInteraction is returned by a Replayer for requests that don't match anything left in its cassette.
+var ErrNoInteraction = errors.New("no recorded interaction matches request")
+
+// Interaction is a single request and what came back for it.
+type Interaction struct {
+	Request  *jsonrpc.Request     `json:"request"`
+	Response *jsonrpc.RawResponse `json:"response,omitempty"`
+
+	// Error is set instead of Response if the request failed without a response
+	Error string `json:"error,omitempty"`
+
+	// Notifications are those read from a subscription made by the request, in order
+	Notifications []jsonrpc.Notification `json:"notifications,omitempty"`
+}
+
+// Cassette is a recording of traffic with a node, which can be saved to a file and later replayed.
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// LoadCassette reads a cassette saved by Cassette.Save.
+func LoadCassette(path string) (*Cassette, error) {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not read cassette")
+	}
+
+	c := Cassette{}
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errors.Wrap(err, "could not decode cassette")
+	}
+
+	return &c, nil
+}
+
+// Save writes the cassette to path as indented JSON, so it can be reviewed and edited by hand.
+func (c *Cassette) Save(path string) error {
+	b, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return errors.Wrap(err, "could not encode cassette")
+	}
+
+	return errors.Wrap(ioutil.WriteFile(path, b, 0644), "could not write cassette")
+}
+
+// Recorder is a Requester and Subscriber which passes everything through to a real node while recording
+// it to a cassette.  Batches sent through a client using the recorder are recorded as individual requests.
+type Recorder struct {
+	requester  Requester
+	subscriber Subscriber
+
+	mu       sync.Mutex
+	cassette Cassette
+}
+
+// NewRecorder records the traffic sent to requester and subscriber, which may be nil if subscriptions aren't needed.
+// A Client can be passed as both.
+func NewRecorder(requester Requester, subscriber Subscriber) *Recorder {
+	return &Recorder{
+		requester:  requester,
+		subscriber: subscriber,
+	}
+}
+
+// Cassette returns a copy of everything recorded so far.
+func (r *Recorder) Cassette() *Cassette {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	c := Cassette{Interactions: make([]Interaction, len(r.cassette.Interactions))}
+	for i, interaction := range r.cassette.Interactions {
+		interaction.Notifications = append([]jsonrpc.Notification(nil), interaction.Notifications...)
+		c.Interactions[i] = interaction
+	}
+
+	return &c
+}
+
+// Save writes everything recorded so far to path.
+func (r *Recorder) Save(path string) error {
+	return r.Cassette().Save(path)
+}
+
+// record adds an interaction to the cassette, returning its index
+func (r *Recorder) record(request *jsonrpc.Request, response *jsonrpc.RawResponse, err error) int {
+	interaction := Interaction{Request: request, Response: response}
+	if err != nil {
+		interaction.Error = err.Error()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	return len(r.cassette.Interactions) - 1
+}
+
+func (r *Recorder) Request(ctx context.Context, request *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	// keep our own copy in case the caller reuses the request
+	recorded := *request
+
+	response, err := r.requester.Request(ctx, request)
+	r.record(&recorded, response, err)
+	return response, err
+}
+
+func (r *Recorder) Subscribe(ctx context.Context, request *jsonrpc.Request) (Subscription, error) {
+	if r.subscriber == nil {
+		return nil, errors.New("subscriptions not supported by this recorder")
+	}
+
+	recorded := *request
+
+	sub, err := r.subscriber.Subscribe(ctx, request)
+	if err != nil {
+		r.record(&recorded, nil, err)
+		return nil, err
+	}
+
+	i := r.record(&recorded, sub.Response(), nil)
+
+	s := recordedSubscription{
+		Subscription: sub,
+		ch:           make(chan *jsonrpc.Notification),
+		doneCh:       make(chan struct{}),
+		stopCh:       make(chan struct{}),
+	}
+
+	go func() {
+		defer close(s.doneCh)
+		defer close(s.ch)
+
+		for n := range sub.Ch() {
+			r.mu.Lock()
+			r.cassette.Interactions[i].Notifications = append(r.cassette.Interactions[i].Notifications, *n)
+			r.mu.Unlock()
+
+			// a caller that unsubscribed may well have stopped reading
+			select {
+			case s.ch <- n:
+			case <-s.stopCh:
+				return
+			case <-sub.Done():
+				return
+			}
+		}
+	}()
+
+	return &s, nil
+}
+
+// recordedSubscription passes on notifications from a subscription once they have been recorded
+type recordedSubscription struct {
+	Subscription
+	ch     chan *jsonrpc.Notification
+	doneCh chan struct{}
+
+	stopCh   chan struct{}
+	stopOnce sync.Once
+}
+
+func (s *recordedSubscription) Unsubscribe(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopCh) })
+	return s.Subscription.Unsubscribe(ctx)
+}
+
+func (s *recordedSubscription) Ch() <-chan *jsonrpc.Notification {
+	return s.ch
+}
+
+func (s *recordedSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// MatchMode is how strictly a Replayer matches requests against its cassette.
+type MatchMode int
+
+const (
+	// MatchParams matches on method and params, in any order.  Params match regardless of whitespace and key order.
+	MatchParams MatchMode = iota
+	// MatchSequence matches on method and params, and requests must be made in the order they were recorded
+	MatchSequence
+	// MatchMethod matches on method alone, in any order
+	MatchMethod
+)
+
+// ReplayConfig controls how a Replayer serves its cassette.
+type ReplayConfig struct {
+	// Match is how requests are matched against the cassette, defaults to MatchParams
+	Match MatchMode
+
+	// Repeat allows an interaction to be replayed more than once.  Interactions are normally used up as they
+	// are replayed, so identical requests are answered in the order they were recorded.  With Repeat the
+	// last matching interaction keeps being replayed once the others have been used.
+	Repeat bool
+}
+
+// Replayer is a Requester and Subscriber which answers requests from a cassette, for use with NewCustomClient
+// in tests that shouldn't depend on a real node.  Subscriptions deliver their recorded notifications straight
+// away and then stay open until they are unsubscribed.
+type Replayer struct {
+	cassette *Cassette
+	config   ReplayConfig
+
+	mu            sync.Mutex
+	used          []bool
+	subscriptions map[string]*subscription
+}
+
+// NewReplayer serves requests from cassette according to config.
+func NewReplayer(cassette *Cassette, config ReplayConfig) *Replayer {
+	return &Replayer{
+		cassette:      cassette,
+		config:        config,
+		used:          make([]bool, len(cassette.Interactions)),
+		subscriptions: make(map[string]*subscription),
+	}
+}
+
+// Unused returns the interactions that haven't been replayed yet, so tests can check everything expected was asked for.
+func (r *Replayer) Unused() []Interaction {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	unused := make([]Interaction, 0)
+	for i, interaction := range r.cassette.Interactions {
+		if !r.used[i] {
+			unused = append(unused, interaction)
+		}
+	}
+
+	return unused
+}
+
+func (r *Replayer) matches(recorded, request *jsonrpc.Request) bool {
+	if recorded == nil || recorded.Method != request.Method {
+		return false
+	}
+
+	if r.config.Match == MatchMethod {
+		return true
+	}
+
+	a, err := requestKey(recorded)
+	if err != nil {
+		return false
+	}
+
+	b, err := requestKey(request)
+	if err != nil {
+		return false
+	}
+
+	return a == b
+}
+
+// next finds the interaction to replay for request and marks it as used
+func (r *Replayer) next(request *jsonrpc.Request) (*Interaction, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	last := -1
+	for i := range r.cassette.Interactions {
+		interaction := &r.cassette.Interactions[i]
+
+		if r.used[i] {
+			if r.matches(interaction.Request, request) {
+				last = i
+			}
+			continue
+		}
+
+		if r.matches(interaction.Request, request) {
+			r.used[i] = true
+			return interaction, nil
+		}
+
+		if r.config.Match == MatchSequence {
+			// the next request we expected was something else
+			return nil, errors.Wrapf(ErrNoInteraction, "expected %s next but got %s", interaction.Request.Method, request.Method)
+		}
+	}
+
+	if r.config.Repeat && last >= 0 {
+		return &r.cassette.Interactions[last], nil
+	}
+
+	return nil, errors.Wrapf(ErrNoInteraction, "%s", request.Method)
+}
+
+func (r *Replayer) Request(ctx context.Context, request *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	if request.Method == "eth_unsubscribe" {
+		// unsubscribing isn't recorded, since it goes straight through the real subscription
+		var id string
+		if request.Params.UnmarshalInto(&id) == nil {
+			r.mu.Lock()
+			sub, ok := r.subscriptions[id]
+			delete(r.subscriptions, id)
+			r.mu.Unlock()
+
+			if ok {
+				sub.terminate(ctx, TerminationUnsubscribed, nil)
+				return &jsonrpc.RawResponse{
+					JSONRPC: "2.0",
+					ID:      request.ID,
+					Result:  json.RawMessage(`true`),
+				}, nil
+			}
+		}
+	}
+
+	interaction, err := r.next(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+
+	return copyResponse(interaction.Response, request.ID), nil
+}
+
+func (r *Replayer) Subscribe(ctx context.Context, request *jsonrpc.Request) (Subscription, error) {
+	interaction, err := r.next(request)
+	if err != nil {
+		return nil, err
+	}
+
+	if interaction.Error != "" {
+		return nil, errors.New(interaction.Error)
+	}
+
+	if interaction.Response == nil {
+		return nil, errors.New("recorded subscription has no response")
+	}
+
+	response := copyResponse(interaction.Response, request.ID)
+	if response.Error != nil {
+		return nil, errors.New(string(*response.Error))
+	}
+
+	var id string
+	if err := json.Unmarshal(response.Result, &id); err != nil {
+		return nil, errors.Wrap(err, "could not decode recorded subscription ID")
+	}
+
+	sub := newSubscription(response, id, r)
+
+	r.mu.Lock()
+	r.subscriptions[id] = sub
+	r.mu.Unlock()
+
+	notifications := append([]jsonrpc.Notification(nil), interaction.Notifications...)
+	go func() {
+		for _, n := range notifications {
+			sub.dispatch(context.Background(), n)
+		}
+	}()
+
+	return sub, nil
+}
+
+var (
+	_ Requester  = (*Recorder)(nil)
+	_ Subscriber = (*Recorder)(nil)
+	_ Requester  = (*Replayer)(nil)
+	_ Subscriber = (*Replayer)(nil)
+)
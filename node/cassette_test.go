@@ -0,0 +1,195 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func TestCassette_RecordAndReplay(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend := &countingBackend{
+		head: 0x10,
+		results: map[string]string{
+			"eth_chainId": `"0x1"`,
+			"eth_getCode": `"0x6000"`,
+		},
+		calls: make(map[string]int),
+	}
+	tr, pipe := newPipeTransport(t, ctx)
+
+	recorder := NewRecorder(backend, tr)
+	c, err := NewCustomClient(recorder, recorder)
+	require.NoError(t, err)
+
+	n, err := c.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x10), n)
+
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_getCode", "0x0000000000000000000000000000000000000001", "latest"))
+	require.NoError(t, err)
+
+	go func() {
+		r := pipe.next()
+		pipe.respond(r, "0x9")
+	}()
+
+	sub, err := c.SubscribeNewPendingTransactions(ctx)
+	require.NoError(t, err)
+
+	for _, hash := range []string{"0x1", "0x2"} {
+		pipe.send(&jsonrpc.Notification{Method: "eth_subscription", Params: json.RawMessage(`{"subscription":"0x9","result":"` + hash + `"}`)})
+		<-sub.Ch()
+	}
+
+	dir, err := ioutil.TempDir("", "cassette")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "cassette.json")
+	require.NoError(t, recorder.Save(path))
+
+	cassette, err := LoadCassette(path)
+	require.NoError(t, err)
+	require.Len(t, cassette.Interactions, 3)
+	require.Len(t, cassette.Interactions[2].Notifications, 2)
+
+	replayer := NewReplayer(cassette, ReplayConfig{})
+	replayed, err := NewCustomClient(replayer, replayer)
+	require.NoError(t, err)
+
+	// params match regardless of formatting, and the caller's ID comes back
+	response, err := replayed.Request(ctx, &jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 42},
+		Method: "eth_getCode",
+		Params: jsonrpc.Params{jsonrpc.Param(`"0x0000000000000000000000000000000000000001"`), jsonrpc.Param(` "latest" `)},
+	})
+	require.NoError(t, err)
+	require.Equal(t, jsonrpc.ID{Num: 42}, response.ID)
+	require.Equal(t, json.RawMessage(`"0x6000"`), response.Result)
+
+	n, err = replayed.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x10), n)
+
+	// each interaction is only replayed once
+	_, err = replayed.BlockNumber(ctx)
+	require.Equal(t, ErrNoInteraction, errors.Cause(err))
+
+	_, err = replayed.Request(ctx, jsonrpc.MustRequest(1, "eth_getCode", "0x0000000000000000000000000000000000000001", "0x1"))
+	require.Equal(t, ErrNoInteraction, errors.Cause(err))
+
+	replayedSub, err := replayed.SubscribeNewPendingTransactions(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x9", replayedSub.ID())
+
+	for _, hash := range []string{"0x1", "0x2"} {
+		n := <-replayedSub.Ch()
+		params := SubscriptionParams{}
+		require.NoError(t, n.UnmarshalParamsInto(&params))
+		require.Equal(t, `"`+hash+`"`, string(params.Result))
+	}
+
+	require.NoError(t, replayedSub.Unsubscribe(ctx))
+	requireTerminated(t, replayedSub, TerminationUnsubscribed)
+	require.Empty(t, replayer.Unused())
+}
+
+func TestReplayer_Strictness(t *testing.T) {
+	ctx := context.Background()
+	cassette := &Cassette{Interactions: []Interaction{
+		{Request: jsonrpc.MustRequest(1, "eth_blockNumber"), Response: &jsonrpc.RawResponse{Result: json.RawMessage(`"0x1"`)}},
+		{Request: jsonrpc.MustRequest(1, "eth_getBalance", "0x0000000000000000000000000000000000000001", "0x1"), Error: "connection refused"},
+		{Request: jsonrpc.MustRequest(1, "eth_blockNumber"), Response: &jsonrpc.RawResponse{Result: json.RawMessage(`"0x2"`)}},
+	}}
+
+	blockNumber := func(r *Replayer) (uint64, error) {
+		c, err := NewCustomClient(r, nil)
+		require.NoError(t, err)
+		return c.BlockNumber(ctx)
+	}
+
+	t.Run("sequence", func(t *testing.T) {
+		r := NewReplayer(cassette, ReplayConfig{Match: MatchSequence})
+
+		n, err := blockNumber(r)
+		require.NoError(t, err)
+		require.Equal(t, uint64(1), n)
+
+		_, err = blockNumber(r)
+		require.Equal(t, ErrNoInteraction, errors.Cause(err))
+		require.Len(t, r.Unused(), 2)
+	})
+
+	t.Run("method", func(t *testing.T) {
+		r := NewReplayer(cassette, ReplayConfig{Match: MatchMethod})
+
+		_, err := r.Request(ctx, jsonrpc.MustRequest(1, "eth_getBalance", "0x0000000000000000000000000000000000000002", "latest"))
+		require.EqualError(t, err, "connection refused")
+	})
+
+	t.Run("truncated subscription", func(t *testing.T) {
+		r := NewReplayer(&Cassette{Interactions: []Interaction{
+			{Request: jsonrpc.MustRequest(1, "eth_subscribe", "newHeads")},
+		}}, ReplayConfig{})
+
+		_, err := r.Subscribe(ctx, jsonrpc.MustRequest(1, "eth_subscribe", "newHeads"))
+		require.EqualError(t, err, "recorded subscription has no response")
+	})
+
+	t.Run("repeat", func(t *testing.T) {
+		r := NewReplayer(cassette, ReplayConfig{Repeat: true})
+
+		for _, expected := range []uint64{1, 2, 2, 2} {
+			n, err := blockNumber(r)
+			require.NoError(t, err)
+			require.Equal(t, expected, n)
+		}
+	})
+}
+
+func TestRecorder_UnsubscribeWithoutReading(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr, pipe := newPipeTransport(t, ctx)
+	recorder := NewRecorder(tr, tr)
+
+	go func() {
+		r := pipe.next()
+		pipe.respond(r, "0x9")
+	}()
+
+	sub, err := recorder.Subscribe(ctx, jsonrpc.MustRequest(1, "eth_subscribe", "newPendingTransactions"))
+	require.NoError(t, err)
+
+	// a notification nobody reads must not keep the recorder around once the caller unsubscribes
+	pipe.send(&jsonrpc.Notification{Method: "eth_subscription", Params: json.RawMessage(`{"subscription":"0x9","result":"0x1"}`)})
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.Cassette().Interactions[0].Notifications) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	go func() {
+		r := pipe.next()
+		pipe.respond(r, true)
+	}()
+
+	require.NoError(t, sub.Unsubscribe(ctx))
+	select {
+	case <-sub.Done():
+	case <-time.After(time.Second):
+		t.Fatal("recorded subscription did not finish after unsubscribing")
+	}
+}
@@ -4,7 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"net/url"
+	"math/big"
 
 	"github.com/pkg/errors"
 
@@ -15,51 +15,36 @@ import (
 var (
 	ErrBlockNotFound       = errors.New("block not found")
 	ErrTransactionNotFound = errors.New("transaction not found")
+	ErrQuantityOverflow    = errors.New("quantity does not fit in uint64")
+
+	// ErrLogsSubscriptionRange is returned by SubscribeLogs for filters with a block range, which nodes reject
+	// since a subscription only ever sees new logs
+	ErrLogsSubscriptionRange = errors.New("logs subscriptions do not take fromBlock or toBlock")
 )
 
 var _ Client = (*client)(nil)
 
 func NewClient(ctx context.Context, rawURL string) (Client, error) {
-	parsedURL, err := url.Parse(rawURL)
-	if err != nil {
-		return nil, errors.Wrap(err, "could not parse url")
-	}
-
-	var transport transport
-
-	switch parsedURL.Scheme {
-	case "http", "https":
-		transport, err = newHTTPTransport(ctx, parsedURL)
-	case "wss", "ws":
-		transport, err = newWebsocketTransport(ctx, parsedURL)
-	default:
-		transport, err = newIPCTransport(ctx, parsedURL)
-	}
-
-	if err != nil {
-		return nil, errors.Wrap(err, "could not create client transport")
-	}
-
-	return &client{
-		transport: transport,
-		rawURL:    rawURL,
-	}, nil
+	return NewClientWithOptions(ctx, rawURL)
 }
 
-func NewCustomClient(requester Requester, subscriber Subscriber) (Client, error) {
+// NewCustomClient returns a Client that sends requests with requester and makes subscriptions with subscriber,
+// which may be nil.  Of the opts only the interceptors apply, since there is no transport to configure.
+func NewCustomClient(requester Requester, subscriber Subscriber, opts ...Option) (Client, error) {
 	t, err := newCustomTransport(requester, subscriber)
 	if err != nil {
 		return nil, errors.Wrap(err, "could not create custom transport")
 	}
 
 	return &client{
-		transport: t,
+		transport: intercept(t, newClientOptions(opts...)),
 		rawURL:    "",
 	}, nil
 }
 
 type transport interface {
 	Requester
+	BatchRequester
 	Subscriber
 
 	IsBidirectional() bool
@@ -74,6 +59,10 @@ func (c *client) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawR
 	return c.transport.Request(ctx, r)
 }
 
+func (c *client) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	return c.transport.RequestBatch(ctx, batch)
+}
+
 func (c *client) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
 	return c.transport.Subscribe(ctx, r)
 }
@@ -108,7 +97,7 @@ func (c *client) BlockNumber(ctx context.Context) (uint64, error) {
 		return 0, errors.Wrap(err, "could not decode result")
 	}
 
-	return q.UInt64(), nil
+	return bigToUInt64(q.Big())
 }
 
 func (c *client) GetTransactionCount(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
@@ -134,7 +123,7 @@ func (c *client) GetTransactionCount(ctx context.Context, address eth.Address, n
 		return 0, errors.Wrap(err, "could not decode result")
 	}
 
-	return q.UInt64(), err
+	return bigToUInt64(q.Big())
 }
 
 func (c *client) NetVersion(ctx context.Context) (string, error) {
@@ -206,6 +195,15 @@ func (c *client) BlockByNumber(ctx context.Context, numberOrTag eth.BlockNumberO
 }
 
 func (c *client) EstimateGas(ctx context.Context, msg eth.Transaction) (uint64, error) {
+	gas, err := c.EstimateGasBig(ctx, msg)
+	if err != nil {
+		return 0, err
+	}
+
+	return bigToUInt64(gas)
+}
+
+func (c *client) EstimateGasBig(ctx context.Context, msg eth.Transaction) (*big.Int, error) {
 	arg := map[string]interface{}{
 		"from":  msg.From,
 		"to":    msg.To,
@@ -220,22 +218,8 @@ func (c *client) EstimateGas(ctx context.Context, msg eth.Transaction) (uint64,
 		Method: "eth_estimateGas",
 		Params: jsonrpc.MustParams(arg),
 	}
-	applyContext(ctx, &request)
-	response, err := c.Request(ctx, &request)
-	if err != nil {
-		return 0, errors.Wrap(err, "could not make request")
-	}
-
-	if response.Error != nil {
-		return 0, errors.New(string(*response.Error))
-	}
 
-	q := eth.Quantity{}
-	err = json.Unmarshal(response.Result, &q)
-	if err != nil {
-		return 0, errors.Wrap(err, "could not decode result")
-	}
-	return q.UInt64(), err
+	return c.requestBig(ctx, &request)
 }
 
 func (c *client) SendRawTransaction(ctx context.Context, msg string) (string, error) {
@@ -265,72 +249,129 @@ func (c *client) SendRawTransaction(ctx context.Context, msg string) (string, er
 }
 
 func (c *client) MaxPriorityFeePerGas(ctx context.Context) (uint64, error) {
+	tip, err := c.MaxPriorityFeePerGasBig(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	return bigToUInt64(tip)
+}
+
+func (c *client) MaxPriorityFeePerGasBig(ctx context.Context) (*big.Int, error) {
 	request := jsonrpc.Request{
 		ID:     jsonrpc.ID{Num: 1},
 		Method: "eth_maxPriorityFeePerGas",
 		Params: nil,
 	}
 
-	applyContext(ctx, &request)
-	response, err := c.Request(ctx, &request)
-	if err != nil {
-		return 0, errors.Wrap(err, "could not make request")
-	}
+	return c.requestBig(ctx, &request)
+}
 
-	q := eth.Quantity{}
-	err = json.Unmarshal(response.Result, &q)
+func (c *client) GasPrice(ctx context.Context) (uint64, error) {
+	price, err := c.GasPriceBig(ctx)
 	if err != nil {
-		return 0, errors.Wrap(err, "could not decode result")
+		return 0, err
 	}
-	return q.UInt64(), err
+
+	return bigToUInt64(price)
 }
 
-func (c *client) GasPrice(ctx context.Context) (uint64, error) {
+func (c *client) GasPriceBig(ctx context.Context) (*big.Int, error) {
 	request := jsonrpc.Request{
 		ID:     jsonrpc.ID{Num: 1},
 		Method: "eth_gasPrice",
 		Params: nil,
 	}
 
+	return c.requestBig(ctx, &request)
+}
+
+func (c *client) FeeHistory(ctx context.Context, blockCount uint64, newest eth.BlockNumberOrTag, rewardPercentiles []float64) (*eth.FeeHistory, error) {
+	if rewardPercentiles == nil {
+		rewardPercentiles = []float64{}
+	}
+
+	request := jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 1},
+		Method: "eth_feeHistory",
+		Params: jsonrpc.MustParams(eth.QuantityFromUInt64(blockCount), &newest, rewardPercentiles),
+	}
+
 	applyContext(ctx, &request)
 	response, err := c.Request(ctx, &request)
 	if err != nil {
-		return 0, errors.Wrap(err, "could not make request")
+		return nil, errors.Wrap(err, "could not make request")
 	}
 
-	q := eth.Quantity{}
-	err = json.Unmarshal(response.Result, &q)
+	if response.Error != nil {
+		// decoded so that callers can tell apart the error codes of unsupported methods
+		e := jsonrpc.Error{}
+		if json.Unmarshal(*response.Error, &e) == nil {
+			return nil, &e
+		}
+		return nil, errors.New(string(*response.Error))
+	}
+
+	if len(response.Result) == 0 || bytes.Equal(response.Result, json.RawMessage(`null`)) {
+		return nil, errors.New("fee history not available")
+	}
+
+	history := eth.FeeHistory{}
+	err = json.Unmarshal(response.Result, &history)
 	if err != nil {
-		return 0, errors.Wrap(err, "could not decode result")
+		return nil, errors.Wrap(err, "could not unmarshal result")
 	}
 
-	return q.UInt64(), err
+	return &history, nil
 }
 
 func (c *client) GetBalance(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
+	balance, err := c.GetBalanceBig(ctx, address, numberOrTag)
+	if err != nil {
+		return 0, err
+	}
+
+	return bigToUInt64(balance)
+}
+
+func (c *client) GetBalanceBig(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (*big.Int, error) {
 	request := jsonrpc.Request{
 		ID:     jsonrpc.ID{Num: 1},
 		Method: "eth_getBalance",
 		Params: jsonrpc.MustParams(address, &numberOrTag),
 	}
 
-	applyContext(ctx, &request)
-	response, err := c.Request(ctx, &request)
+	return c.requestBig(ctx, &request)
+}
+
+// requestBig sends a request whose result is a single quantity and returns it with full precision.
+func (c *client) requestBig(ctx context.Context, request *jsonrpc.Request) (*big.Int, error) {
+	applyContext(ctx, request)
+	response, err := c.Request(ctx, request)
 	if err != nil {
-		return 0, errors.Wrap(err, "could not make request")
+		return nil, errors.Wrap(err, "could not make request")
 	}
 
 	if response.Error != nil {
-		return 0, errors.New(string(*response.Error))
+		return nil, errors.New(string(*response.Error))
 	}
 
 	q := eth.Quantity{}
 	err = json.Unmarshal(response.Result, &q)
 	if err != nil {
-		return 0, errors.Wrap(err, "could not decode result")
+		return nil, errors.Wrap(err, "could not decode result")
+	}
+
+	return q.Big(), nil
+}
+
+// bigToUInt64 narrows i to a uint64, returning ErrQuantityOverflow rather than silently truncating.
+func bigToUInt64(i *big.Int) (uint64, error) {
+	if !i.IsUint64() {
+		return 0, errors.Wrapf(ErrQuantityOverflow, "value %s", i.String())
 	}
 
-	return q.UInt64(), err
+	return i.Uint64(), nil
 }
 
 func (c *client) BlockByNumberOrTag(ctx context.Context, numberOrTag eth.BlockNumberOrTag, full bool) (*eth.Block, error) {
@@ -447,6 +488,78 @@ func (c *client) Logs(ctx context.Context, filter eth.LogFilter) ([]eth.Log, err
 	return _logs, nil
 }
 
+func (c *client) NewFilter(ctx context.Context, filter eth.LogFilter) (*Filter, error) {
+	id, err := installFilter(ctx, c, "eth_newFilter", filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterHandle(ctx, c, id, FilterTypeLogs, &filter), nil
+}
+
+func (c *client) NewBlockFilter(ctx context.Context) (*Filter, error) {
+	id, err := installFilter(ctx, c, "eth_newBlockFilter")
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterHandle(ctx, c, id, FilterTypeBlocks, nil), nil
+}
+
+func (c *client) NewPendingTransactionFilter(ctx context.Context) (*Filter, error) {
+	id, err := installFilter(ctx, c, "eth_newPendingTransactionFilter")
+	if err != nil {
+		return nil, err
+	}
+
+	return newFilterHandle(ctx, c, id, FilterTypePendingTransactions, nil), nil
+}
+
+func (c *client) GetFilterChanges(ctx context.Context, id string) (*FilterChanges, error) {
+	result, err := filterRequest(ctx, c, "eth_getFilterChanges", id)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := FilterChanges{}
+	err = json.Unmarshal(result, &changes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode result")
+	}
+
+	return &changes, nil
+}
+
+func (c *client) GetFilterLogs(ctx context.Context, id string) ([]eth.Log, error) {
+	result, err := filterRequest(ctx, c, "eth_getFilterLogs", id)
+	if err != nil {
+		return nil, err
+	}
+
+	_logs := make([]eth.Log, 0)
+	err = json.Unmarshal(result, &_logs)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode result")
+	}
+
+	return _logs, nil
+}
+
+func (c *client) UninstallFilter(ctx context.Context, id string) (bool, error) {
+	result, err := filterRequest(ctx, c, "eth_uninstallFilter", id)
+	if err != nil {
+		return false, err
+	}
+
+	uninstalled := false
+	err = json.Unmarshal(result, &uninstalled)
+	if err != nil {
+		return false, errors.Wrap(err, "could not decode result")
+	}
+
+	return uninstalled, nil
+}
+
 func (c *client) TransactionByHash(ctx context.Context, hash string) (*eth.Transaction, error) {
 	h, err := eth.NewHash(hash)
 	if err != nil {
@@ -499,6 +612,46 @@ func (c *client) SubscribeNewPendingTransactions(ctx context.Context) (Subscript
 	return c.Subscribe(ctx, &request)
 }
 
+func (c *client) SubscribeNewPendingTransactionBodies(ctx context.Context) (Subscription, error) {
+	request := jsonrpc.Request{
+		JSONRPC: "2.0",
+		ID:      jsonrpc.ID{Str: "pendingBodies", IsString: true},
+		Method:  "eth_subscribe",
+		Params:  jsonrpc.MustParams("newPendingTransactions", true),
+	}
+
+	applyContext(ctx, &request)
+	return c.Subscribe(ctx, &request)
+}
+
+func (c *client) SubscribeLogs(ctx context.Context, filter eth.LogFilter) (Subscription, error) {
+	if filter.FromBlock != nil || filter.ToBlock != nil {
+		return nil, ErrLogsSubscriptionRange
+	}
+
+	request := jsonrpc.Request{
+		JSONRPC: "2.0",
+		ID:      jsonrpc.ID{Str: "logs", IsString: true},
+		Method:  "eth_subscribe",
+		Params:  jsonrpc.MustParams("logs", filter),
+	}
+
+	applyContext(ctx, &request)
+	return c.Subscribe(ctx, &request)
+}
+
+func (c *client) SubscribeSyncing(ctx context.Context) (Subscription, error) {
+	request := jsonrpc.Request{
+		JSONRPC: "2.0",
+		ID:      jsonrpc.ID{Str: "syncing", IsString: true},
+		Method:  "eth_subscribe",
+		Params:  jsonrpc.MustParams("syncing"),
+	}
+
+	applyContext(ctx, &request)
+	return c.Subscribe(ctx, &request)
+}
+
 func applyContext(ctx context.Context, request *jsonrpc.Request) {
 	if id := requestIDFromContext(ctx); id != nil {
 		request.ID = *id
@@ -537,7 +690,7 @@ func (c *client) GetBlockTransactionCountByHash(ctx context.Context, hash string
 		return 0, errors.Wrap(err, "could not decode result")
 	}
 
-	return q.UInt64(), nil
+	return bigToUInt64(q.Big())
 }
 
 func (c *client) GetBlockTransactionCountByNumber(ctx context.Context, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
@@ -567,7 +720,7 @@ func (c *client) GetBlockTransactionCountByNumber(ctx context.Context, numberOrT
 		return 0, errors.Wrap(err, "could not decode result")
 	}
 
-	return q.UInt64(), nil
+	return bigToUInt64(q.Big())
 }
 
 func (c *client) GetCode(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (string, error) {
@@ -596,6 +749,66 @@ func (c *client) GetCode(ctx context.Context, address eth.Address, numberOrTag e
 	return code, nil
 }
 
+func (c *client) GetStorageAt(ctx context.Context, address eth.Address, slot eth.Data, numberOrTag eth.BlockNumberOrTag) (eth.Data, error) {
+	request := jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 1},
+		Method: "eth_getStorageAt",
+		Params: jsonrpc.MustParams(address, slot, &numberOrTag),
+	}
+
+	applyContext(ctx, &request)
+	response, err := c.Request(ctx, &request)
+	if err != nil {
+		return "", errors.Wrap(err, "could not make request")
+	}
+
+	if response.Error != nil {
+		return "", errors.New(string(*response.Error))
+	}
+
+	var value eth.Data
+	err = json.Unmarshal(response.Result, &value)
+	if err != nil {
+		return "", errors.Wrap(err, "could not decode result")
+	}
+
+	return value, nil
+}
+
+func (c *client) GetProof(ctx context.Context, address eth.Address, slots []eth.Data, numberOrTag eth.BlockNumberOrTag) (*eth.AccountProof, error) {
+	if slots == nil {
+		slots = []eth.Data{}
+	}
+
+	request := jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 1},
+		Method: "eth_getProof",
+		Params: jsonrpc.MustParams(address, slots, &numberOrTag),
+	}
+
+	applyContext(ctx, &request)
+	response, err := c.Request(ctx, &request)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make request")
+	}
+
+	if response.Error != nil {
+		return nil, errors.New(string(*response.Error))
+	}
+
+	if len(response.Result) == 0 || bytes.Equal(response.Result, json.RawMessage(`null`)) {
+		return nil, errors.Errorf("proof for account %s not found", address.String())
+	}
+
+	proof := eth.AccountProof{}
+	err = json.Unmarshal(response.Result, &proof)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not unmarshal result")
+	}
+
+	return &proof, nil
+}
+
 func (c *client) SendTransaction(ctx context.Context, msg eth.Transaction) (string, error) {
 	request := jsonrpc.Request{
 		ID:     jsonrpc.ID{Num: 1},
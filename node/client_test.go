@@ -0,0 +1,124 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// requesterFunc adapts a function to the Requester interface
+type requesterFunc func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error)
+
+func (f requesterFunc) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	return f(ctx, r)
+}
+
+// newResultClient returns a Client whose requests are answered with the results in the passed in map, keyed by method
+func newResultClient(t *testing.T, results map[string]interface{}) Client {
+	c, err := NewCustomClient(requesterFunc(func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		result, ok := results[r.Method]
+		if !ok {
+			return nil, errors.Errorf("unexpected method %s", r.Method)
+		}
+
+		raw, err := json.Marshal(result)
+		require.NoError(t, err)
+		return &jsonrpc.RawResponse{ID: r.ID, Result: raw}, nil
+	}), nil)
+	require.NoError(t, err)
+	return c
+}
+
+func TestClient_BigQuantities(t *testing.T) {
+	ctx := context.Background()
+	huge := "0x3635c9adc5dea00000" // 1000 ETH in wei
+	c := newResultClient(t, map[string]interface{}{
+		"eth_getBalance":           huge,
+		"eth_gasPrice":             huge,
+		"eth_maxPriorityFeePerGas": "0x3b9aca00",
+		"eth_estimateGas":          "0x5208",
+		"eth_blockNumber":          "0x10000000000000000",
+	})
+
+	expected, ok := new(big.Int).SetString("1000000000000000000000", 10)
+	require.True(t, ok)
+
+	balance, err := c.GetBalanceBig(ctx, "0xed28874e52A12f0D42118653B0FBCee0ACFadC00", *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, 0, expected.Cmp(balance))
+
+	_, err = c.GetBalance(ctx, "0xed28874e52A12f0D42118653B0FBCee0ACFadC00", *eth.MustBlockNumberOrTag("latest"))
+	require.Equal(t, ErrQuantityOverflow, errors.Cause(err))
+
+	price, err := c.GasPriceBig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, 0, expected.Cmp(price))
+
+	_, err = c.GasPrice(ctx)
+	require.Equal(t, ErrQuantityOverflow, errors.Cause(err))
+
+	tip, err := c.MaxPriorityFeePerGas(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000000000), tip)
+
+	gas, err := c.EstimateGas(ctx, eth.Transaction{})
+	require.NoError(t, err)
+	require.Equal(t, uint64(21000), gas)
+
+	_, err = c.BlockNumber(ctx)
+	require.Equal(t, ErrQuantityOverflow, errors.Cause(err))
+}
+
+func TestClient_AccountState(t *testing.T) {
+	ctx := context.Background()
+	address := *eth.MustAddress("0x7F0d15C7FAae65896648C8273B6d7E43f58Fa842")
+	slot := *eth.MustData("0x0")
+
+	var params []jsonrpc.Params
+	c, err := NewCustomClient(requesterFunc(func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		params = append(params, r.Params)
+		switch r.Method {
+		case "eth_getStorageAt":
+			return &jsonrpc.RawResponse{ID: r.ID, Result: json.RawMessage(`"0x000000000000000000000000000000000000000000000000000000000000002a"`)}, nil
+		case "eth_getProof":
+			return &jsonrpc.RawResponse{ID: r.ID, Result: json.RawMessage(`{
+				"address": "0x7f0d15c7faae65896648c8273b6d7e43f58fa842",
+				"accountProof": ["0xf90211a0", "0xf8669d"],
+				"balance": "0x0",
+				"codeHash": "0xc5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+				"nonce": "0x1",
+				"storageHash": "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421",
+				"storageProof": [{"key": "0x0", "value": "0x2a", "proof": ["0xe3a1"]}]
+			}`)}, nil
+		}
+		return nil, errors.Errorf("unexpected method %s", r.Method)
+	}), nil)
+	require.NoError(t, err)
+
+	value, err := c.GetStorageAt(ctx, address, slot, *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, byte(42), value.Bytes()[31])
+
+	proof, err := c.GetProof(ctx, address, []eth.Data{slot}, *eth.MustBlockNumberOrTag("0x10"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), proof.Nonce.UInt64())
+	require.Equal(t, []eth.Data{"0xf90211a0", "0xf8669d"}, proof.AccountProof)
+	require.Len(t, proof.StorageProof, 1)
+	require.Equal(t, uint64(42), proof.StorageProof[0].Value.UInt64())
+	require.Equal(t, []eth.Data{"0xe3a1"}, proof.StorageProof[0].Proof)
+
+	require.Equal(t, jsonrpc.MustParams(address, slot, "latest"), params[0])
+	require.Equal(t, jsonrpc.MustParams(address, []string{"0x0"}, "0x10"), params[1])
+
+	// no slots are still sent as an empty list
+	_, err = c.GetProof(ctx, address, nil, *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, jsonrpc.MustParams(address, []string{}, "latest"), params[2])
+}
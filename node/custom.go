@@ -17,6 +17,15 @@ func (t *customTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jso
 	return t.requester.Request(ctx, r)
 }
 
+func (t *customTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	if b, ok := t.requester.(BatchRequester); ok {
+		return b.RequestBatch(ctx, batch)
+	}
+
+	// the requester can't batch natively, so just fan the requests out individually
+	return requestBatchConcurrently(ctx, t.requester, batch)
+}
+
 func (t *customTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
 	if t.subscriber == nil {
 		return nil, errors.New("subscriptions not supported over this transport")
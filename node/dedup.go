@@ -0,0 +1,219 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// WithDeduplication collapses concurrent identical requests, with the same method and params, into a single
+// request to the backend whose response is shared by every caller with their own ID restored.  Only idempotent
+// methods are deduplicated, two eth_newFilter calls still create two filters.  The shared request is cancelled
+// once every caller waiting on it has given up, and has the latest deadline of those callers, or none if any of
+// them has none.
+func WithDeduplication() Option {
+	return func(o *clientOptions) {
+		d := newDeduplicator()
+		o.requestInterceptors = append(o.requestInterceptors, d.interceptRequest)
+	}
+}
+
+type deduplicator struct {
+	mu    sync.Mutex
+	calls map[string]*dedupCall
+}
+
+// dedupCall is a request in flight on behalf of one or more callers
+type dedupCall struct {
+	done     chan struct{}
+	response *jsonrpc.RawResponse
+	err      error
+	waiters  int
+	ctx      *sharedContext
+}
+
+func newDeduplicator() *deduplicator {
+	return &deduplicator{calls: make(map[string]*dedupCall)}
+}
+
+func (d *deduplicator) interceptRequest(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error) {
+	if !IsIdempotent(r.Method) {
+		return next(ctx, r)
+	}
+
+	key, err := requestKey(r)
+	if err != nil {
+		return next(ctx, r)
+	}
+
+	d.mu.Lock()
+	call, ok := d.calls[key]
+	if ok {
+		call.waiters++
+		call.ctx.extend(ctx)
+	} else {
+		call = &dedupCall{done: make(chan struct{}), waiters: 1, ctx: newSharedContext(ctx)}
+		d.calls[key] = call
+
+		// the request is shared, so don't let the inner chain see changes made by whoever started it
+		shared := *r
+		go d.run(key, call, &shared, next)
+	}
+	d.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if call.err != nil {
+			return nil, call.err
+		}
+
+		return copyResponse(call.response, r.ID), nil
+	case <-ctx.Done():
+		d.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			call.ctx.cancel()
+			if d.calls[key] == call {
+				delete(d.calls, key)
+			}
+		}
+		d.mu.Unlock()
+		return nil, errors.Wrap(ctx.Err(), "context finished waiting for response")
+	}
+}
+
+func (d *deduplicator) run(key string, call *dedupCall, r *jsonrpc.Request, next RequestInvoker) {
+	defer call.ctx.cancel()
+
+	response, err := next(call.ctx, r)
+
+	d.mu.Lock()
+	if d.calls[key] == call {
+		delete(d.calls, key)
+	}
+	d.mu.Unlock()
+
+	call.response, call.err = response, err
+	close(call.done)
+}
+
+// copyResponse returns a copy of response for the caller whose request had the given ID
+func copyResponse(response *jsonrpc.RawResponse, id jsonrpc.ID) *jsonrpc.RawResponse {
+	if response == nil {
+		return nil
+	}
+
+	patched := *response
+	patched.ID = id
+
+	if response.Result != nil {
+		patched.Result = append(json.RawMessage(nil), response.Result...)
+	}
+
+	if response.Error != nil {
+		e := append(json.RawMessage(nil), (*response.Error)...)
+		patched.Error = &e
+	}
+
+	return &patched
+}
+
+// requestKey identifies a request by its method and params, with each param re-encoded so that differences
+// in whitespace or object key order don't matter
+func requestKey(r *jsonrpc.Request) (string, error) {
+	key := bytes.NewBufferString(r.Method)
+	for _, p := range r.Params {
+		d := json.NewDecoder(bytes.NewReader(p))
+		d.UseNumber()
+
+		var v interface{}
+		if err := d.Decode(&v); err != nil {
+			return "", err
+		}
+
+		b, err := json.Marshal(v)
+		if err != nil {
+			return "", err
+		}
+
+		key.WriteByte(0)
+		key.Write(b)
+	}
+
+	return key.String(), nil
+}
+
+// sharedContext keeps the values of the context of the caller that started a shared request, and is cancelled
+// at the latest deadline of the callers waiting on it or once they have all given up
+type sharedContext struct {
+	context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func newSharedContext(ctx context.Context) *sharedContext {
+	inner, cancel := context.WithCancel(detachedContext{ctx})
+	s := sharedContext{Context: inner, cancel: cancel}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		s.deadline = deadline
+		s.timer = time.AfterFunc(time.Until(deadline), cancel)
+	}
+
+	return &s
+}
+
+// extend pushes the deadline out to that of ctx, or removes it if ctx has none
+func (s *sharedContext) extend(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer == nil {
+		return
+	}
+
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		s.timer.Stop()
+		s.timer, s.deadline = nil, time.Time{}
+		return
+	}
+
+	if deadline.After(s.deadline) && s.timer.Stop() {
+		s.deadline = deadline
+		s.timer.Reset(time.Until(deadline))
+	}
+}
+
+func (s *sharedContext) Deadline() (time.Time, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.deadline, !s.deadline.IsZero()
+}
+
+func (s *sharedContext) Err() error {
+	err := s.Context.Err()
+	if deadline, ok := s.Deadline(); err != nil && ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+
+	return err
+}
+
+// detachedContext keeps the values of a context, but not its deadline or cancellation
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) { return time.Time{}, false }
+func (detachedContext) Done() <-chan struct{}       { return nil }
+func (detachedContext) Err() error                  { return nil }
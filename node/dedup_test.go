@@ -0,0 +1,194 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// countingRequester wraps blockingRequester, counting the requests that reach it
+type countingRequester struct {
+	blockingRequester
+	count int32
+}
+
+func (c *countingRequester) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	atomic.AddInt32(&c.count, 1)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	default:
+	}
+	return c.blockingRequester.Request(ctx, r)
+}
+
+// waiters returns how many callers are waiting on the request with key
+func (d *deduplicator) waiters(key string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if call, ok := d.calls[key]; ok {
+		return call.waiters
+	}
+	return 0
+}
+
+func waitForWaiters(t *testing.T, d *deduplicator, key string, n int) {
+	deadline := time.Now().Add(time.Second)
+	for d.waiters(key) < n && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	require.Equal(t, n, d.waiters(key))
+}
+
+func TestDeduplication(t *testing.T) {
+	ctx := context.Background()
+	requester := &countingRequester{blockingRequester: blockingRequester{release: make(chan struct{})}}
+	d := newDeduplicator()
+
+	request := func(id int64, params ...jsonrpc.Param) *jsonrpc.Request {
+		return &jsonrpc.Request{ID: jsonrpc.ID{Num: uint64(id)}, Method: "eth_getBlockByNumber", Params: params}
+	}
+
+	// whitespace and key order don't make requests different
+	first := request(1, jsonrpc.Param(`{"a":1,"b":"latest"}`), jsonrpc.Param(`false`))
+	key, err := requestKey(first)
+	require.NoError(t, err)
+	same, err := requestKey(request(2, jsonrpc.Param(`{ "b": "latest", "a": 1 }`), jsonrpc.Param(` false`)))
+	require.NoError(t, err)
+	require.Equal(t, key, same)
+
+	wg := sync.WaitGroup{}
+	for i := 1; i <= 10; i++ {
+		wg.Add(1)
+		go func(id int64) {
+			defer wg.Done()
+			response, err := d.interceptRequest(ctx, request(id, jsonrpc.Param(`{"a":1,"b":"latest"}`), jsonrpc.Param(`false`)), requester.Request)
+			require.NoError(t, err)
+			require.Equal(t, jsonrpc.ID{Num: uint64(id)}, response.ID)
+			require.Equal(t, json.RawMessage(`"0x1"`), response.Result)
+		}(int64(i))
+	}
+
+	waitForWaiters(t, d, key, 10)
+	close(requester.release)
+	wg.Wait()
+	require.Equal(t, int32(1), atomic.LoadInt32(&requester.count))
+
+	// once finished the next request goes to the backend again
+	_, err = d.interceptRequest(ctx, first, requester.Request)
+	require.NoError(t, err)
+	require.Equal(t, int32(2), atomic.LoadInt32(&requester.count))
+
+	// and methods that aren't idempotent are never shared
+	for i := 0; i < 2; i++ {
+		_, err = d.interceptRequest(ctx, &jsonrpc.Request{Method: "eth_newBlockFilter"}, requester.Request)
+		require.NoError(t, err)
+	}
+	require.Equal(t, int32(4), atomic.LoadInt32(&requester.count))
+}
+
+func TestDeduplication_Cancel(t *testing.T) {
+	requester := &countingRequester{blockingRequester: blockingRequester{release: make(chan struct{})}}
+	defer close(requester.release)
+	d := newDeduplicator()
+
+	r := jsonrpc.MustRequest(1, "eth_blockNumber")
+	key, err := requestKey(r)
+	require.NoError(t, err)
+
+	leader, cancelLeader := context.WithCancel(context.Background())
+	follower, cancelFollower := context.WithCancel(context.Background())
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := d.interceptRequest(leader, r, requester.Request)
+		errs <- err
+	}()
+	waitForWaiters(t, d, key, 1)
+	go func() {
+		_, err := d.interceptRequest(follower, r, requester.Request)
+		errs <- err
+	}()
+	waitForWaiters(t, d, key, 2)
+
+	// the first caller giving up doesn't cancel the request the second is still waiting on
+	cancelLeader()
+	require.Equal(t, context.Canceled, errors.Cause(<-errs))
+	require.Equal(t, 1, d.waiters(key))
+
+	cancelFollower()
+	require.Equal(t, context.Canceled, errors.Cause(<-errs))
+	require.Equal(t, 0, d.waiters(key))
+}
+
+func TestWithDeduplication(t *testing.T) {
+	requester := &blockingRequester{release: make(chan struct{})}
+	close(requester.release)
+
+	c, err := NewCustomClient(requester, nil, WithDeduplication())
+	require.NoError(t, err)
+
+	n, err := c.BlockNumber(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), n)
+}
+
+func TestDeduplication_Deadline(t *testing.T) {
+	d := newDeduplicator()
+	r := jsonrpc.MustRequest(1, "eth_blockNumber")
+	key, err := requestKey(r)
+	require.NoError(t, err)
+
+	// a backend that never answers, reporting the deadline the shared request had once everyone joined
+	release := make(chan struct{})
+	deadlines := make(chan time.Time, 1)
+	hung := func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		<-release
+		deadline, _ := ctx.Deadline()
+		deadlines <- deadline
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	first, cancelFirst := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelFirst()
+	second, cancelSecond := context.WithTimeout(context.Background(), 300*time.Millisecond)
+	defer cancelSecond()
+
+	errs := make(chan error, 2)
+	go func() {
+		_, err := d.interceptRequest(first, r, hung)
+		errs <- err
+	}()
+	waitForWaiters(t, d, key, 1)
+	go func() {
+		_, err := d.interceptRequest(second, r, hung)
+		errs <- err
+	}()
+	waitForWaiters(t, d, key, 2)
+	close(release)
+
+	// the shared request runs until the later of the two deadlines
+	expected, _ := second.Deadline()
+	require.Equal(t, expected, <-deadlines)
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(<-errs))
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(<-errs))
+
+	// and a caller without a deadline lifts it
+	third, cancelThird := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancelThird()
+	shared := newSharedContext(third)
+	defer shared.cancel()
+
+	shared.extend(context.Background())
+	_, ok := shared.Deadline()
+	require.False(t, ok)
+}
@@ -0,0 +1,327 @@
+package node
+
+import (
+	"context"
+	"math/big"
+	"sort"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+const defaultFeeOracleBlocks = 20
+
+// FeeSpeed selects how quickly a transaction using a fee suggestion should be included.
+type FeeSpeed int
+
+const (
+	FeeSlow FeeSpeed = iota
+	FeeStandard
+	FeeFast
+)
+
+func (s FeeSpeed) String() string {
+	switch s {
+	case FeeSlow:
+		return "slow"
+	case FeeStandard:
+		return "standard"
+	case FeeFast:
+		return "fast"
+	default:
+		return "unknown"
+	}
+}
+
+// headroom is how many blocks of maximum base fee growth the max fees of each speed can absorb
+var headroom = map[FeeSpeed]int{
+	FeeSlow:     2,
+	FeeStandard: 4,
+	FeeFast:     8,
+}
+
+// FeeSuggestion holds the fee fields for a transaction of one speed.
+type FeeSuggestion struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+
+	// MaxFeePerBlobGas is nil if the backend did not report a blob base fee
+	MaxFeePerBlobGas *big.Int
+}
+
+// Apply sets the EIP-1559 fee fields of tx and clears its GasPrice, turning legacy and EIP-2930 transactions
+// into EIP-1559 ones.  The blob fee is only set if tx carries blobs.
+func (s *FeeSuggestion) Apply(tx *eth.Transaction) {
+	if t := tx.TransactionType(); t == eth.TransactionTypeLegacy || t == eth.TransactionTypeAccessList {
+		typ := eth.QuantityFromInt64(eth.TransactionTypeDynamicFee)
+		tx.Type = &typ
+	}
+
+	maxFee := eth.QuantityFromBigInt(s.MaxFeePerGas)
+	tip := eth.QuantityFromBigInt(s.MaxPriorityFeePerGas)
+	tx.MaxFeePerGas = &maxFee
+	tx.MaxPriorityFeePerGas = &tip
+	tx.GasPrice = nil
+
+	if len(tx.BlobVersionedHashes) > 0 && s.MaxFeePerBlobGas != nil {
+		blobFee := eth.QuantityFromBigInt(s.MaxFeePerBlobGas)
+		tx.MaxFeePerBlobGas = &blobFee
+	}
+}
+
+// FeeSuggestions are the fees suggested for the next block.
+type FeeSuggestions struct {
+	// BaseFee is the base fee of the next block
+	BaseFee *big.Int
+
+	// BlobBaseFee is the blob base fee of the next block, or nil if the backend did not report one
+	BlobBaseFee *big.Int
+
+	// Rising is true if the base fee of the next block is above the average of the recent blocks, in
+	// which case the max fees allow for more growth
+	Rising bool
+
+	Slow     FeeSuggestion
+	Standard FeeSuggestion
+	Fast     FeeSuggestion
+}
+
+// Speed returns the suggestion for speed.
+func (s *FeeSuggestions) Speed(speed FeeSpeed) *FeeSuggestion {
+	switch speed {
+	case FeeSlow:
+		return &s.Slow
+	case FeeFast:
+		return &s.Fast
+	default:
+		return &s.Standard
+	}
+}
+
+// FeeOracleConfig controls the history a FeeOracle bases its suggestions on.
+type FeeOracleConfig struct {
+	// Blocks is how many recent blocks are considered.  Defaults to 20.
+	Blocks uint64
+
+	// Percentiles are the reward percentiles of the recent blocks used as the priority fee of the slow,
+	// standard and fast suggestions.  Defaults to 10, 50 and 90.
+	Percentiles [3]float64
+
+	// MinPriorityFee is the lowest priority fee suggested, in wei
+	MinPriorityFee *big.Int
+
+	// FeeParams are the fee market parameters used to compute the next base fees of backends without
+	// eth_feeHistory.  Defaults to eth.PragueFeeParams.
+	FeeParams eth.FeeParams
+}
+
+// FeeOracle suggests EIP-1559 fees from the recent history of a backend.
+type FeeOracle struct {
+	client Client
+	config FeeOracleConfig
+}
+
+// NewFeeOracle returns a FeeOracle which asks client for the fee history of recent blocks.  The priority fees
+// suggested are the median over those blocks of their reward percentiles, ignoring empty blocks, and the max
+// fees leave room for the base fee to keep growing for a number of blocks depending on the speed.  Backends
+// without eth_feeHistory fall back to the base fees following the latest block and eth_maxPriorityFeePerGas.
+func NewFeeOracle(client Client, config FeeOracleConfig) *FeeOracle {
+	if config.Blocks == 0 {
+		config.Blocks = defaultFeeOracleBlocks
+	}
+
+	if config.Percentiles == [3]float64{} {
+		config.Percentiles = [3]float64{10, 50, 90}
+	}
+
+	if config.FeeParams == (eth.FeeParams{}) {
+		config.FeeParams = eth.PragueFeeParams
+	}
+
+	return &FeeOracle{
+		client: client,
+		config: config,
+	}
+}
+
+// Suggest returns the slow, standard and fast fee suggestions for the next block.
+func (o *FeeOracle) Suggest(ctx context.Context) (*FeeSuggestions, error) {
+	latest := *eth.MustBlockNumberOrTag("latest")
+	history, err := o.client.FeeHistory(ctx, o.config.Blocks, latest, o.config.Percentiles[:])
+	if err != nil {
+		if unsupported(err) {
+			return o.suggestFromBlock(ctx)
+		}
+		return nil, errors.Wrap(err, "could not get fee history")
+	}
+
+	next := history.NextBaseFeePerGas()
+	if next == nil || next.Big().Sign() == 0 {
+		return nil, eth.ErrNoBaseFee
+	}
+
+	s := FeeSuggestions{
+		BaseFee: next.Big(),
+		Rising:  rising(history.BaseFeePerGas),
+	}
+
+	if blob := history.NextBaseFeePerBlobGas(); blob != nil {
+		s.BlobBaseFee = blob.Big()
+	}
+
+	var fallback *big.Int
+	for i, speed := range []FeeSpeed{FeeSlow, FeeStandard, FeeFast} {
+		tip := medianReward(history, i)
+		if tip == nil {
+			if fallback == nil {
+				fallback, err = o.client.MaxPriorityFeePerGasBig(ctx)
+				if err != nil {
+					return nil, errors.Wrap(err, "could not get priority fee")
+				}
+			}
+			tip = fallback
+		}
+
+		o.fill(&s, speed, tip)
+	}
+
+	return &s, nil
+}
+
+// Fill sets the fee fields of tx to the suggestion for speed.
+func (o *FeeOracle) Fill(ctx context.Context, tx *eth.Transaction, speed FeeSpeed) error {
+	s, err := o.Suggest(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.Speed(speed).Apply(tx)
+	return nil
+}
+
+// suggestFromBlock suggests fees for the block following the latest one and the backend's own priority fee
+// suggestion
+func (o *FeeOracle) suggestFromBlock(ctx context.Context) (*FeeSuggestions, error) {
+	block, err := o.client.BlockByNumber(ctx, *eth.MustBlockNumberOrTag("latest"), false)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get latest block")
+	}
+
+	if block.BaseFeePerGas == nil || block.BaseFeePerGas.Big().Sign() == 0 {
+		return nil, eth.ErrNoBaseFee
+	}
+
+	next, err := block.NextBaseFee(o.config.FeeParams)
+	if err != nil {
+		return nil, err
+	}
+
+	tip, err := o.client.MaxPriorityFeePerGasBig(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not get priority fee")
+	}
+
+	s := FeeSuggestions{
+		BaseFee: next,
+		Rising:  next.Cmp(block.BaseFeePerGas.Big()) > 0,
+	}
+
+	// blocks from before EIP-4844 have no blob base fee
+	if blob, err := block.NextBlobBaseFee(o.config.FeeParams); err == nil {
+		s.BlobBaseFee = blob
+	}
+
+	for _, speed := range []FeeSpeed{FeeSlow, FeeStandard, FeeFast} {
+		o.fill(&s, speed, tip)
+	}
+
+	return &s, nil
+}
+
+// fill sets the suggestion for speed from the base fees in s and a priority fee of tip
+func (o *FeeOracle) fill(s *FeeSuggestions, speed FeeSpeed, tip *big.Int) {
+	tip = new(big.Int).Set(tip)
+	if o.config.MinPriorityFee != nil && tip.Cmp(o.config.MinPriorityFee) < 0 {
+		tip.Set(o.config.MinPriorityFee)
+	}
+
+	// a faster suggestion never tips less than a slower one
+	if speed > FeeSlow {
+		if slower := s.Speed(speed - 1).MaxPriorityFeePerGas; tip.Cmp(slower) < 0 {
+			tip.Set(slower)
+		}
+	}
+
+	blocks := headroom[speed]
+	if s.Rising {
+		blocks += 2
+	}
+
+	suggestion := s.Speed(speed)
+	suggestion.MaxPriorityFeePerGas = tip
+	suggestion.MaxFeePerGas = new(big.Int).Add(grow(s.BaseFee, blocks), tip)
+	if s.BlobBaseFee != nil {
+		suggestion.MaxFeePerBlobGas = grow(s.BlobBaseFee, blocks)
+	}
+}
+
+// grow returns fee after increasing by the maximum of 12.5% for each of blocks
+func grow(fee *big.Int, blocks int) *big.Int {
+	grown := new(big.Int).Set(fee)
+	for i := 0; i < blocks; i++ {
+		grown.Mul(grown, big.NewInt(9))
+		grown.Quo(grown, big.NewInt(8))
+	}
+
+	if grown.Cmp(fee) == 0 && blocks > 0 {
+		// tiny fees don't grow under integer division, but they still need some headroom
+		grown.Add(grown, big.NewInt(int64(blocks)))
+	}
+
+	return grown
+}
+
+// rising returns true if the last of the base fees is above the average of the others
+func rising(baseFees []eth.Quantity) bool {
+	if len(baseFees) < 2 {
+		return false
+	}
+
+	sum := new(big.Int)
+	for _, fee := range baseFees[:len(baseFees)-1] {
+		sum.Add(sum, fee.Big())
+	}
+
+	last := new(big.Int).Mul(baseFees[len(baseFees)-1].Big(), big.NewInt(int64(len(baseFees)-1)))
+	return last.Cmp(sum) > 0
+}
+
+// medianReward returns the median of the rewards at percentile index i over the blocks that used any gas,
+// or nil if there are none
+func medianReward(history *eth.FeeHistory, i int) *big.Int {
+	rewards := make([]*big.Int, 0, len(history.Reward))
+	for block, reward := range history.Reward {
+		if i >= len(reward) || (block < len(history.GasUsedRatio) && history.GasUsedRatio[block] == 0) {
+			continue
+		}
+		rewards = append(rewards, reward[i].Big())
+	}
+
+	if len(rewards) == 0 {
+		return nil
+	}
+
+	sort.Slice(rewards, func(a, b int) bool {
+		return rewards[a].Cmp(rewards[b]) < 0
+	})
+
+	return rewards[len(rewards)/2]
+}
+
+// unsupported returns true if err is the error response of a backend that does not implement the method at all
+func unsupported(err error) bool {
+	e, ok := errors.Cause(err).(*jsonrpc.Error)
+	return ok && (e.Code == jsonrpc.ErrCodeMethodNotFound || e.Code == jsonrpc.ErrCodeMethodNotSupported)
+}
@@ -0,0 +1,117 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func TestFeeOracle(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{
+		results: map[string]string{
+			"eth_feeHistory": `{
+				"oldestBlock": "0x10",
+				"baseFeePerGas": ["0x64", "0x64", "0xc8"],
+				"gasUsedRatio": [0.5, 0],
+				"reward": [["0x1", "0x2", "0x3"], ["0x0", "0x0", "0x0"]],
+				"baseFeePerBlobGas": ["0x1", "0x1", "0x1"],
+				"blobGasUsedRatio": [0, 0]
+			}`,
+		},
+		calls: make(map[string]int),
+	}
+
+	c, err := NewCustomClient(backend, nil)
+	require.NoError(t, err)
+
+	s, err := NewFeeOracle(c, FeeOracleConfig{}).Suggest(ctx)
+	require.NoError(t, err)
+
+	require.Equal(t, big.NewInt(200), s.BaseFee)
+	require.True(t, s.Rising)
+
+	// the empty block's rewards are ignored, and the max fee allows for 4, 6 and 10 blocks of growth while rising
+	require.Equal(t, big.NewInt(1), s.Slow.MaxPriorityFeePerGas)
+	require.Equal(t, big.NewInt(320), s.Slow.MaxFeePerGas)
+	require.Equal(t, big.NewInt(2), s.Standard.MaxPriorityFeePerGas)
+	require.Equal(t, big.NewInt(404), s.Standard.MaxFeePerGas)
+	require.Equal(t, big.NewInt(3), s.Fast.MaxPriorityFeePerGas)
+	require.Equal(t, big.NewInt(645), s.Fast.MaxFeePerGas)
+	require.Equal(t, big.NewInt(5), s.Slow.MaxFeePerBlobGas)
+	require.Equal(t, 0, backend.count("eth_maxPriorityFeePerGas"))
+
+	price := eth.QuantityFromUInt64(7)
+	tx := eth.Transaction{GasPrice: &price, BlobVersionedHashes: eth.Hashes{eth.Hash("0x01")}}
+	s.Speed(FeeFast).Apply(&tx)
+	require.Equal(t, eth.TransactionTypeDynamicFee, tx.TransactionType())
+	require.Nil(t, tx.GasPrice)
+	require.Equal(t, uint64(645), tx.MaxFeePerGas.UInt64())
+	require.Equal(t, uint64(3), tx.MaxPriorityFeePerGas.UInt64())
+	require.Equal(t, uint64(11), tx.MaxFeePerBlobGas.UInt64())
+}
+
+func TestFeeOracle_Fallback(t *testing.T) {
+	ctx := context.Background()
+	backend := &countingBackend{
+		results: map[string]string{
+			"eth_getBlockByNumber":     `{"number":"0x10","baseFeePerGas":"0x3b9aca00","gasUsed":"0x1c9c380","gasLimit":"0x1c9c380","transactions":[]}`,
+			"eth_maxPriorityFeePerGas": `"0x5f5e100"`,
+		},
+		calls: make(map[string]int),
+	}
+
+	// the backend does not know eth_feeHistory, unless it is set to fail it in some other way
+	var failure error
+	c, err := NewCustomClient(requesterFunc(func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		if r.Method != "eth_feeHistory" {
+			return backend.Request(ctx, r)
+		}
+
+		if failure != nil {
+			return nil, failure
+		}
+
+		raw, err := json.Marshal(jsonrpc.MethodNotFound(r))
+		require.NoError(t, err)
+		e := json.RawMessage(raw)
+		return &jsonrpc.RawResponse{ID: r.ID, Error: &e}, nil
+	}), nil)
+	require.NoError(t, err)
+
+	oracle := NewFeeOracle(c, FeeOracleConfig{MinPriorityFee: big.NewInt(200000000)})
+	tx := eth.Transaction{}
+	require.NoError(t, oracle.Fill(ctx, &tx, FeeSlow))
+	require.Equal(t, uint64(200000000), tx.MaxPriorityFeePerGas.UInt64())
+	// the full block raises the next base fee by 12.5%, and the max fee allows for 4 more blocks of growth
+	require.Equal(t, uint64(1802032470+200000000), tx.MaxFeePerGas.UInt64())
+	require.Nil(t, tx.MaxFeePerBlobGas)
+
+	backend.results["eth_getBlockByNumber"] = `{"number":"0x10","baseFeePerGas":"0x3b9aca00","gasUsed":"0xe4e1c0","gasLimit":"0x1c9c380","excessBlobGas":"0x0","blobGasUsed":"0x180000","transactions":[]}`
+	s, err := oracle.Suggest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, big.NewInt(1000000000), s.BaseFee)
+	require.False(t, s.Rising)
+	require.Equal(t, big.NewInt(1), s.BlobBaseFee)
+	require.Equal(t, big.NewInt(3), s.Slow.MaxFeePerBlobGas)
+
+	backend.results["eth_getBlockByNumber"] = `{"number":"0x10","transactions":[]}`
+	_, err = oracle.Suggest(ctx)
+	require.Equal(t, eth.ErrNoBaseFee, err)
+
+	// any other failure is passed on rather than hidden behind the fallback
+	blocks := backend.count("eth_getBlockByNumber")
+	for _, failure = range []error{context.Canceled, &HTTPError{StatusCode: http.StatusTooManyRequests}} {
+		_, err = oracle.Suggest(ctx)
+		require.Equal(t, failure, errors.Cause(err))
+	}
+	require.Equal(t, blocks, backend.count("eth_getBlockByNumber"))
+}
@@ -0,0 +1,235 @@
+package node
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// ErrFilterNotFound is returned when the backend no longer knows a filter ID, typically because
+// it was uninstalled or expired after not being polled for a while (5 minutes for geth).
+var ErrFilterNotFound = errors.New("filter not found")
+
+// installFilter sends one of the eth_new*Filter methods and returns the new filter ID.
+func installFilter(ctx context.Context, r Requester, method string, params ...interface{}) (string, error) {
+	p, err := jsonrpc.MakeParams(params...)
+	if err != nil {
+		return "", errors.Wrap(err, "could not encode filter params")
+	}
+
+	request := jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 1},
+		Method: method,
+		Params: p,
+	}
+
+	applyContext(ctx, &request)
+	response, err := r.Request(ctx, &request)
+	if err != nil {
+		return "", errors.Wrap(err, "could not make request")
+	}
+
+	if response.Error != nil {
+		return "", errors.New(string(*response.Error))
+	}
+
+	id := ""
+	err = json.Unmarshal(response.Result, &id)
+	if err != nil {
+		return "", errors.Wrap(err, "could not decode filter id")
+	}
+
+	return id, nil
+}
+
+// filterRequest sends a method that takes a filter ID as its only parameter and returns the raw result.
+func filterRequest(ctx context.Context, r Requester, method string, id string) (json.RawMessage, error) {
+	request := jsonrpc.Request{
+		ID:     jsonrpc.ID{Num: 1},
+		Method: method,
+		Params: jsonrpc.MustParams(id),
+	}
+
+	applyContext(ctx, &request)
+	response, err := r.Request(ctx, &request)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not make request")
+	}
+
+	if response.Error != nil {
+		e := jsonrpc.Error{}
+		if json.Unmarshal(*response.Error, &e) == nil && strings.EqualFold(e.Message, ErrFilterNotFound.Error()) {
+			return nil, errors.Wrapf(ErrFilterNotFound, "filter %s", id)
+		}
+
+		return nil, errors.New(string(*response.Error))
+	}
+
+	return response.Result, nil
+}
+
+// filterUninstallTimeout bounds how long a Filter waits to uninstall itself after its context finished
+const filterUninstallTimeout = 10 * time.Second
+
+// FilterType identifies what kind of changes a Filter reports.
+type FilterType int
+
+const (
+	// FilterTypeLogs is a filter created by eth_newFilter, its changes are logs
+	FilterTypeLogs FilterType = iota + 1
+	// FilterTypeBlocks is a filter created by eth_newBlockFilter, its changes are block hashes
+	FilterTypeBlocks
+	// FilterTypePendingTransactions is a filter created by eth_newPendingTransactionFilter, its changes are transaction hashes
+	FilterTypePendingTransactions
+)
+
+func (t FilterType) String() string {
+	switch t {
+	case FilterTypeLogs:
+		return "logs"
+	case FilterTypeBlocks:
+		return "blocks"
+	case FilterTypePendingTransactions:
+		return "pending transactions"
+	default:
+		return "unknown"
+	}
+}
+
+// FilterChanges is the decoded result of eth_getFilterChanges.  Log filters populate Logs while
+// block and pending transaction filters populate Hashes.
+type FilterChanges struct {
+	Logs   []eth.Log
+	Hashes []eth.Hash
+}
+
+// UnmarshalJSON decodes the mixed array returned by eth_getFilterChanges, where each item
+// is either a hash string or a log object.
+func (c *FilterChanges) UnmarshalJSON(data []byte) error {
+	items := make([]json.RawMessage, 0)
+	err := json.Unmarshal(data, &items)
+	if err != nil {
+		return err
+	}
+
+	*c = FilterChanges{}
+	for i := range items {
+		item := bytes.TrimSpace(items[i])
+		if len(item) > 0 && item[0] == '"' {
+			h := eth.Hash("")
+			err = json.Unmarshal(item, &h)
+			if err != nil {
+				return errors.Wrapf(err, "could not decode filter change %d", i)
+			}
+			c.Hashes = append(c.Hashes, h)
+			continue
+		}
+
+		l := eth.Log{}
+		err = json.Unmarshal(item, &l)
+		if err != nil {
+			return errors.Wrapf(err, "could not decode filter change %d", i)
+		}
+		c.Logs = append(c.Logs, l)
+	}
+
+	return nil
+}
+
+// Filter is a handle to a filter installed on the backend.  It is uninstalled when the context
+// it was created with finishes, or by calling Uninstall, which a filter made with a context that
+// never finishes needs.
+type Filter struct {
+	client   Client
+	id       string
+	typ      FilterType
+	criteria *eth.LogFilter
+
+	uninstallOnce sync.Once
+	uninstallErr  error
+	doneCh        chan struct{}
+}
+
+func newFilterHandle(ctx context.Context, c Client, id string, typ FilterType, criteria *eth.LogFilter) *Filter {
+	f := Filter{
+		client:   c,
+		id:       id,
+		typ:      typ,
+		criteria: criteria,
+		doneCh:   make(chan struct{}),
+	}
+
+	if ctx.Done() == nil {
+		// nothing to wait for, the filter lives until it is uninstalled
+		return &f
+	}
+
+	go func() {
+		select {
+		case <-ctx.Done():
+			// the caller's context is gone, so we have to use our own to clean up
+			uctx, cancel := context.WithTimeout(context.Background(), filterUninstallTimeout)
+			_ = f.Uninstall(uctx)
+			cancel()
+		case <-f.doneCh:
+		}
+	}()
+
+	return &f
+}
+
+// ID returns the filter ID assigned by the backend
+func (f *Filter) ID() string {
+	return f.id
+}
+
+// Type returns what kind of changes this filter reports
+func (f *Filter) Type() FilterType {
+	return f.typ
+}
+
+// Criteria returns the log filter this filter was created with, or nil for block and pending transaction filters
+func (f *Filter) Criteria() *eth.LogFilter {
+	return f.criteria
+}
+
+// Changes returns everything that happened since the last poll of this filter
+func (f *Filter) Changes(ctx context.Context) (*FilterChanges, error) {
+	return f.client.GetFilterChanges(ctx, f.id)
+}
+
+// Logs returns all logs matching a log filter, regardless of whether they were already polled
+func (f *Filter) Logs(ctx context.Context) ([]eth.Log, error) {
+	if f.typ != FilterTypeLogs {
+		return nil, errors.Errorf("eth_getFilterLogs is not supported for %s filters", f.typ)
+	}
+
+	return f.client.GetFilterLogs(ctx, f.id)
+}
+
+// Uninstall removes the filter from the backend.  Calling it more than once returns the result of the first call.
+func (f *Filter) Uninstall(ctx context.Context) error {
+	f.uninstallOnce.Do(func() {
+		defer close(f.doneCh)
+
+		_, err := f.client.UninstallFilter(ctx, f.id)
+		if err != nil {
+			f.uninstallErr = errors.Wrap(err, "could not uninstall filter")
+		}
+	})
+
+	return f.uninstallErr
+}
+
+// Done returns a channel that is closed once the filter has been uninstalled
+func (f *Filter) Done() <-chan struct{} {
+	return f.doneCh
+}
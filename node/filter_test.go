@@ -0,0 +1,123 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+const testLog = `{"removed":false,"logIndex":"0x0","transactionIndex":"0x0","transactionHash":"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd",` +
+	`"blockHash":"0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb","blockNumber":"0x10",` +
+	`"address":"0x007733a1fe69cf3f2cf989f81c7b4cac1693387a","data":"0x","topics":[]}`
+
+func TestFilterChanges_UnmarshalJSON(t *testing.T) {
+	changes := FilterChanges{}
+	require.NoError(t, json.Unmarshal([]byte(`[]`), &changes))
+	require.Empty(t, changes.Logs)
+	require.Empty(t, changes.Hashes)
+
+	require.NoError(t, json.Unmarshal([]byte(`["0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd"]`), &changes))
+	require.Empty(t, changes.Logs)
+	require.Equal(t, []eth.Hash{"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd"}, changes.Hashes)
+
+	require.NoError(t, json.Unmarshal([]byte(`[`+testLog+`]`), &changes))
+	require.Empty(t, changes.Hashes)
+	require.Len(t, changes.Logs, 1)
+	require.Equal(t, "0x10", changes.Logs[0].BlockNumber.String())
+
+	require.Error(t, json.Unmarshal([]byte(`[1]`), &changes))
+}
+
+func TestClient_Filters(t *testing.T) {
+	ctx := context.Background()
+	backend := &filterBackend{t: t, filters: make(map[string][]json.RawMessage)}
+	c, err := NewCustomClient(backend, nil)
+	require.NoError(t, err)
+
+	blocks, err := c.NewBlockFilter(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x1", blocks.ID())
+	require.Equal(t, FilterTypeBlocks, blocks.Type())
+	require.Nil(t, blocks.Criteria())
+
+	backend.push(blocks.ID(), `"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd"`)
+	changes, err := blocks.Changes(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes.Hashes, 1)
+
+	_, err = blocks.Logs(ctx)
+	require.Error(t, err)
+
+	logs, err := c.NewFilter(ctx, eth.LogFilter{})
+	require.NoError(t, err)
+	require.Equal(t, FilterTypeLogs, logs.Type())
+	require.NotNil(t, logs.Criteria())
+
+	backend.push(logs.ID(), testLog)
+	all, err := logs.Logs(ctx)
+	require.NoError(t, err)
+	require.Len(t, all, 1)
+
+	changes, err = c.GetFilterChanges(ctx, logs.ID())
+	require.NoError(t, err)
+	require.Len(t, changes.Logs, 1)
+
+	require.NoError(t, blocks.Uninstall(ctx))
+	require.NoError(t, blocks.Uninstall(ctx))
+	<-blocks.Done()
+
+	_, err = c.GetFilterChanges(ctx, blocks.ID())
+	require.Equal(t, ErrFilterNotFound, errors.Cause(err))
+
+	ok, err := c.UninstallFilter(ctx, blocks.ID())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestFilter_UninstallOnCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	backend := &filterBackend{t: t, filters: make(map[string][]json.RawMessage)}
+	c, err := NewCustomClient(backend, nil)
+	require.NoError(t, err)
+
+	f, err := c.NewPendingTransactionFilter(ctx)
+	require.NoError(t, err)
+	require.Equal(t, FilterTypePendingTransactions, f.Type())
+
+	cancel()
+	select {
+	case <-f.Done():
+	case <-time.After(time.Second):
+		t.Fatal("filter was not uninstalled")
+	}
+
+	backend.mu.Lock()
+	defer backend.mu.Unlock()
+	require.Empty(t, backend.filters)
+	require.Equal(t, "eth_uninstallFilter", backend.calls[len(backend.calls)-1])
+}
+
+func TestFilterRequest_NotFound(t *testing.T) {
+	ctx := context.Background()
+	var response string
+	r := requesterFunc(func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		return &jsonrpc.RawResponse{ID: r.ID, Error: rawError(response)}, nil
+	})
+
+	response = `{"code":-32000,"message":"filter not found"}`
+	_, err := filterRequest(ctx, r, "eth_getFilterChanges", "0x1")
+	require.Equal(t, ErrFilterNotFound, errors.Cause(err))
+
+	// only the message says whether the filter is gone, not text elsewhere in the error
+	response = `{"code":-32000,"message":"execution reverted","data":{"reason":"filter not found"}}`
+	_, err = filterRequest(ctx, r, "eth_getFilterChanges", "0x1")
+	require.Error(t, err)
+	require.NotEqual(t, ErrFilterNotFound, errors.Cause(err))
+}
@@ -0,0 +1,298 @@
+package node
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+)
+
+// ErrReorgTooDeep is returned by Follower.Err when the chain reorganized past the oldest block in its window,
+// so it is no longer possible to tell which blocks were removed.
+var ErrReorgTooDeep = errors.New("reorg is deeper than the follower window")
+
+const defaultFollowerWindow = 128
+
+// BlockEventType identifies whether a block joined or left the canonical chain.
+type BlockEventType int
+
+const (
+	// BlockAdded means the block is now part of the canonical chain
+	BlockAdded BlockEventType = iota + 1
+	// BlockRemoved means a previously added block was reorged out of the canonical chain
+	BlockRemoved
+)
+
+func (t BlockEventType) String() string {
+	switch t {
+	case BlockAdded:
+		return "added"
+	case BlockRemoved:
+		return "removed"
+	default:
+		return "unknown"
+	}
+}
+
+// BlockEvent is emitted by a Follower whenever the canonical chain changes.
+type BlockEvent struct {
+	Type  BlockEventType
+	Block *eth.Block
+}
+
+// FollowerConfig controls how a Follower tracks the chain.
+type FollowerConfig struct {
+	// Confirmations is how many blocks must be built on top of a block before it is added, or 0 to
+	// add blocks as soon as they are seen.  Reorgs shallower than this never produce BlockRemoved events.
+	Confirmations uint64
+
+	// Window is the number of recent headers kept to resolve reorgs.  Defaults to 128 and must be
+	// larger than Confirmations.
+	Window int
+}
+
+// Follower follows the head of the chain, turning newHeads notifications into an ordered stream of
+// BlockAdded and BlockRemoved events.  Blocks are emitted in chain order, a reorg first removes the
+// abandoned blocks starting at the old head and then adds the new branch starting at the common ancestor.
+// Blocks that were missed, for example while a connection was re-established, are backfilled by number.
+type Follower struct {
+	client Client
+	config FollowerConfig
+	sub    *NewHeadsSubscription
+
+	// chain is the canonical chain we know of, ordered by number and linked by parent hash
+	chain []*eth.Block
+	// added is how many blocks at the start of chain have been emitted as BlockAdded
+	added int
+
+	eventsCh chan BlockEvent
+	doneCh   chan struct{}
+	err      error
+	errMu    sync.RWMutex
+}
+
+// NewFollower subscribes to new heads using client and starts following the chain from the next head,
+// until ctx finishes or the subscription terminates.
+func NewFollower(ctx context.Context, client Client, config FollowerConfig) (*Follower, error) {
+	if config.Window <= 0 {
+		config.Window = defaultFollowerWindow
+	}
+
+	if uint64(config.Window) <= config.Confirmations {
+		return nil, errors.Errorf("follower window of %d blocks can not hold %d confirmations", config.Window, config.Confirmations)
+	}
+
+	sub, err := client.SubscribeNewHeads(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not subscribe to new heads")
+	}
+
+	f := Follower{
+		client:   client,
+		config:   config,
+		sub:      WrapNewHeads(sub),
+		eventsCh: make(chan BlockEvent),
+		doneCh:   make(chan struct{}),
+	}
+
+	go f.run(ctx)
+	return &f, nil
+}
+
+// Events returns the channel block events are delivered on, which is closed when the follower stops
+func (f *Follower) Events() <-chan BlockEvent {
+	return f.eventsCh
+}
+
+// Done returns a channel that is closed once the follower has stopped
+func (f *Follower) Done() <-chan struct{} {
+	return f.doneCh
+}
+
+// Err returns why the follower stopped, or nil while it is still running
+func (f *Follower) Err() error {
+	f.errMu.RLock()
+	defer f.errMu.RUnlock()
+	return f.err
+}
+
+func (f *Follower) run(ctx context.Context) {
+	err := f.follow(ctx)
+
+	// we don't need the subscription any more, but ctx may already be gone
+	uctx, cancel := context.WithTimeout(context.Background(), typedUnsubscribeTimeout)
+	_ = f.sub.Unsubscribe(uctx)
+	cancel()
+
+	f.errMu.Lock()
+	f.err = err
+	f.errMu.Unlock()
+
+	close(f.eventsCh)
+	close(f.doneCh)
+}
+
+func (f *Follower) follow(ctx context.Context) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case head, ok := <-f.sub.Ch():
+			if !ok {
+				if err := f.sub.Err(); err != nil {
+					return err
+				}
+				return errors.New("new heads subscription ended")
+			}
+
+			if head.Hash == "" {
+				continue
+			}
+
+			block, err := f.client.BlockByHash(ctx, head.Hash.String(), false)
+			if err == ErrBlockNotFound {
+				// already reorged away, we'll catch up with the next head
+				continue
+			}
+
+			if err != nil {
+				return errors.Wrap(err, "could not fetch new head")
+			}
+
+			err = f.handle(ctx, block)
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// handle brings the chain up to date with a new head, filling in any blocks between our head and it
+func (f *Follower) handle(ctx context.Context, block *eth.Block) error {
+	if block.Number == nil || block.Hash == nil {
+		return errors.New("new head is missing its number or hash")
+	}
+
+	if len(f.chain) > 0 {
+		tip := f.chain[len(f.chain)-1]
+		for n := tip.Number.UInt64() + 1; n < block.Number.UInt64(); n++ {
+			missed, err := f.client.BlockByNumber(ctx, *eth.MustBlockNumberOrTag(eth.QuantityFromUInt64(n).String()), false)
+			if err == ErrBlockNotFound {
+				// our backend hasn't caught up with the head it told us about, the head itself will sort things out
+				break
+			}
+
+			if err != nil {
+				return errors.Wrapf(err, "could not backfill block %d", n)
+			}
+
+			err = f.apply(ctx, missed)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return f.apply(ctx, block)
+}
+
+// apply makes block the head of the chain, resolving a reorg if it doesn't extend our current head
+func (f *Follower) apply(ctx context.Context, block *eth.Block) error {
+	if f.index(*block.Hash) >= 0 {
+		// seen it already, e.g. a backfilled block that was also notified
+		return nil
+	}
+
+	if len(f.chain) > 0 && block.Number.UInt64() < f.chain[0].Number.UInt64() {
+		// a stale notification for a block older than anything we're tracking, which can't change our view
+		return nil
+	}
+
+	branch := []*eth.Block{block}
+	ancestor := -1
+	if len(f.chain) > 0 {
+		for {
+			ancestor = f.index(branch[0].ParentHash)
+			if ancestor >= 0 {
+				break
+			}
+
+			oldest := f.chain[0]
+			if branch[0].Number.UInt64() <= oldest.Number.UInt64() {
+				return errors.Wrapf(ErrReorgTooDeep, "no common ancestor for block %s", block.Hash.String())
+			}
+
+			parent, err := f.client.BlockByHash(ctx, branch[0].ParentHash.String(), false)
+			if err == ErrBlockNotFound {
+				// the new branch is already being replaced, so skip it and wait for the next head
+				return nil
+			}
+
+			if err != nil {
+				return errors.Wrap(err, "could not fetch parent block")
+			}
+
+			branch = append([]*eth.Block{parent}, branch...)
+		}
+	}
+
+	// remove the abandoned blocks, newest first, but only report the ones we told anyone about
+	for i := len(f.chain) - 1; i > ancestor; i-- {
+		if i < f.added {
+			err := f.emit(ctx, BlockEvent{Type: BlockRemoved, Block: f.chain[i]})
+			if err != nil {
+				return err
+			}
+			f.added = i
+		}
+	}
+
+	f.chain = append(f.chain[:ancestor+1], branch...)
+	return f.confirm(ctx)
+}
+
+// confirm emits every block that now has enough confirmations and trims the window
+func (f *Follower) confirm(ctx context.Context) error {
+	head := f.chain[len(f.chain)-1].Number.UInt64()
+	for f.added < len(f.chain) {
+		block := f.chain[f.added]
+		if block.Number.UInt64()+f.config.Confirmations > head {
+			break
+		}
+
+		err := f.emit(ctx, BlockEvent{Type: BlockAdded, Block: block})
+		if err != nil {
+			return err
+		}
+		f.added++
+	}
+
+	if extra := len(f.chain) - f.config.Window; extra > 0 {
+		f.chain = append([]*eth.Block(nil), f.chain[extra:]...)
+		f.added -= extra
+	}
+
+	return nil
+}
+
+func (f *Follower) emit(ctx context.Context, event BlockEvent) error {
+	select {
+	case f.eventsCh <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// index returns the position of the block with the given hash in the chain, or -1
+func (f *Follower) index(hash eth.Hash) int {
+	for i := len(f.chain) - 1; i >= 0; i-- {
+		if *f.chain[i].Hash == hash {
+			return i
+		}
+	}
+
+	return -1
+}
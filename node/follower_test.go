@@ -0,0 +1,189 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// chainBackend serves blocks over a pipe and lets the test announce new heads
+type chainBackend struct {
+	*pipeBackend
+	mu       sync.Mutex
+	byHash   map[string]json.RawMessage
+	byNumber map[uint64]json.RawMessage
+	fetched  chan string
+}
+
+// testBlockHash returns a unique hash for block n on the given fork
+func testBlockHash(n uint64, fork int) string {
+	return fmt.Sprintf("0x%060x%04x", n, fork)
+}
+
+func newChainBackend(t *testing.T, ctx context.Context) (*client, *chainBackend) {
+	tr, pipe := newPipeTransport(t, ctx)
+	b := &chainBackend{
+		pipeBackend: pipe,
+		byHash:      make(map[string]json.RawMessage),
+		byNumber:    make(map[uint64]json.RawMessage),
+		fetched:     make(chan string, 100),
+	}
+
+	go func() {
+		for r := range pipe.requests {
+			b.mu.Lock()
+			var result interface{} = nil
+			switch r.Method {
+			case "eth_subscribe":
+				result = "0x1"
+			case "eth_unsubscribe":
+				result = true
+			case "eth_getBlockByHash":
+				hash := ""
+				require.NoError(t, r.Params.UnmarshalSingleParam(0, &hash))
+				if block, ok := b.byHash[hash]; ok {
+					result = block
+				}
+				b.fetched <- hash
+			case "eth_getBlockByNumber":
+				q := eth.Quantity{}
+				require.NoError(t, r.Params.UnmarshalSingleParam(0, &q))
+				if block, ok := b.byNumber[q.UInt64()]; ok {
+					result = block
+				}
+			}
+			b.mu.Unlock()
+			pipe.respond(r, result)
+		}
+	}()
+
+	return &client{transport: tr}, b
+}
+
+// mine makes block n on fork canonical, built on top of block n-1 of parentFork
+func (b *chainBackend) mine(n uint64, fork, parentFork int) string {
+	hash := testBlockHash(n, fork)
+	block := json.RawMessage(`{"number":"` + eth.QuantityFromUInt64(n).String() + `","hash":"` + hash + `","parentHash":"` + testBlockHash(n-1, parentFork) + `",` +
+		`"sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","logsBloom":"0x` + strings.Repeat("00", 256) + `",` +
+		`"transactionsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","stateRoot":"0x4d301d25429a2114965ce829e2c8b96b69ab826aca09aafa7fe71428d5545651",` +
+		`"receiptsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","miner":"0x007733a1fe69cf3f2cf989f81c7b4cac1693387a","difficulty":"0x0",` +
+		`"totalDifficulty":"0x0","extraData":"0x","size":"0x200","gasLimit":"0x1c9c380","gasUsed":"0x0","timestamp":"0x5c6341f4","transactions":[],"uncles":[]}`)
+
+	b.mu.Lock()
+	b.byHash[hash] = block
+	b.byNumber[n] = block
+	b.mu.Unlock()
+	return hash
+}
+
+// announce sends a newHeads notification for the given block and waits for the follower to fetch it, since
+// notifications aren't guaranteed to be delivered in the order they were sent
+func (b *chainBackend) announce(n uint64, hash string) {
+	b.send(&jsonrpc.Notification{
+		Method: "eth_subscription",
+		Params: []byte(`{"subscription":"0x1","result":{"number":"` + eth.QuantityFromUInt64(n).String() + `","hash":"` + hash + `"}}`),
+	})
+
+	for {
+		select {
+		case fetched := <-b.fetched:
+			if fetched == hash {
+				return
+			}
+		case <-time.After(time.Second):
+			b.t.Fatalf("block %s was never fetched", hash)
+		}
+	}
+}
+
+func requireBlockEvent(t *testing.T, f *Follower, typ BlockEventType, hash string) {
+	select {
+	case event, ok := <-f.Events():
+		require.True(t, ok, "follower stopped: %v", f.Err())
+		require.Equal(t, typ, event.Type)
+		require.Equal(t, hash, event.Block.Hash.String())
+	case <-time.After(time.Second):
+		t.Fatalf("timed out waiting for %s event for %s", typ, hash)
+	}
+}
+
+func TestFollower_Reorg(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c, backend := newChainBackend(t, ctx)
+	f, err := NewFollower(ctx, c, FollowerConfig{})
+	require.NoError(t, err)
+
+	backend.announce(1, backend.mine(1, 0, 0))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(1, 0))
+
+	// blocks 2 and 3 are missed and have to be backfilled
+	backend.mine(2, 0, 0)
+	backend.mine(3, 0, 0)
+	backend.announce(4, backend.mine(4, 0, 0))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(2, 0))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(3, 0))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(4, 0))
+
+	// a competing branch forks off after block 2 and overtakes us
+	backend.mine(3, 1, 0)
+	backend.mine(4, 1, 1)
+	backend.announce(5, backend.mine(5, 1, 1))
+	requireBlockEvent(t, f, BlockRemoved, testBlockHash(4, 0))
+	requireBlockEvent(t, f, BlockRemoved, testBlockHash(3, 0))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(3, 1))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(4, 1))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(5, 1))
+
+	cancel()
+	<-f.Done()
+	require.Equal(t, context.Canceled, f.Err())
+}
+
+func TestFollower_Confirmations(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err := NewFollower(ctx, nil, FollowerConfig{Confirmations: 4, Window: 4})
+	require.Error(t, err)
+
+	c, backend := newChainBackend(t, ctx)
+	f, err := NewFollower(ctx, c, FollowerConfig{Confirmations: 2, Window: 4})
+	require.NoError(t, err)
+
+	backend.announce(1, backend.mine(1, 0, 0))
+	backend.announce(2, backend.mine(2, 0, 0))
+	// a reorg shallower than the confirmation depth is never seen
+	backend.announce(2, backend.mine(2, 1, 0))
+	backend.announce(3, backend.mine(3, 1, 1))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(1, 0))
+
+	backend.announce(4, backend.mine(4, 1, 1))
+	requireBlockEvent(t, f, BlockAdded, testBlockHash(2, 1))
+
+	// the window only holds 4 blocks, so forking off below it can't be resolved
+	for n := uint64(5); n <= 8; n++ {
+		backend.announce(n, backend.mine(n, 1, 1))
+		requireBlockEvent(t, f, BlockAdded, testBlockHash(n-2, 1))
+	}
+
+	for n := uint64(3); n <= 9; n++ {
+		backend.mine(n, 2, 2)
+	}
+	backend.mine(3, 2, 1)
+	backend.announce(9, testBlockHash(9, 2))
+
+	<-f.Done()
+	require.Error(t, f.Err())
+	require.Contains(t, f.Err().Error(), ErrReorgTooDeep.Error())
+}
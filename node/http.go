@@ -7,6 +7,8 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -14,14 +16,18 @@ import (
 	"github.com/pkg/errors"
 )
 
-func newHTTPTransport(ctx context.Context, parsedURL *url.URL) (transport, error) {
+func newHTTPTransport(ctx context.Context, parsedURL *url.URL, opts *clientOptions) (transport, error) {
 	return &httpTransport{
 		rawURL: parsedURL.String(),
+		opts:   opts,
+		logger: opts.loggerFor(parsedURL.String()),
 	}, nil
 }
 
 type httpTransport struct {
 	rawURL string
+	opts   *clientOptions
+	logger Logger
 	client *http.Client
 	once   sync.Once
 }
@@ -34,6 +40,7 @@ func (t *httpTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jsonr
 
 	body, err := t.dispatchBytes(ctx, b)
 	if err != nil {
+		t.logger.Debug("request failed", "request_id", r.ID, "method", r.Method, "error", err)
 		return nil, errors.Wrap(err, "could not dispatch request")
 	}
 
@@ -46,6 +53,48 @@ func (t *httpTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jsonr
 	return &jr, nil
 }
 
+func (t *httpTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	proxied, err := proxyBatch(batch)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := json.Marshal(proxied)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode batch request json")
+	}
+
+	body, err := t.dispatchBytes(ctx, b)
+	if err != nil {
+		t.logger.Debug("batch request failed", "batch_size", len(batch), "error", err)
+		return nil, errors.Wrap(err, "could not dispatch batch request")
+	}
+
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) > 0 && trimmed[0] == '{' {
+		// Backends that reject a batch outright (e.g. it is too large) reply with a single response object
+		jr := jsonrpc.RawResponse{}
+		err = json.Unmarshal(trimmed, &jr)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not decode response json")
+		}
+
+		if jr.Error != nil {
+			return nil, errors.Errorf("batch rejected: %s", string(*jr.Error))
+		}
+
+		return nil, errors.New("backend did not return a batch response")
+	}
+
+	responses := make([]*jsonrpc.RawResponse, 0, len(batch))
+	err = json.Unmarshal(trimmed, &responses)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode batch response json")
+	}
+
+	return matchBatch(batch, responses)
+}
+
 func (t *httpTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
 	return nil, errors.New("subscriptions not supported over HTTP")
 }
@@ -56,12 +105,17 @@ func (t *httpTransport) IsBidirectional() bool {
 
 func (t *httpTransport) dispatchBytes(ctx context.Context, input []byte) ([]byte, error) {
 	t.once.Do(func() {
+		if t.opts.httpClient != nil {
+			t.client = t.opts.httpClient
+			return
+		}
+
 		// Since this client is only ever used to access a single endpoint,
 		// we allow all the idle connections to point that host
 		tr := http.DefaultTransport.(*http.Transport).Clone()
 		tr.MaxIdleConnsPerHost = tr.MaxIdleConns
 		t.client = &http.Client{
-			Timeout:   120 * time.Second,
+			Timeout:   t.opts.httpTimeout,
 			Transport: tr,
 		}
 	})
@@ -72,7 +126,11 @@ func (t *httpTransport) dispatchBytes(ctx context.Context, input []byte) ([]byte
 	}
 
 	r = r.WithContext(ctx)
-	r.Header.Add("Content-Type", "application/json")
+	r.Header, err = t.opts.header()
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Set("Content-Type", "application/json")
 
 	resp, err := t.client.Do(r)
 	if err != nil {
@@ -86,5 +144,126 @@ func (t *httpTransport) dispatchBytes(ctx context.Context, input []byte) ([]byte
 		return nil, errors.Wrap(err, "error reading body")
 	}
 
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, newHTTPError(resp, body)
+	}
+
+	// proxies and load balancers in front of a node like to answer with an HTML page
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || (trimmed[0] != '{' && trimmed[0] != '[') {
+		return nil, newHTTPError(resp, body)
+	}
+
 	return body, nil
 }
+
+// maxHTTPErrorBody is how much of the response body an HTTPError keeps
+const maxHTTPErrorBody = 512
+
+// HTTPError is returned by HTTP transports when the backend replies with a non-2xx status,
+// or with a body that is not JSON.  It can be recovered from a returned error with errors.Cause.
+type HTTPError struct {
+	// StatusCode and Status are the HTTP status of the response
+	StatusCode int
+	Status     string
+
+	// RetryAfter is how long the Retry-After header asked us to wait, or 0 if it was not set
+	RetryAfter time.Duration
+
+	// Body is the start of the response body, truncated to 512 bytes
+	Body string
+}
+
+func newHTTPError(resp *http.Response, body []byte) *HTTPError {
+	e := HTTPError{
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), time.Now()),
+	}
+
+	if len(body) > maxHTTPErrorBody {
+		body = body[:maxHTTPErrorBody]
+	}
+	e.Body = string(bytes.TrimSpace(body))
+
+	return &e
+}
+
+func (e *HTTPError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("http error ")
+	if e.Status != "" {
+		sb.WriteString(e.Status)
+	} else {
+		sb.WriteString(strconv.Itoa(e.StatusCode))
+	}
+
+	if e.StatusCode >= 200 && e.StatusCode <= 299 {
+		sb.WriteString(", response is not json")
+	}
+
+	if e.RetryAfter > 0 {
+		sb.WriteString(", retry after ")
+		sb.WriteString(e.RetryAfter.String())
+	}
+
+	if e.Body != "" {
+		sb.WriteString(": ")
+		sb.WriteString(e.Body)
+	}
+
+	return sb.String()
+}
+
+// IsRateLimited returns true if the backend rejected the request because too many requests were made
+func (e *HTTPError) IsRateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsUpstream returns true if a gateway or proxy in front of the node failed or the node was unavailable
+func (e *HTTPError) IsUpstream() bool {
+	switch e.StatusCode {
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// Temporary returns true if the same request may succeed when it is sent again later
+func (e *HTTPError) Temporary() bool {
+	return e.IsRateLimited() || e.IsUpstream() || e.StatusCode == http.StatusRequestTimeout
+}
+
+// IsRateLimited returns true if err was caused by an HTTPError for a rate limited request
+func IsRateLimited(err error) bool {
+	e, ok := errors.Cause(err).(*HTTPError)
+	return ok && e.IsRateLimited()
+}
+
+// IsUpstreamError returns true if err was caused by an HTTPError from a failing gateway or unavailable node
+func IsUpstreamError(err error) bool {
+	e, ok := errors.Cause(err).(*HTTPError)
+	return ok && e.IsUpstream()
+}
+
+// parseRetryAfter decodes a Retry-After header, which is either a number of seconds or an HTTP date
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+
+	if at, err := http.ParseTime(value); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+
+	return 0
+}
@@ -0,0 +1,88 @@
+package node
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func TestHTTPTransport_HTTPError(t *testing.T) {
+	t.Run("rate limited", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"error":{"code":-32005,"message":"daily request count exceeded"}}`))
+		})
+
+		_, err := tr.Request(context.Background(), jsonrpc.MustRequest(1, "eth_blockNumber"))
+		require.Error(t, err)
+		require.True(t, IsRateLimited(err))
+		require.False(t, IsUpstreamError(err))
+
+		e, ok := errors.Cause(err).(*HTTPError)
+		require.True(t, ok)
+		require.Equal(t, http.StatusTooManyRequests, e.StatusCode)
+		require.Equal(t, 3*time.Second, e.RetryAfter)
+		require.True(t, e.Temporary())
+		require.Contains(t, err.Error(), "daily request count exceeded")
+	})
+
+	t.Run("bad gateway", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("<html>" + strings.Repeat("x", 2000) + "</html>"))
+		})
+
+		_, err := tr.RequestBatch(context.Background(), newTestBatch())
+		require.True(t, IsUpstreamError(err))
+
+		e := errors.Cause(err).(*HTTPError)
+		require.Len(t, e.Body, maxHTTPErrorBody)
+		require.Equal(t, time.Duration(0), e.RetryAfter)
+	})
+
+	t.Run("not json", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("<html>maintenance</html>"))
+		})
+
+		_, err := tr.Request(context.Background(), jsonrpc.MustRequest(1, "eth_blockNumber"))
+		e, ok := errors.Cause(err).(*HTTPError)
+		require.True(t, ok)
+		require.Equal(t, http.StatusOK, e.StatusCode)
+		require.False(t, e.Temporary())
+		require.Contains(t, e.Error(), "not json")
+		require.Contains(t, e.Error(), "maintenance")
+	})
+
+	t.Run("bad request", func(t *testing.T) {
+		tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		_, err := tr.Request(context.Background(), jsonrpc.MustRequest(1, "eth_blockNumber"))
+		e, ok := errors.Cause(err).(*HTTPError)
+		require.True(t, ok)
+		require.False(t, e.Temporary())
+		require.False(t, IsRateLimited(err))
+		require.False(t, IsUpstreamError(errors.New("unrelated")))
+	})
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 13, 12, 0, 0, 0, time.UTC)
+
+	require.Equal(t, time.Duration(0), parseRetryAfter("", now))
+	require.Equal(t, 120*time.Second, parseRetryAfter("120", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("-1", now))
+	require.Equal(t, 30*time.Second, parseRetryAfter("Wed, 13 Mar 2024 12:00:30 GMT", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("Wed, 13 Mar 2024 11:00:00 GMT", now))
+	require.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
@@ -0,0 +1,117 @@
+package node
+
+import (
+	"context"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// RequestInvoker sends a request on to the next interceptor, or finally to the transport.
+type RequestInvoker func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error)
+
+// RequestInterceptor wraps every request made by a client.  It may inspect or modify the request, call next
+// zero or more times, and inspect or replace the response and error.  Timing a request is a matter of noting
+// the time before and after calling next.
+type RequestInterceptor func(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error)
+
+// BatchInvoker sends a batch on to the next interceptor, or finally to the transport.
+type BatchInvoker func(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error)
+
+// BatchInterceptor wraps every batch request made by a client, like a RequestInterceptor.
+type BatchInterceptor func(ctx context.Context, batch jsonrpc.BatchRequest, next BatchInvoker) (jsonrpc.BatchRawResponse, error)
+
+// SubscribeInvoker makes a subscription with the next interceptor, or finally with the transport.
+type SubscribeInvoker func(ctx context.Context, r *jsonrpc.Request) (Subscription, error)
+
+// SubscribeInterceptor wraps every subscription made by a client.  It sees the eth_subscribe request and the
+// resulting Subscription, which it may wrap to observe notifications.
+type SubscribeInterceptor func(ctx context.Context, r *jsonrpc.Request, next SubscribeInvoker) (Subscription, error)
+
+// WithRequestInterceptors adds interceptors around every request.  Interceptors run in the order they are
+// added, so the first one added sees the request first and the response last.
+func WithRequestInterceptors(interceptors ...RequestInterceptor) Option {
+	return func(o *clientOptions) {
+		o.requestInterceptors = append(o.requestInterceptors, interceptors...)
+	}
+}
+
+// WithBatchInterceptors adds interceptors around every batch request, in the same order as WithRequestInterceptors.
+func WithBatchInterceptors(interceptors ...BatchInterceptor) Option {
+	return func(o *clientOptions) {
+		o.batchInterceptors = append(o.batchInterceptors, interceptors...)
+	}
+}
+
+// WithSubscribeInterceptors adds interceptors around every subscription, in the same order as WithRequestInterceptors.
+func WithSubscribeInterceptors(interceptors ...SubscribeInterceptor) Option {
+	return func(o *clientOptions) {
+		o.subscribeInterceptors = append(o.subscribeInterceptors, interceptors...)
+	}
+}
+
+// intercept wraps t with the interceptors in o, if there are any
+func intercept(t transport, o *clientOptions) transport {
+	if len(o.requestInterceptors) == 0 && len(o.batchInterceptors) == 0 && len(o.subscribeInterceptors) == 0 {
+		return t
+	}
+
+	it := interceptingTransport{
+		transport: t,
+		request:   t.Request,
+		batch:     t.RequestBatch,
+		subscribe: t.Subscribe,
+	}
+
+	for i := len(o.requestInterceptors) - 1; i >= 0; i-- {
+		interceptor, next := o.requestInterceptors[i], it.request
+		it.request = func(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+			return interceptor(ctx, r, next)
+		}
+	}
+
+	for i := len(o.batchInterceptors) - 1; i >= 0; i-- {
+		interceptor, next := o.batchInterceptors[i], it.batch
+		it.batch = func(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+			return interceptor(ctx, batch, next)
+		}
+	}
+
+	for i := len(o.subscribeInterceptors) - 1; i >= 0; i-- {
+		interceptor, next := o.subscribeInterceptors[i], it.subscribe
+		it.subscribe = func(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
+			return interceptor(ctx, r, next)
+		}
+	}
+
+	return &it
+}
+
+// interceptingTransport runs the interceptor chains in front of the transport it wraps
+type interceptingTransport struct {
+	transport
+	request   RequestInvoker
+	batch     BatchInvoker
+	subscribe SubscribeInvoker
+}
+
+func (t *interceptingTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	return t.request(ctx, r)
+}
+
+func (t *interceptingTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	return t.batch(ctx, batch)
+}
+
+func (t *interceptingTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
+	return t.subscribe(ctx, r)
+}
+
+// Done forwards the liveness of the wrapped transport, so wrapping a connection does not hide that it dropped.  The
+// channel is nil, and so never closes, for transports without a connection to lose.
+func (t *interceptingTransport) Done() <-chan struct{} {
+	if done, ok := t.transport.(interface{ Done() <-chan struct{} }); ok {
+		return done.Done()
+	}
+
+	return nil
+}
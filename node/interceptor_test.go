@@ -0,0 +1,144 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// countingSubscription counts the notifications read from a wrapped subscription
+type countingSubscription struct {
+	Subscription
+	ch    chan *jsonrpc.Notification
+	count int
+}
+
+func (s *countingSubscription) Ch() <-chan *jsonrpc.Notification {
+	return s.ch
+}
+
+func TestInterceptors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	order := make([]string, 0)
+	tagging := func(name string) RequestInterceptor {
+		return func(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error) {
+			order = append(order, name+" before "+r.Method)
+			response, err := next(ctx, r)
+			order = append(order, name+" after")
+			return response, err
+		}
+	}
+
+	rewrite := func(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error) {
+		r.Method = "net_version"
+		return next(ctx, r)
+	}
+
+	var elapsed time.Duration
+	timing := func(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error) {
+		start := time.Now()
+		defer func() { elapsed = time.Since(start) }()
+		return next(ctx, r)
+	}
+
+	batches := 0
+	counting := func(ctx context.Context, batch jsonrpc.BatchRequest, next BatchInvoker) (jsonrpc.BatchRawResponse, error) {
+		batches++
+		return next(ctx, batch)
+	}
+
+	var wrapped *countingSubscription
+	observing := func(ctx context.Context, r *jsonrpc.Request, next SubscribeInvoker) (Subscription, error) {
+		sub, err := next(ctx, r)
+		if err != nil {
+			return nil, err
+		}
+
+		wrapped = &countingSubscription{Subscription: sub, ch: make(chan *jsonrpc.Notification)}
+		go func() {
+			defer close(wrapped.ch)
+			for n := range sub.Ch() {
+				wrapped.count++
+				wrapped.ch <- n
+			}
+		}()
+		return wrapped, nil
+	}
+
+	tr, backend := newPipeTransport(t, ctx)
+	c := &client{transport: intercept(tr, newClientOptions(
+		WithRequestInterceptors(tagging("outer"), tagging("inner")),
+		WithRequestInterceptors(rewrite, timing),
+		WithBatchInterceptors(counting),
+		WithSubscribeInterceptors(observing),
+	))}
+
+	go func() {
+		r := backend.next()
+		require.Equal(t, "net_version", r.Method)
+		backend.respond(r, "1")
+	}()
+
+	response, err := c.Request(ctx, jsonrpc.MustRequest(1, "eth_chainId"))
+	require.NoError(t, err)
+	require.Equal(t, json.RawMessage(`"1"`), response.Result)
+	require.Equal(t, []string{"outer before eth_chainId", "inner before eth_chainId", "inner after", "outer after"}, order)
+	require.True(t, elapsed > 0)
+
+	go func() {
+		for i := 0; i < 3; i++ {
+			r := backend.next()
+			backend.respond(r, "0x1")
+		}
+	}()
+
+	_, err = c.RequestBatch(ctx, newTestBatch())
+	require.NoError(t, err)
+	require.Equal(t, 1, batches)
+
+	go func() {
+		r := backend.next()
+		backend.respond(r, "0x9")
+		backend.send(&jsonrpc.Notification{Method: "eth_subscription", Params: json.RawMessage(`{"subscription":"0x9","result":"0x1"}`)})
+	}()
+
+	sub, err := c.SubscribeNewPendingTransactions(ctx)
+	require.NoError(t, err)
+	<-sub.Ch()
+	require.Equal(t, wrapped, sub)
+	require.Equal(t, 1, wrapped.count)
+
+	// without interceptors the transport is used as-is
+	require.Equal(t, tr, intercept(tr, newClientOptions()))
+}
+
+func TestInterceptors_Done(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr, _ := newPipeTransport(t, ctx)
+	wrapped := intercept(tr, newClientOptions(WithRateLimit(RateLimitConfig{RequestsPerSecond: 10})))
+	done, ok := wrapped.(interface{ Done() <-chan struct{} })
+	require.True(t, ok)
+
+	select {
+	case <-done.Done():
+		t.Fatal("transport should still be running")
+	default:
+	}
+
+	// the multi client relies on this to notice a dropped connection behind interceptors
+	cancel()
+	select {
+	case <-done.Done():
+	case <-time.After(time.Second):
+		t.Fatal("intercepting transport did not report the wrapped one shutting down")
+	}
+}
@@ -5,6 +5,7 @@ package node
 
 import (
 	"context"
+	"math/big"
 
 	"github.com/justinwongcn/go-ethlibs/eth"
 	"github.com/justinwongcn/go-ethlibs/jsonrpc"
@@ -15,6 +16,11 @@ type Requester interface {
 	Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error)
 }
 
+type BatchRequester interface {
+	// RequestBatch method can be used to send a JSONRPC batch and receive the responses in the same order as the requests
+	RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error)
+}
+
 type Subscriber interface {
 	// Subscribe method can be used to subscribe via eth_subscribe
 	Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error)
@@ -23,6 +29,7 @@ type Subscriber interface {
 // Client represents a connection to an ethereum node
 type Client interface {
 	Requester
+	BatchRequester
 	Subscriber
 
 	// URL returns the backend URL we are connected to
@@ -40,15 +47,31 @@ type Client interface {
 	// EstimateGas returns the estimate gas
 	EstimateGas(ctx context.Context, msg eth.Transaction) (uint64, error)
 
+	// EstimateGasBig is the same as EstimateGas but keeps the full precision of the result
+	EstimateGasBig(ctx context.Context, msg eth.Transaction) (*big.Int, error)
+
 	// MaxPriorityFeePerGas (EIP1559) returns the suggested tip for block
 	MaxPriorityFeePerGas(ctx context.Context) (uint64, error)
 
+	// MaxPriorityFeePerGasBig is the same as MaxPriorityFeePerGas but keeps the full precision of the result
+	MaxPriorityFeePerGasBig(ctx context.Context) (*big.Int, error)
+
 	// GasPrice (Legacy) returns the suggested gas price
 	GasPrice(ctx context.Context) (uint64, error)
 
-	// GetBalance returns the balance of the account of given address
+	// GasPriceBig is the same as GasPrice but keeps the full precision of the result
+	GasPriceBig(ctx context.Context) (*big.Int, error)
+
+	// FeeHistory returns the base fees, gas used ratios and priority fees at the given reward percentiles of
+	// the blockCount blocks up to and including newest.  Error responses are returned as a *jsonrpc.Error.
+	FeeHistory(ctx context.Context, blockCount uint64, newest eth.BlockNumberOrTag, rewardPercentiles []float64) (*eth.FeeHistory, error)
+
+	// GetBalance returns the balance of the account of given address, or ErrQuantityOverflow if it does not fit in a uint64
 	GetBalance(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error)
 
+	// GetBalanceBig returns the balance in wei of the account of given address with full 256-bit precision
+	GetBalanceBig(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (*big.Int, error)
+
 	// GetTransactionCount get the pending nonce for public address
 	GetTransactionCount(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error)
 
@@ -73,12 +96,39 @@ type Client interface {
 	// SubscribeNewPendingTransactions initiates a subscription for newPendingTransaction events
 	SubscribeNewPendingTransactions(ctx context.Context) (Subscription, error)
 
+	// SubscribeNewPendingTransactionBodies initiates a subscription for newPendingTransaction events carrying full transactions
+	SubscribeNewPendingTransactionBodies(ctx context.Context) (Subscription, error)
+
+	// SubscribeLogs initiates a subscription for logs matching the passed in filter, which must not have a block range
+	SubscribeLogs(ctx context.Context, filter eth.LogFilter) (Subscription, error)
+
+	// SubscribeSyncing initiates a subscription for changes in the sync status of the node
+	SubscribeSyncing(ctx context.Context) (Subscription, error)
+
 	// TransactionReceipt can be used to get a TransactionReceipt for a particular transaction
 	TransactionReceipt(ctx context.Context, hash string) (*eth.TransactionReceipt, error)
 
 	// Logs returns an array of Logs matching the passed in filter
 	Logs(ctx context.Context, filter eth.LogFilter) ([]eth.Log, error)
 
+	// NewFilter installs a log filter on the backend, which is uninstalled when ctx finishes
+	NewFilter(ctx context.Context, filter eth.LogFilter) (*Filter, error)
+
+	// NewBlockFilter installs a filter for new block hashes on the backend, which is uninstalled when ctx finishes
+	NewBlockFilter(ctx context.Context) (*Filter, error)
+
+	// NewPendingTransactionFilter installs a filter for pending transaction hashes on the backend, which is uninstalled when ctx finishes
+	NewPendingTransactionFilter(ctx context.Context) (*Filter, error)
+
+	// GetFilterChanges returns the logs or hashes reported by the filter with the given ID since it was last polled
+	GetFilterChanges(ctx context.Context, id string) (*FilterChanges, error)
+
+	// GetFilterLogs returns all logs matching the log filter with the given ID
+	GetFilterLogs(ctx context.Context, id string) ([]eth.Log, error)
+
+	// UninstallFilter removes the filter with the given ID, returning false if the backend did not know it
+	UninstallFilter(ctx context.Context, id string) (bool, error)
+
 	// IsBidirectional returns true if the under laying transport supports bidirectional features such as subscriptions
 	IsBidirectional() bool
 
@@ -91,6 +141,12 @@ type Client interface {
 	// GetCode returns the code at a given address
 	GetCode(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (string, error)
 
+	// GetStorageAt returns the 32 byte value of a storage slot of the account at a given address
+	GetStorageAt(ctx context.Context, address eth.Address, slot eth.Data, numberOrTag eth.BlockNumberOrTag) (eth.Data, error)
+
+	// GetProof returns the account at a given address and the given storage slots, with the Merkle-Patricia proofs of each
+	GetProof(ctx context.Context, address eth.Address, slots []eth.Data, numberOrTag eth.BlockNumberOrTag) (*eth.AccountProof, error)
+
 	// SendTransaction creates new message call transaction or a contract creation
 	SendTransaction(ctx context.Context, msg eth.Transaction) (string, error)
 
@@ -115,4 +171,10 @@ type Subscription interface {
 	ID() string
 	Ch() <-chan *jsonrpc.Notification
 	Unsubscribe(ctx context.Context) error
+
+	// Done returns a channel that is closed once the subscription has terminated and Ch() has been closed
+	Done() <-chan struct{}
+
+	// Err returns nil while the subscription is active, and a *SubscriptionError describing why it terminated afterwards
+	Err() error
 }
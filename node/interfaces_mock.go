@@ -6,6 +6,7 @@ package node
 
 import (
 	context "context"
+	big "math/big"
 	reflect "reflect"
 
 	gomock "github.com/golang/mock/gomock"
@@ -51,6 +52,44 @@ func (mr *MockRequesterMockRecorder) Request(ctx, r interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Request", reflect.TypeOf((*MockRequester)(nil).Request), ctx, r)
 }
 
+// MockBatchRequester is a mock of BatchRequester interface.
+type MockBatchRequester struct {
+	ctrl     *gomock.Controller
+	recorder *MockBatchRequesterMockRecorder
+}
+
+// MockBatchRequesterMockRecorder is the mock recorder for MockBatchRequester.
+type MockBatchRequesterMockRecorder struct {
+	mock *MockBatchRequester
+}
+
+// NewMockBatchRequester creates a new mock instance.
+func NewMockBatchRequester(ctrl *gomock.Controller) *MockBatchRequester {
+	mock := &MockBatchRequester{ctrl: ctrl}
+	mock.recorder = &MockBatchRequesterMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockBatchRequester) EXPECT() *MockBatchRequesterMockRecorder {
+	return m.recorder
+}
+
+// RequestBatch mocks base method.
+func (m *MockBatchRequester) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestBatch", ctx, batch)
+	ret0, _ := ret[0].(jsonrpc.BatchRawResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestBatch indicates an expected call of RequestBatch.
+func (mr *MockBatchRequesterMockRecorder) RequestBatch(ctx, batch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestBatch", reflect.TypeOf((*MockBatchRequester)(nil).RequestBatch), ctx, batch)
+}
+
 // MockSubscriber is a mock of Subscriber interface.
 type MockSubscriber struct {
 	ctrl     *gomock.Controller
@@ -128,18 +167,18 @@ func (mr *MockClientMockRecorder) BlockByHash(ctx, hash, full interface{}) *gomo
 }
 
 // BlockByNumber mocks base method.
-func (m *MockClient) BlockByNumber(ctx context.Context, number uint64, full bool) (*eth.Block, error) {
+func (m *MockClient) BlockByNumber(ctx context.Context, numberOrTag eth.BlockNumberOrTag, full bool) (*eth.Block, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "BlockByNumber", ctx, number, full)
+	ret := m.ctrl.Call(m, "BlockByNumber", ctx, numberOrTag, full)
 	ret0, _ := ret[0].(*eth.Block)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // BlockByNumber indicates an expected call of BlockByNumber.
-func (mr *MockClientMockRecorder) BlockByNumber(ctx, number, full interface{}) *gomock.Call {
+func (mr *MockClientMockRecorder) BlockByNumber(ctx, numberOrTag, full interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockByNumber", reflect.TypeOf((*MockClient)(nil).BlockByNumber), ctx, number, full)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockByNumber", reflect.TypeOf((*MockClient)(nil).BlockByNumber), ctx, numberOrTag, full)
 }
 
 // BlockByNumberOrTag mocks base method.
@@ -172,6 +211,21 @@ func (mr *MockClientMockRecorder) BlockNumber(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BlockNumber", reflect.TypeOf((*MockClient)(nil).BlockNumber), ctx)
 }
 
+// Call mocks base method.
+func (m *MockClient) Call(ctx context.Context, msg eth.Transaction, numberOrTag eth.BlockNumberOrTag) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Call", ctx, msg, numberOrTag)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Call indicates an expected call of Call.
+func (mr *MockClientMockRecorder) Call(ctx, msg, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Call", reflect.TypeOf((*MockClient)(nil).Call), ctx, msg, numberOrTag)
+}
+
 // ChainId mocks base method.
 func (m *MockClient) ChainId(ctx context.Context) (string, error) {
 	m.ctrl.T.Helper()
@@ -202,6 +256,36 @@ func (mr *MockClientMockRecorder) EstimateGas(ctx, msg interface{}) *gomock.Call
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateGas", reflect.TypeOf((*MockClient)(nil).EstimateGas), ctx, msg)
 }
 
+// EstimateGasBig mocks base method.
+func (m *MockClient) EstimateGasBig(ctx context.Context, msg eth.Transaction) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EstimateGasBig", ctx, msg)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EstimateGasBig indicates an expected call of EstimateGasBig.
+func (mr *MockClientMockRecorder) EstimateGasBig(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EstimateGasBig", reflect.TypeOf((*MockClient)(nil).EstimateGasBig), ctx, msg)
+}
+
+// FeeHistory mocks base method.
+func (m *MockClient) FeeHistory(ctx context.Context, blockCount uint64, newest eth.BlockNumberOrTag, rewardPercentiles []float64) (*eth.FeeHistory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FeeHistory", ctx, blockCount, newest, rewardPercentiles)
+	ret0, _ := ret[0].(*eth.FeeHistory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FeeHistory indicates an expected call of FeeHistory.
+func (mr *MockClientMockRecorder) FeeHistory(ctx, blockCount, newest, rewardPercentiles interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FeeHistory", reflect.TypeOf((*MockClient)(nil).FeeHistory), ctx, blockCount, newest, rewardPercentiles)
+}
+
 // GasPrice mocks base method.
 func (m *MockClient) GasPrice(ctx context.Context) (uint64, error) {
 	m.ctrl.T.Helper()
@@ -217,6 +301,21 @@ func (mr *MockClientMockRecorder) GasPrice(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GasPrice", reflect.TypeOf((*MockClient)(nil).GasPrice), ctx)
 }
 
+// GasPriceBig mocks base method.
+func (m *MockClient) GasPriceBig(ctx context.Context) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GasPriceBig", ctx)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GasPriceBig indicates an expected call of GasPriceBig.
+func (mr *MockClientMockRecorder) GasPriceBig(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GasPriceBig", reflect.TypeOf((*MockClient)(nil).GasPriceBig), ctx)
+}
+
 // GetBalance mocks base method.
 func (m *MockClient) GetBalance(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
 	m.ctrl.T.Helper()
@@ -232,6 +331,156 @@ func (mr *MockClientMockRecorder) GetBalance(ctx, address, numberOrTag interface
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalance", reflect.TypeOf((*MockClient)(nil).GetBalance), ctx, address, numberOrTag)
 }
 
+// GetBalanceBig mocks base method.
+func (m *MockClient) GetBalanceBig(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBalanceBig", ctx, address, numberOrTag)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBalanceBig indicates an expected call of GetBalanceBig.
+func (mr *MockClientMockRecorder) GetBalanceBig(ctx, address, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBalanceBig", reflect.TypeOf((*MockClient)(nil).GetBalanceBig), ctx, address, numberOrTag)
+}
+
+// GetBlockTransactionCountByHash mocks base method.
+func (m *MockClient) GetBlockTransactionCountByHash(ctx context.Context, hash string) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockTransactionCountByHash", ctx, hash)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockTransactionCountByHash indicates an expected call of GetBlockTransactionCountByHash.
+func (mr *MockClientMockRecorder) GetBlockTransactionCountByHash(ctx, hash interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockTransactionCountByHash", reflect.TypeOf((*MockClient)(nil).GetBlockTransactionCountByHash), ctx, hash)
+}
+
+// GetBlockTransactionCountByNumber mocks base method.
+func (m *MockClient) GetBlockTransactionCountByNumber(ctx context.Context, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetBlockTransactionCountByNumber", ctx, numberOrTag)
+	ret0, _ := ret[0].(uint64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetBlockTransactionCountByNumber indicates an expected call of GetBlockTransactionCountByNumber.
+func (mr *MockClientMockRecorder) GetBlockTransactionCountByNumber(ctx, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetBlockTransactionCountByNumber", reflect.TypeOf((*MockClient)(nil).GetBlockTransactionCountByNumber), ctx, numberOrTag)
+}
+
+// GetCode mocks base method.
+func (m *MockClient) GetCode(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetCode", ctx, address, numberOrTag)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetCode indicates an expected call of GetCode.
+func (mr *MockClientMockRecorder) GetCode(ctx, address, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetCode", reflect.TypeOf((*MockClient)(nil).GetCode), ctx, address, numberOrTag)
+}
+
+// GetFilterChanges mocks base method.
+func (m *MockClient) GetFilterChanges(ctx context.Context, id string) (*FilterChanges, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilterChanges", ctx, id)
+	ret0, _ := ret[0].(*FilterChanges)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilterChanges indicates an expected call of GetFilterChanges.
+func (mr *MockClientMockRecorder) GetFilterChanges(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilterChanges", reflect.TypeOf((*MockClient)(nil).GetFilterChanges), ctx, id)
+}
+
+// GetFilterLogs mocks base method.
+func (m *MockClient) GetFilterLogs(ctx context.Context, id string) ([]eth.Log, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetFilterLogs", ctx, id)
+	ret0, _ := ret[0].([]eth.Log)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetFilterLogs indicates an expected call of GetFilterLogs.
+func (mr *MockClientMockRecorder) GetFilterLogs(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetFilterLogs", reflect.TypeOf((*MockClient)(nil).GetFilterLogs), ctx, id)
+}
+
+// GetProof mocks base method.
+func (m *MockClient) GetProof(ctx context.Context, address eth.Address, slots []eth.Data, numberOrTag eth.BlockNumberOrTag) (*eth.AccountProof, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetProof", ctx, address, slots, numberOrTag)
+	ret0, _ := ret[0].(*eth.AccountProof)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetProof indicates an expected call of GetProof.
+func (mr *MockClientMockRecorder) GetProof(ctx, address, slots, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetProof", reflect.TypeOf((*MockClient)(nil).GetProof), ctx, address, slots, numberOrTag)
+}
+
+// GetStorageAt mocks base method.
+func (m *MockClient) GetStorageAt(ctx context.Context, address eth.Address, slot eth.Data, numberOrTag eth.BlockNumberOrTag) (eth.Data, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetStorageAt", ctx, address, slot, numberOrTag)
+	ret0, _ := ret[0].(eth.Data)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetStorageAt indicates an expected call of GetStorageAt.
+func (mr *MockClientMockRecorder) GetStorageAt(ctx, address, slot, numberOrTag interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetStorageAt", reflect.TypeOf((*MockClient)(nil).GetStorageAt), ctx, address, slot, numberOrTag)
+}
+
+// GetTransactionByBlockHashAndIndex mocks base method.
+func (m *MockClient) GetTransactionByBlockHashAndIndex(ctx context.Context, hash string, index uint64) (*eth.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionByBlockHashAndIndex", ctx, hash, index)
+	ret0, _ := ret[0].(*eth.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionByBlockHashAndIndex indicates an expected call of GetTransactionByBlockHashAndIndex.
+func (mr *MockClientMockRecorder) GetTransactionByBlockHashAndIndex(ctx, hash, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionByBlockHashAndIndex", reflect.TypeOf((*MockClient)(nil).GetTransactionByBlockHashAndIndex), ctx, hash, index)
+}
+
+// GetTransactionByBlockNumberAndIndex mocks base method.
+func (m *MockClient) GetTransactionByBlockNumberAndIndex(ctx context.Context, numberOrTag eth.BlockNumberOrTag, index uint64) (*eth.Transaction, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetTransactionByBlockNumberAndIndex", ctx, numberOrTag, index)
+	ret0, _ := ret[0].(*eth.Transaction)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetTransactionByBlockNumberAndIndex indicates an expected call of GetTransactionByBlockNumberAndIndex.
+func (mr *MockClientMockRecorder) GetTransactionByBlockNumberAndIndex(ctx, numberOrTag, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionByBlockNumberAndIndex", reflect.TypeOf((*MockClient)(nil).GetTransactionByBlockNumberAndIndex), ctx, numberOrTag, index)
+}
+
 // GetTransactionCount mocks base method.
 func (m *MockClient) GetTransactionCount(ctx context.Context, address eth.Address, numberOrTag eth.BlockNumberOrTag) (uint64, error) {
 	m.ctrl.T.Helper()
@@ -247,6 +496,36 @@ func (mr *MockClientMockRecorder) GetTransactionCount(ctx, address, numberOrTag
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetTransactionCount", reflect.TypeOf((*MockClient)(nil).GetTransactionCount), ctx, address, numberOrTag)
 }
 
+// GetUncleByBlockHashAndIndex mocks base method.
+func (m *MockClient) GetUncleByBlockHashAndIndex(ctx context.Context, hash string, index uint64) (*eth.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUncleByBlockHashAndIndex", ctx, hash, index)
+	ret0, _ := ret[0].(*eth.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUncleByBlockHashAndIndex indicates an expected call of GetUncleByBlockHashAndIndex.
+func (mr *MockClientMockRecorder) GetUncleByBlockHashAndIndex(ctx, hash, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUncleByBlockHashAndIndex", reflect.TypeOf((*MockClient)(nil).GetUncleByBlockHashAndIndex), ctx, hash, index)
+}
+
+// GetUncleByBlockNumberAndIndex mocks base method.
+func (m *MockClient) GetUncleByBlockNumberAndIndex(ctx context.Context, numberOrTag eth.BlockNumberOrTag, index uint64) (*eth.Block, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetUncleByBlockNumberAndIndex", ctx, numberOrTag, index)
+	ret0, _ := ret[0].(*eth.Block)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetUncleByBlockNumberAndIndex indicates an expected call of GetUncleByBlockNumberAndIndex.
+func (mr *MockClientMockRecorder) GetUncleByBlockNumberAndIndex(ctx, numberOrTag, index interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetUncleByBlockNumberAndIndex", reflect.TypeOf((*MockClient)(nil).GetUncleByBlockNumberAndIndex), ctx, numberOrTag, index)
+}
+
 // IsBidirectional mocks base method.
 func (m *MockClient) IsBidirectional() bool {
 	m.ctrl.T.Helper()
@@ -291,6 +570,21 @@ func (mr *MockClientMockRecorder) MaxPriorityFeePerGas(ctx interface{}) *gomock.
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxPriorityFeePerGas", reflect.TypeOf((*MockClient)(nil).MaxPriorityFeePerGas), ctx)
 }
 
+// MaxPriorityFeePerGasBig mocks base method.
+func (m *MockClient) MaxPriorityFeePerGasBig(ctx context.Context) (*big.Int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MaxPriorityFeePerGasBig", ctx)
+	ret0, _ := ret[0].(*big.Int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MaxPriorityFeePerGasBig indicates an expected call of MaxPriorityFeePerGasBig.
+func (mr *MockClientMockRecorder) MaxPriorityFeePerGasBig(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MaxPriorityFeePerGasBig", reflect.TypeOf((*MockClient)(nil).MaxPriorityFeePerGasBig), ctx)
+}
+
 // NetVersion mocks base method.
 func (m *MockClient) NetVersion(ctx context.Context) (string, error) {
 	m.ctrl.T.Helper()
@@ -306,6 +600,51 @@ func (mr *MockClientMockRecorder) NetVersion(ctx interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NetVersion", reflect.TypeOf((*MockClient)(nil).NetVersion), ctx)
 }
 
+// NewBlockFilter mocks base method.
+func (m *MockClient) NewBlockFilter(ctx context.Context) (*Filter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewBlockFilter", ctx)
+	ret0, _ := ret[0].(*Filter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewBlockFilter indicates an expected call of NewBlockFilter.
+func (mr *MockClientMockRecorder) NewBlockFilter(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewBlockFilter", reflect.TypeOf((*MockClient)(nil).NewBlockFilter), ctx)
+}
+
+// NewFilter mocks base method.
+func (m *MockClient) NewFilter(ctx context.Context, filter eth.LogFilter) (*Filter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewFilter", ctx, filter)
+	ret0, _ := ret[0].(*Filter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewFilter indicates an expected call of NewFilter.
+func (mr *MockClientMockRecorder) NewFilter(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewFilter", reflect.TypeOf((*MockClient)(nil).NewFilter), ctx, filter)
+}
+
+// NewPendingTransactionFilter mocks base method.
+func (m *MockClient) NewPendingTransactionFilter(ctx context.Context) (*Filter, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NewPendingTransactionFilter", ctx)
+	ret0, _ := ret[0].(*Filter)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// NewPendingTransactionFilter indicates an expected call of NewPendingTransactionFilter.
+func (mr *MockClientMockRecorder) NewPendingTransactionFilter(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NewPendingTransactionFilter", reflect.TypeOf((*MockClient)(nil).NewPendingTransactionFilter), ctx)
+}
+
 // Request mocks base method.
 func (m *MockClient) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
 	m.ctrl.T.Helper()
@@ -321,6 +660,21 @@ func (mr *MockClientMockRecorder) Request(ctx, r interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Request", reflect.TypeOf((*MockClient)(nil).Request), ctx, r)
 }
 
+// RequestBatch mocks base method.
+func (m *MockClient) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequestBatch", ctx, batch)
+	ret0, _ := ret[0].(jsonrpc.BatchRawResponse)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequestBatch indicates an expected call of RequestBatch.
+func (mr *MockClientMockRecorder) RequestBatch(ctx, batch interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequestBatch", reflect.TypeOf((*MockClient)(nil).RequestBatch), ctx, batch)
+}
+
 // SendRawTransaction mocks base method.
 func (m *MockClient) SendRawTransaction(ctx context.Context, msg string) (string, error) {
 	m.ctrl.T.Helper()
@@ -336,6 +690,21 @@ func (mr *MockClientMockRecorder) SendRawTransaction(ctx, msg interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendRawTransaction", reflect.TypeOf((*MockClient)(nil).SendRawTransaction), ctx, msg)
 }
 
+// SendTransaction mocks base method.
+func (m *MockClient) SendTransaction(ctx context.Context, msg eth.Transaction) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendTransaction", ctx, msg)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SendTransaction indicates an expected call of SendTransaction.
+func (mr *MockClientMockRecorder) SendTransaction(ctx, msg interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendTransaction", reflect.TypeOf((*MockClient)(nil).SendTransaction), ctx, msg)
+}
+
 // Subscribe mocks base method.
 func (m *MockClient) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
 	m.ctrl.T.Helper()
@@ -351,6 +720,21 @@ func (mr *MockClientMockRecorder) Subscribe(ctx, r interface{}) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Subscribe", reflect.TypeOf((*MockClient)(nil).Subscribe), ctx, r)
 }
 
+// SubscribeLogs mocks base method.
+func (m *MockClient) SubscribeLogs(ctx context.Context, filter eth.LogFilter) (Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeLogs", ctx, filter)
+	ret0, _ := ret[0].(Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeLogs indicates an expected call of SubscribeLogs.
+func (mr *MockClientMockRecorder) SubscribeLogs(ctx, filter interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeLogs", reflect.TypeOf((*MockClient)(nil).SubscribeLogs), ctx, filter)
+}
+
 // SubscribeNewHeads mocks base method.
 func (m *MockClient) SubscribeNewHeads(ctx context.Context) (Subscription, error) {
 	m.ctrl.T.Helper()
@@ -366,6 +750,21 @@ func (mr *MockClientMockRecorder) SubscribeNewHeads(ctx interface{}) *gomock.Cal
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeNewHeads", reflect.TypeOf((*MockClient)(nil).SubscribeNewHeads), ctx)
 }
 
+// SubscribeNewPendingTransactionBodies mocks base method.
+func (m *MockClient) SubscribeNewPendingTransactionBodies(ctx context.Context) (Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeNewPendingTransactionBodies", ctx)
+	ret0, _ := ret[0].(Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeNewPendingTransactionBodies indicates an expected call of SubscribeNewPendingTransactionBodies.
+func (mr *MockClientMockRecorder) SubscribeNewPendingTransactionBodies(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeNewPendingTransactionBodies", reflect.TypeOf((*MockClient)(nil).SubscribeNewPendingTransactionBodies), ctx)
+}
+
 // SubscribeNewPendingTransactions mocks base method.
 func (m *MockClient) SubscribeNewPendingTransactions(ctx context.Context) (Subscription, error) {
 	m.ctrl.T.Helper()
@@ -381,6 +780,21 @@ func (mr *MockClientMockRecorder) SubscribeNewPendingTransactions(ctx interface{
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeNewPendingTransactions", reflect.TypeOf((*MockClient)(nil).SubscribeNewPendingTransactions), ctx)
 }
 
+// SubscribeSyncing mocks base method.
+func (m *MockClient) SubscribeSyncing(ctx context.Context) (Subscription, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SubscribeSyncing", ctx)
+	ret0, _ := ret[0].(Subscription)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SubscribeSyncing indicates an expected call of SubscribeSyncing.
+func (mr *MockClientMockRecorder) SubscribeSyncing(ctx interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SubscribeSyncing", reflect.TypeOf((*MockClient)(nil).SubscribeSyncing), ctx)
+}
+
 // TransactionByHash mocks base method.
 func (m *MockClient) TransactionByHash(ctx context.Context, hash string) (*eth.Transaction, error) {
 	m.ctrl.T.Helper()
@@ -425,6 +839,21 @@ func (mr *MockClientMockRecorder) URL() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "URL", reflect.TypeOf((*MockClient)(nil).URL))
 }
 
+// UninstallFilter mocks base method.
+func (m *MockClient) UninstallFilter(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UninstallFilter", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UninstallFilter indicates an expected call of UninstallFilter.
+func (mr *MockClientMockRecorder) UninstallFilter(ctx, id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UninstallFilter", reflect.TypeOf((*MockClient)(nil).UninstallFilter), ctx, id)
+}
+
 // MockSubscription is a mock of Subscription interface.
 type MockSubscription struct {
 	ctrl     *gomock.Controller
@@ -462,6 +891,34 @@ func (mr *MockSubscriptionMockRecorder) Ch() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Ch", reflect.TypeOf((*MockSubscription)(nil).Ch))
 }
 
+// Done mocks base method.
+func (m *MockSubscription) Done() <-chan struct{} {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Done")
+	ret0, _ := ret[0].(<-chan struct{})
+	return ret0
+}
+
+// Done indicates an expected call of Done.
+func (mr *MockSubscriptionMockRecorder) Done() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Done", reflect.TypeOf((*MockSubscription)(nil).Done))
+}
+
+// Err mocks base method.
+func (m *MockSubscription) Err() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Err")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Err indicates an expected call of Err.
+func (mr *MockSubscriptionMockRecorder) Err() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Err", reflect.TypeOf((*MockSubscription)(nil).Err))
+}
+
 // ID mocks base method.
 func (m *MockSubscription) ID() string {
 	m.ctrl.T.Helper()
@@ -0,0 +1,53 @@
+// Package logging holds the logging helpers shared by the node packages.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"net/url"
+	"strings"
+)
+
+// StdLogger writes to a standard library logger in the form "[WARN] msg key=value ...".
+type StdLogger struct {
+	l     *log.Logger
+	debug bool
+}
+
+// NewStdLogger returns a StdLogger writing to l, which only writes debug messages when debug is true.
+func NewStdLogger(l *log.Logger, debug bool) *StdLogger {
+	return &StdLogger{l: l, debug: debug}
+}
+
+func (s *StdLogger) Debug(msg string, args ...interface{}) {
+	if s.debug {
+		s.print("DEBUG", msg, args)
+	}
+}
+
+func (s *StdLogger) Info(msg string, args ...interface{})  { s.print("INFO", msg, args) }
+func (s *StdLogger) Warn(msg string, args ...interface{})  { s.print("WARN", msg, args) }
+func (s *StdLogger) Error(msg string, args ...interface{}) { s.print("ERROR", msg, args) }
+
+func (s *StdLogger) print(level, msg string, args []interface{}) {
+	b := strings.Builder{}
+	b.WriteString("[" + level + "] " + msg)
+	for i := 0; i < len(args); i += 2 {
+		if i+1 < len(args) {
+			fmt.Fprintf(&b, " %v=%v", args[i], args[i+1])
+		} else {
+			fmt.Fprintf(&b, " %v", args[i])
+		}
+	}
+	s.l.Print(b.String())
+}
+
+// RedactURL hides the password of rawURL, if it has one, so that it does not end up in logs
+func RedactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+
+	return u.Redacted()
+}
@@ -3,26 +3,35 @@ package node
 import (
 	"bufio"
 	"context"
+	"io"
 	"net"
 	"net/url"
 
 	"github.com/pkg/errors"
 )
 
-func newIPCTransport(ctx context.Context, parsedURL *url.URL) (*ipcTransport, error) {
+func newIPCTransport(ctx context.Context, parsedURL *url.URL, opts *clientOptions) (*ipcTransport, error) {
+	dialCtx, cancel := opts.dialContext(ctx)
+	defer cancel()
+
 	var d net.Dialer
-	conn, err := d.DialContext(ctx, "unix", parsedURL.String())
+	conn, err := d.DialContext(dialCtx, "unix", parsedURL.String())
 	if err != nil {
 		return nil, errors.Wrap(err, "could not connect over IPC")
 	}
 	scanner := bufio.NewScanner(conn)
 	readMessage := func() (payload []byte, err error) {
 		if !scanner.Scan() {
-			return nil, ctx.Err()
-		}
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
 
-		if err := scanner.Err(); err != nil {
-			return nil, err
+			// the scanner stops without an error when the backend closes the connection
+			return nil, io.EOF
 		}
 
 		payload = []byte(scanner.Text())
@@ -36,7 +45,7 @@ func newIPCTransport(ctx context.Context, parsedURL *url.URL) (*ipcTransport, er
 	}
 
 	t := ipcTransport{
-		loopingTransport: newLoopingTransport(ctx, conn, readMessage, writeMessage),
+		loopingTransport: newLoopingTransport(ctx, conn, readMessage, writeMessage, opts.observer(), opts.loggerFor(parsedURL.String())),
 	}
 
 	return &t, nil
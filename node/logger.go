@@ -0,0 +1,101 @@
+package node
+
+import (
+	"log"
+
+	"github.com/justinwongcn/go-ethlibs/node/internal/logging"
+)
+
+// Logger receives the messages a client logs when something unexpected happens, such as a notification that
+// could not be decoded or a connection that was lost.  Messages are followed by alternating keys and values,
+// such as "url", "request_id" and "subscription_id".  The method set matches *slog.Logger, so one can be
+// passed to WithLogger directly.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
+
+// NopLogger discards every message.
+type NopLogger struct{}
+
+func (NopLogger) Debug(msg string, args ...interface{}) {}
+func (NopLogger) Info(msg string, args ...interface{})  {}
+func (NopLogger) Warn(msg string, args ...interface{})  {}
+func (NopLogger) Error(msg string, args ...interface{}) {}
+
+// NewStdLogger returns a Logger which writes to l in the form "[WARN] msg key=value ...".  Debug messages
+// are only written when debug is true.  Clients log to NewStdLogger(log.Default(), false) unless
+// WithLogger is used.
+func NewStdLogger(l *log.Logger, debug bool) Logger {
+	return logging.NewStdLogger(l, debug)
+}
+
+// WithLogger sends the messages of the client and its transports to l instead of the standard library logger.
+// Use NopLogger to silence them.
+func WithLogger(l Logger) Option {
+	return func(o *clientOptions) {
+		o.logger = l
+	}
+}
+
+// loggerFor returns the configured Logger, with the backend at rawURL added to every message
+func (o *clientOptions) loggerFor(rawURL string) Logger {
+	l := o.logger
+	if l == nil {
+		l = NewStdLogger(log.Default(), false)
+	}
+
+	return withFields(l, "url", logging.RedactURL(rawURL))
+}
+
+// withFields returns a Logger that adds args in front of the arguments of every message
+func withFields(l Logger, args ...interface{}) Logger {
+	return &fieldLogger{l: l, fields: args}
+}
+
+type fieldLogger struct {
+	l      Logger
+	fields []interface{}
+}
+
+func (f *fieldLogger) with(args []interface{}) []interface{} {
+	return append(append(make([]interface{}, 0, len(f.fields)+len(args)), f.fields...), args...)
+}
+
+func (f *fieldLogger) Debug(msg string, args ...interface{}) { f.l.Debug(msg, f.with(args)...) }
+func (f *fieldLogger) Info(msg string, args ...interface{})  { f.l.Info(msg, f.with(args)...) }
+func (f *fieldLogger) Warn(msg string, args ...interface{})  { f.l.Warn(msg, f.with(args)...) }
+func (f *fieldLogger) Error(msg string, args ...interface{}) { f.l.Error(msg, f.with(args)...) }
+
+// logReconnects returns a copy of config which logs events to logger as well as reporting them to OnEvent
+func logReconnects(config *ReconnectConfig, logger Logger) *ReconnectConfig {
+	if config == nil {
+		return nil
+	}
+
+	logged := *config
+	logged.OnEvent = func(e ReconnectEvent) {
+		switch e.Type {
+		case ReconnectEventReconnected:
+			logger.Info("reconnected", "attempt", e.Attempt)
+		case ReconnectEventDialFailed:
+			logger.Warn("reconnect attempt failed", "attempt", e.Attempt, "error", e.Err)
+		case ReconnectEventResubscribeFailed:
+			logger.Warn("could not resubscribe", "subscription_id", e.SubscriptionID, "error", e.Err)
+		case ReconnectEventGaveUp:
+			logger.Error("gave up reconnecting", "attempt", e.Attempt, "error", e.Err)
+		default:
+			logger.Warn("connection "+e.Type.String(), "error", e.Err)
+		}
+
+		if config.OnEvent != nil {
+			config.OnEvent(e)
+		}
+	}
+
+	return &logged
+}
+
+var _ Logger = NopLogger{}
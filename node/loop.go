@@ -5,7 +5,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"math/rand"
 	"sync"
 	"sync/atomic"
@@ -17,7 +16,7 @@ import (
 	"github.com/justinwongcn/go-ethlibs/jsonrpc"
 )
 
-func newLoopingTransport(ctx context.Context, conn connCloser, readMessage readMessageFunc, writeMessage writeMessageFunc) *loopingTransport {
+func newLoopingTransport(ctx context.Context, conn connCloser, readMessage readMessageFunc, writeMessage writeMessageFunc, observer Observer, logger Logger) *loopingTransport {
 	t := loopingTransport{
 		conn:                   conn,
 		ctx:                    ctx,
@@ -30,6 +29,9 @@ func newLoopingTransport(ctx context.Context, conn connCloser, readMessage readM
 		subscriptions:          make(map[string]*subscription),
 		readMessage:            readMessage,
 		writeMessage:           writeMessage,
+		observer:               observer,
+		logger:                 logger,
+		done:                   make(chan struct{}),
 	}
 
 	go t.loop()
@@ -95,6 +97,16 @@ type loopingTransport struct {
 
 	writeMu      sync.Mutex
 	writeMessage writeMessageFunc
+
+	// observer, if set, is told about every notification, and backlog counts those not yet dispatched
+	observer Observer
+	backlog  int64
+
+	logger Logger
+
+	// done is closed once the loop has exited, at which point err holds the reason
+	done chan struct{}
+	err  error
 }
 
 func (t *loopingTransport) loop() {
@@ -194,7 +206,7 @@ func (t *loopingTransport) loop() {
 							return
 						case <-o.chAbandoned:
 							// request was abandoned (e.g. client disconnected)
-							log.Printf("[WARN] request abandoned %v %v", r.ID, o.request.ID)
+							t.logger.Warn("response to abandoned request", "request_id", o.request.ID, "proxied_id", r.ID)
 							return
 						case o.chResult <- &patchedResponse:
 							return
@@ -215,15 +227,46 @@ func (t *loopingTransport) loop() {
 				sp := SubscriptionParams{}
 				err := json.Unmarshal(msg.Params, &sp)
 				if err != nil {
-					log.Printf("[WARN] eth_subscription Notification not decoded: %v", err)
+					t.logger.Warn("eth_subscription notification not decoded", "error", err)
 					continue
 				}
 
+				if sp.Error != nil {
+					// the backend has ended this subscription, so it won't be sending any more notifications for it
+					go func(id string, cause error) {
+						t.subscriptionsMu.Lock()
+						defer t.subscriptionsMu.Unlock()
+						if sub, ok := t.subscriptions[id]; ok {
+							sub.terminate(ctx, TerminationBackend, cause)
+							delete(t.subscriptions, id)
+						}
+					}(sp.Subscription, errors.New(string(*sp.Error)))
+					continue
+				}
+
+				event := NotificationEvent{
+					SubscriptionID: sp.Subscription,
+					Backlog:        int(atomic.AddInt64(&t.backlog, 1)),
+				}
+				if t.observer != nil {
+					t.observer.NotificationReceived(event)
+				}
+
 				go func(n jsonrpc.Notification) {
+					defer atomic.AddInt64(&t.backlog, -1)
+
 					t.subscriptionsMu.RLock()
 					defer t.subscriptionsMu.RUnlock()
-					if subscription, ok := t.subscriptions[sp.Subscription]; ok {
-						subscription.dispatch(ctx, n)
+					subscription, ok := t.subscriptions[sp.Subscription]
+					if ok {
+						ok = subscription.dispatch(ctx, n)
+					}
+
+					if !ok {
+						t.logger.Debug("notification dropped", "subscription_id", event.SubscriptionID)
+						if t.observer != nil {
+							t.observer.NotificationDropped(event)
+						}
 					}
 				}(*msg)
 			}
@@ -246,10 +289,10 @@ func (t *loopingTransport) loop() {
 					// manually creates the eth_unsubscribe RPC versus calling subscription.Unsubscribe()
 					var id string
 					if r.Params.UnmarshalInto(&id) == nil {
-						log.Printf("[DEBUG] removing subscription id %s", id)
+						t.logger.Debug("removing subscription", "subscription_id", id)
 						t.subscriptionsMu.Lock()
 						if sub, ok := t.subscriptions[id]; ok {
-							sub.stop(ctx)
+							sub.terminate(ctx, TerminationUnsubscribed, nil)
 							delete(t.subscriptions, id)
 						}
 						t.subscriptionsMu.Unlock()
@@ -336,16 +379,40 @@ func (t *loopingTransport) loop() {
 		err = context.Canceled
 	}
 
+	reason := TerminationConnectionLost
+	if t.ctx.Err() != nil {
+		reason, err = TerminationClosed, t.ctx.Err()
+	}
+
 	// let's clean up all the remaining subscriptions
 	t.subscriptionsMu.Lock()
 	for id, sub := range t.subscriptions {
 		// don't pass in our ctx here, it's already been stopped
-		sub.stop(context.Background())
+		sub.terminate(context.Background(), reason, err)
 		delete(t.subscriptions, id)
 	}
 	t.subscriptionsMu.Unlock()
 
 	_ = t.conn.Close()
+
+	t.err = err
+	close(t.done)
+}
+
+// Done returns a channel that is closed once the transport's connection has shut down, either because
+// the transport context finished or because the connection to the backend failed.
+func (t *loopingTransport) Done() <-chan struct{} {
+	return t.done
+}
+
+// Err returns the reason the transport shut down, or nil if it is still running.
+func (t *loopingTransport) Err() error {
+	select {
+	case <-t.done:
+		return t.err
+	default:
+		return nil
+	}
 }
 
 func (t *loopingTransport) nextID(seed jsonrpc.ID) jsonrpc.ID {
@@ -366,6 +433,8 @@ func (t *loopingTransport) Request(ctx context.Context, r *jsonrpc.Request) (*js
 	select {
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(t.ctx.Err(), "transport context finished")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed")
 	default:
 		// transport context is still valid, we can process this request
 	}
@@ -390,6 +459,8 @@ func (t *loopingTransport) Request(ctx context.Context, r *jsonrpc.Request) (*js
 		// log.Printf("[SPAM] outbound request sent")
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(t.ctx.Err(), "transport context finished waiting for response")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed waiting for response")
 	case <-ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "context finished waiting for response")
 	}
@@ -401,11 +472,19 @@ func (t *loopingTransport) Request(ctx context.Context, r *jsonrpc.Request) (*js
 		return nil, err
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(t.ctx.Err(), "transport context finished waiting for response")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed waiting for response")
 	case <-ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "context finished waiting for response")
 	}
 }
 
+// RequestBatch sends each request in the batch through the looping transport concurrently, since the
+// transport already assigns every request a unique ID and matches responses back to it as they arrive.
+func (t *loopingTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	return requestBatchConcurrently(ctx, t, batch)
+}
+
 func copyRequest(request *jsonrpc.Request) (jsonrpc.Request, error) {
 	copied := jsonrpc.Request{}
 	buf := &bytes.Buffer{}
@@ -426,6 +505,8 @@ func (t *loopingTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (S
 	select {
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "transport context finished")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed")
 	default:
 		// transport context is still valid, we can process this request
 	}
@@ -449,6 +530,8 @@ func (t *loopingTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (S
 		// log.Printf("[SPAM] start request sent")
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "transport context finished waiting for subscription")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed waiting for subscription")
 	case <-ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "context finished waiting for subscription")
 	}
@@ -460,6 +543,8 @@ func (t *loopingTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (S
 		return nil, err
 	case <-t.ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "transport context finished waiting for subscription")
+	case <-t.done:
+		return nil, errors.Wrap(t.err, "transport connection closed waiting for subscription")
 	case <-ctx.Done():
 		return nil, errors.Wrap(ctx.Err(), "context finished waiting for subscription")
 	}
@@ -0,0 +1,678 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// ErrNoHealthyBackend is returned by a multi-endpoint client when none of its endpoints can serve a request.
+var ErrNoHealthyBackend = errors.New("no healthy backend")
+
+const (
+	defaultHealthCheckInterval = 15 * time.Second
+	defaultHealthCheckTimeout  = 5 * time.Second
+
+	// latencyWeight is how much the newest health check counts towards a backend's average latency
+	latencyWeight = 0.3
+)
+
+// BalancePolicy decides which of the healthy endpoints of a multi-endpoint client serves a request.
+type BalancePolicy int
+
+const (
+	// BalancePriority sends everything to the first healthy endpoint, in the order they were given
+	BalancePriority BalancePolicy = iota
+	// BalanceRoundRobin spreads requests evenly over the healthy endpoints
+	BalanceRoundRobin
+	// BalanceLowestLatency sends requests to the healthy endpoint that answered health checks the fastest
+	BalanceLowestLatency
+)
+
+func (p BalancePolicy) String() string {
+	switch p {
+	case BalancePriority:
+		return "priority"
+	case BalanceRoundRobin:
+		return "round robin"
+	case BalanceLowestLatency:
+		return "lowest latency"
+	default:
+		return "unknown"
+	}
+}
+
+// Endpoint is one backend of a multi-endpoint client.
+type Endpoint struct {
+	// URL is an http, websocket or IPC URL, as accepted by NewClient
+	URL string
+
+	// Options configure the transport for URL, as accepted by NewClientWithOptions
+	Options []Option
+}
+
+// MultiConfig controls how a multi-endpoint client routes requests and checks its endpoints.
+type MultiConfig struct {
+	// Policy picks the endpoint for each request, defaulting to BalancePriority
+	Policy BalancePolicy
+
+	// HealthCheckInterval is how often every endpoint is sent eth_blockNumber.  Defaults to 15s.
+	HealthCheckInterval time.Duration
+
+	// HealthCheckTimeout bounds each health check.  It is also the dial timeout of endpoints that don't set
+	// their own with WithDialTimeout.  Defaults to 5s.
+	HealthCheckTimeout time.Duration
+
+	// MaxBlockDrift takes endpoints more than this many blocks behind the highest one out of rotation,
+	// or 0 to only take out endpoints that fail their health check.
+	MaxBlockDrift uint64
+}
+
+// NewMultiClient returns a Client spreading requests over several endpoints, which may be any mix of
+// HTTP, websocket and IPC URLs.  Endpoints are health checked with eth_blockNumber in the background, and
+// an endpoint that fails or falls too far behind is skipped until it recovers.  Requests for idempotent
+// methods that fail to reach an endpoint are tried on the next one.
+//
+// Subscriptions are pinned to a single healthy endpoint that supports them, and when that endpoint fails they
+// are re-issued on another one while keeping their ID() and Ch().  Notifications may be missed or repeated
+// across such a move.
+//
+// Of the opts only the interceptors apply, and they see each request once regardless of how many endpoints it
+// is tried on.  Options for the endpoints themselves belong in their Endpoint.
+func NewMultiClient(ctx context.Context, endpoints []Endpoint, config MultiConfig, opts ...Option) (Client, error) {
+	if len(endpoints) == 0 {
+		return nil, errors.New("no endpoints")
+	}
+
+	if config.HealthCheckInterval <= 0 {
+		config.HealthCheckInterval = defaultHealthCheckInterval
+	}
+
+	if config.HealthCheckTimeout <= 0 {
+		config.HealthCheckTimeout = defaultHealthCheckTimeout
+	}
+
+	t := multiTransport{
+		ctx:           ctx,
+		config:        config,
+		subscriptions: make(map[string]*multiSubscription),
+	}
+
+	for _, endpoint := range endpoints {
+		o := newClientOptions(endpoint.Options...)
+		if o.dialTimeout <= 0 {
+			// an endpoint that never answers would otherwise hold up health checks and requests for as long as the OS lets it
+			o.dialTimeout = config.HealthCheckTimeout
+		}
+
+		t.backends = append(t.backends, &backend{
+			ctx:      ctx,
+			endpoint: endpoint,
+			opts:     o,
+			logger:   o.loggerFor(endpoint.URL),
+			dialCh:   make(chan struct{}, 1),
+		})
+	}
+
+	t.checkAll()
+	go t.monitor()
+
+	return &client{
+		transport: intercept(&t, newClientOptions(opts...)),
+		rawURL:    "",
+	}, nil
+}
+
+// backend is the state of a single endpoint of a multiTransport
+type backend struct {
+	ctx      context.Context
+	endpoint Endpoint
+	opts     *clientOptions
+	logger   Logger
+
+	// dialCh holds a token while a connection is being dialed, so that only one dial happens at a time
+	dialCh chan struct{}
+
+	mu        sync.RWMutex
+	transport transport
+	healthy   bool
+	head      uint64
+	latency   time.Duration
+	err       error
+}
+
+// connection returns the transport of the backend, dialing it first if necessary.  Connections live as long
+// as the client rather than the request that happened to dial them, but ctx bounds the wait for another dial.
+func (b *backend) connection(ctx context.Context) (transport, error) {
+	select {
+	case b.dialCh <- struct{}{}:
+		defer func() { <-b.dialCh }()
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	b.mu.RLock()
+	t := b.transport
+	b.mu.RUnlock()
+
+	if t != nil {
+		done, ok := t.(interface{ Done() <-chan struct{} })
+		if !ok {
+			return t, nil
+		}
+
+		select {
+		case <-done.Done():
+			// the connection is gone, so we have to dial a new one
+		default:
+			return t, nil
+		}
+	}
+
+	t, err := newTransport(b.ctx, b.endpoint.URL, b.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	b.transport = t
+	b.mu.Unlock()
+	return t, nil
+}
+
+// check sends eth_blockNumber to the backend and records how long it took
+func (b *backend) check(ctx context.Context) {
+	t, err := b.connection(ctx)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+
+	start := time.Now()
+	c := client{transport: t}
+	head, err := c.BlockNumber(ctx)
+	if err != nil {
+		b.fail(err)
+		return
+	}
+	elapsed := time.Since(start)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.head = head
+	b.err = nil
+	if b.latency == 0 {
+		b.latency = elapsed
+	} else {
+		b.latency = time.Duration(latencyWeight*float64(elapsed) + (1-latencyWeight)*float64(b.latency))
+	}
+}
+
+// fail takes the backend out of rotation until its next successful health check
+func (b *backend) fail(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.healthy {
+		b.logger.Warn("endpoint unhealthy", "error", err)
+	}
+
+	b.healthy = false
+	b.err = err
+}
+
+type multiTransport struct {
+	ctx      context.Context
+	config   MultiConfig
+	backends []*backend
+	next     uint64
+
+	subscriptions   map[string]*multiSubscription
+	subscriptionsMu sync.Mutex
+}
+
+func (t *multiTransport) monitor() {
+	ticker := time.NewTicker(t.config.HealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-t.ctx.Done():
+			return
+		case <-ticker.C:
+			t.checkAll()
+		}
+	}
+}
+
+// checkAll health checks every backend at once, then decides which of them are healthy
+func (t *multiTransport) checkAll() {
+	ctx, cancel := context.WithTimeout(t.ctx, t.config.HealthCheckTimeout)
+	defer cancel()
+
+	g := errgroup.Group{}
+	for _, b := range t.backends {
+		b := b
+		g.Go(func() error {
+			b.check(ctx)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	highest := uint64(0)
+	for _, b := range t.backends {
+		b.mu.RLock()
+		if b.err == nil && b.head > highest {
+			highest = b.head
+		}
+		b.mu.RUnlock()
+	}
+
+	for _, b := range t.backends {
+		b.mu.Lock()
+		b.healthy = b.err == nil
+		if b.healthy && t.config.MaxBlockDrift > 0 && highest-b.head > t.config.MaxBlockDrift {
+			b.healthy = false
+			b.err = errors.Errorf("%d blocks behind the highest endpoint", highest-b.head)
+		}
+		b.mu.Unlock()
+	}
+}
+
+// candidates returns the healthy backends in the order they should be tried according to the policy,
+// or every backend if none of them is healthy since a failed health check is better than giving up
+func (t *multiTransport) candidates() []*backend {
+	healthy := make([]*backend, 0, len(t.backends))
+	for _, b := range t.backends {
+		b.mu.RLock()
+		if b.healthy {
+			healthy = append(healthy, b)
+		}
+		b.mu.RUnlock()
+	}
+
+	if len(healthy) == 0 {
+		return append([]*backend(nil), t.backends...)
+	}
+
+	switch t.config.Policy {
+	case BalanceRoundRobin:
+		start := int(atomic.AddUint64(&t.next, 1)-1) % len(healthy)
+		rotated := make([]*backend, 0, len(healthy))
+		rotated = append(rotated, healthy[start:]...)
+		healthy = append(rotated, healthy[:start]...)
+	case BalanceLowestLatency:
+		sort.SliceStable(healthy, func(i, j int) bool {
+			healthy[i].mu.RLock()
+			defer healthy[i].mu.RUnlock()
+			healthy[j].mu.RLock()
+			defer healthy[j].mu.RUnlock()
+			return healthy[i].latency < healthy[j].latency
+		})
+	}
+
+	return healthy
+}
+
+// failover returns true if a request that failed with err should be sent to another backend
+func failover(ctx context.Context, method string, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if IsRateLimited(err) {
+		// the backend turned the request away without acting on it
+		return true
+	}
+
+	return IsIdempotent(method)
+}
+
+// unhealthy returns true if a request that failed with err says something about the backend rather than the caller,
+// so neither a cancelled context nor a request the backend rejected takes it out of rotation
+func unhealthy(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if e, ok := errors.Cause(err).(*HTTPError); ok && e.StatusCode >= 400 && e.StatusCode < 500 {
+		return false
+	}
+
+	return true
+}
+
+func (t *multiTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	if r.Method == "eth_unsubscribe" {
+		// callers only know the stable subscription IDs, so this has to be routed to the subscription itself
+		var id string
+		if r.Params.UnmarshalInto(&id) == nil {
+			t.subscriptionsMu.Lock()
+			sub, ok := t.subscriptions[id]
+			t.subscriptionsMu.Unlock()
+
+			if ok {
+				if err := sub.Unsubscribe(ctx); err != nil {
+					return nil, err
+				}
+
+				return &jsonrpc.RawResponse{
+					JSONRPC: "2.0",
+					ID:      r.ID,
+					Result:  json.RawMessage(`true`),
+				}, nil
+			}
+		}
+	}
+
+	err := ErrNoHealthyBackend
+	for _, b := range t.candidates() {
+		var conn transport
+		conn, err = b.connection(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			b.fail(err)
+			continue
+		}
+
+		var response *jsonrpc.RawResponse
+		response, err = conn.Request(ctx, r)
+		if err == nil {
+			return response, nil
+		}
+
+		retry := failover(ctx, r.Method, err)
+		if unhealthy(ctx, err) {
+			b.fail(err)
+		}
+		if !retry {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (t *multiTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	if len(batch) == 0 {
+		return nil, ErrEmptyBatch
+	}
+
+	err := ErrNoHealthyBackend
+	for _, b := range t.candidates() {
+		var conn transport
+		conn, err = b.connection(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			b.fail(err)
+			continue
+		}
+
+		var responses jsonrpc.BatchRawResponse
+		responses, err = conn.RequestBatch(ctx, batch)
+		if err == nil {
+			return responses, nil
+		}
+
+		retry := true
+		for _, r := range batch {
+			if r != nil && !failover(ctx, r.Method, err) {
+				retry = false
+				break
+			}
+		}
+		if unhealthy(ctx, err) {
+			b.fail(err)
+		}
+		if !retry {
+			return nil, err
+		}
+	}
+
+	return nil, err
+}
+
+func (t *multiTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
+	owned, err := copyRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := t.subscribe(ctx, &owned, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := multiSubscription{
+		transport:       t,
+		request:         &owned,
+		response:        inner.Response(),
+		id:              inner.ID(),
+		notificationsCh: make(chan *jsonrpc.Notification),
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+		inner:           inner,
+	}
+
+	t.subscriptionsMu.Lock()
+	t.subscriptions[sub.id] = &sub
+	t.subscriptionsMu.Unlock()
+
+	go sub.forward()
+	return &sub, nil
+}
+
+// subscribe issues r on the first healthy backend that supports subscriptions, other than exclude
+func (t *multiTransport) subscribe(ctx context.Context, r *jsonrpc.Request, exclude *backend) (*pinnedSubscription, error) {
+	err := ErrNoHealthyBackend
+	for _, b := range t.candidates() {
+		if b == exclude {
+			continue
+		}
+
+		var conn transport
+		conn, err = b.connection(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, err
+			}
+			b.fail(err)
+			continue
+		}
+
+		if !conn.IsBidirectional() {
+			err = errors.New("no endpoint supports subscriptions")
+			continue
+		}
+
+		var sub Subscription
+		sub, err = conn.Subscribe(ctx, r)
+		if err == nil {
+			return &pinnedSubscription{Subscription: sub, backend: b}, nil
+		}
+
+		if ctx.Err() != nil {
+			return nil, err
+		}
+	}
+
+	return nil, errors.Wrap(err, "could not subscribe on any endpoint")
+}
+
+func (t *multiTransport) remove(id string) {
+	t.subscriptionsMu.Lock()
+	delete(t.subscriptions, id)
+	t.subscriptionsMu.Unlock()
+}
+
+func (t *multiTransport) IsBidirectional() bool {
+	for _, b := range t.backends {
+		b.mu.RLock()
+		bidirectional := b.transport != nil && b.transport.IsBidirectional()
+		b.mu.RUnlock()
+
+		if bidirectional {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pinnedSubscription is a subscription on the backend it was made on
+type pinnedSubscription struct {
+	Subscription
+	backend *backend
+}
+
+// multiSubscription is the caller facing side of a subscription on a multiTransport, which moves
+// between backends when the one it is on fails while keeping the ID of the first one.
+type multiSubscription struct {
+	transport *multiTransport
+	request   *jsonrpc.Request
+	response  *jsonrpc.RawResponse
+	id        string
+
+	notificationsCh chan *jsonrpc.Notification
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	doneCh          chan struct{}
+	err             *SubscriptionError
+
+	inner *pinnedSubscription
+	mu    sync.Mutex
+}
+
+func (s *multiSubscription) Response() *jsonrpc.RawResponse {
+	return s.response
+}
+
+func (s *multiSubscription) ID() string {
+	return s.id
+}
+
+func (s *multiSubscription) Ch() <-chan *jsonrpc.Notification {
+	return s.notificationsCh
+}
+
+func (s *multiSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+func (s *multiSubscription) Err() error {
+	select {
+	case <-s.doneCh:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *multiSubscription) Unsubscribe(ctx context.Context) error {
+	s.transport.remove(s.id)
+
+	// terminating under the lock means forward either sees it before installing a new inner subscription, or
+	// has already installed the one we unsubscribe from here
+	s.mu.Lock()
+	s.terminate(TerminationUnsubscribed, nil)
+	inner := s.inner
+	s.mu.Unlock()
+
+	select {
+	case <-inner.Done():
+		// the backend already ended it
+		return nil
+	default:
+	}
+
+	return inner.Unsubscribe(ctx)
+}
+
+// terminate stops forwarding notifications, only the first reason is kept.
+func (s *multiSubscription) terminate(reason TerminationReason, cause error) {
+	s.stopOnce.Do(func() {
+		s.err = &SubscriptionError{Reason: reason, Err: cause}
+		close(s.stopCh)
+	})
+}
+
+// forward moves notifications from the backend the subscription is pinned to onto the caller facing channel,
+// and re-homes the subscription on another backend when that one fails.
+func (s *multiSubscription) forward() {
+	defer func() {
+		close(s.notificationsCh)
+		close(s.doneCh)
+	}()
+
+	s.mu.Lock()
+	inner := s.inner
+	s.mu.Unlock()
+
+	for {
+		select {
+		case n, ok := <-inner.Ch():
+			if !ok {
+				serr, _ := inner.Err().(*SubscriptionError)
+				if serr == nil || (serr.Reason != TerminationConnectionLost && serr.Reason != TerminationBackend) {
+					s.transport.remove(s.id)
+					if serr != nil {
+						s.terminate(serr.Reason, serr.Err)
+					} else {
+						s.terminate(TerminationClosed, nil)
+					}
+					return
+				}
+
+				inner.backend.fail(serr)
+				next, err := s.transport.subscribe(s.transport.ctx, s.request, inner.backend)
+				if err != nil {
+					s.transport.remove(s.id)
+					s.terminate(serr.Reason, errors.Wrap(err, serr.Error()))
+					return
+				}
+
+				s.mu.Lock()
+				select {
+				case <-s.stopCh:
+					// unsubscribed while we were moving, so nobody else will clean up the new subscription
+					s.mu.Unlock()
+					ctx, cancel := context.WithTimeout(context.Background(), s.transport.config.HealthCheckTimeout)
+					_ = next.Unsubscribe(ctx)
+					cancel()
+					return
+				default:
+				}
+				s.inner = next
+				s.mu.Unlock()
+				inner = next
+				continue
+			}
+
+			if inner.ID() != s.id {
+				n = rewriteSubscriptionID(n, s.id)
+			}
+
+			select {
+			case s.notificationsCh <- n:
+			case <-s.stopCh:
+				return
+			}
+		case <-s.stopCh:
+			return
+		}
+	}
+}
@@ -0,0 +1,371 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// testNode is a tiny JSON-RPC server over HTTP and websockets for exercising multi-endpoint clients
+type testNode struct {
+	t      *testing.T
+	name   string
+	server *httptest.Server
+
+	mu     sync.Mutex
+	head   uint64
+	down   bool
+	status int
+	calls  map[string]int
+	conns  []*websocket.Conn
+	subs   chan *websocket.Conn
+
+	// gate, if set, is received from when an eth_subscribe arrives and again before it is answered
+	gate chan struct{}
+}
+
+func newTestNode(t *testing.T, name string, head uint64) *testNode {
+	n := &testNode{
+		t:     t,
+		name:  name,
+		head:  head,
+		calls: make(map[string]int),
+		subs:  make(chan *websocket.Conn, 10),
+	}
+
+	upgrader := websocket.Upgrader{}
+	n.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n.mu.Lock()
+		down, status := n.down, n.status
+		n.mu.Unlock()
+
+		if down {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		if status != 0 && !websocket.IsWebSocketUpgrade(r) {
+			w.WriteHeader(status)
+			return
+		}
+
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+
+			n.mu.Lock()
+			n.conns = append(n.conns, conn)
+			n.mu.Unlock()
+
+			go func() {
+				for {
+					request := jsonrpc.Request{}
+					if err := conn.ReadJSON(&request); err != nil {
+						return
+					}
+
+					n.mu.Lock()
+					gate := n.gate
+					n.mu.Unlock()
+
+					if gate != nil && request.Method == "eth_subscribe" {
+						gate <- struct{}{}
+						<-gate
+					}
+
+					n.mu.Lock()
+					_ = conn.WriteJSON(n.respond(&request))
+					n.mu.Unlock()
+
+					if request.Method == "eth_subscribe" {
+						n.subs <- conn
+					}
+				}
+			}()
+			return
+		}
+
+		request := jsonrpc.Request{}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		require.NoError(t, json.NewEncoder(w).Encode(n.respond(&request)))
+	}))
+	t.Cleanup(n.server.Close)
+
+	return n
+}
+
+// respond answers a request, n.mu must be held
+func (n *testNode) respond(r *jsonrpc.Request) *jsonrpc.RawResponse {
+	n.calls[r.Method]++
+
+	var result interface{}
+	switch r.Method {
+	case "eth_blockNumber":
+		result = eth.QuantityFromUInt64(n.head)
+	case "eth_subscribe":
+		result = "0x" + n.name
+	default:
+		result = n.name
+	}
+
+	b, err := json.Marshal(result)
+	require.NoError(n.t, err)
+	return &jsonrpc.RawResponse{JSONRPC: "2.0", ID: r.ID, Result: b}
+}
+
+func (n *testNode) wsURL() string {
+	return "ws" + strings.TrimPrefix(n.server.URL, "http")
+}
+
+func (n *testNode) setDown(down bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.down = down
+	if down {
+		for _, conn := range n.conns {
+			_ = conn.Close()
+		}
+		n.conns = nil
+	}
+}
+
+// setStatus makes the node answer HTTP requests with status, or normally again for 0
+func (n *testNode) setStatus(status int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status = status
+}
+
+func (n *testNode) count(method string) int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.calls[method]
+}
+
+func TestMultiClient_Routing(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestNode(t, "a", 100)
+	b := newTestNode(t, "b", 100)
+	lagging := newTestNode(t, "c", 90)
+
+	endpoints := []Endpoint{{URL: a.server.URL}, {URL: b.server.URL}, {URL: lagging.server.URL}}
+	config := MultiConfig{HealthCheckInterval: time.Hour, MaxBlockDrift: 5}
+
+	c, err := NewMultiClient(ctx, endpoints, config)
+	require.NoError(t, err)
+
+	// priority sends everything to the first endpoint
+	for i := 0; i < 3; i++ {
+		version, err := c.NetVersion(ctx)
+		require.NoError(t, err)
+		require.Equal(t, "a", version)
+	}
+
+	// idempotent requests fail over, sends don't
+	a.setDown(true)
+	version, err := c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "b", version)
+
+	b.setDown(true)
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_sendRawTransaction", "0x00"))
+	require.True(t, IsUpstreamError(err))
+	require.Equal(t, 0, lagging.count("eth_sendRawTransaction"))
+
+	// round robin skips the lagging endpoint
+	a.setDown(false)
+	b.setDown(false)
+	c, err = NewMultiClient(ctx, endpoints, MultiConfig{Policy: BalanceRoundRobin, HealthCheckInterval: time.Hour, MaxBlockDrift: 5})
+	require.NoError(t, err)
+
+	seen := map[string]int{}
+	for i := 0; i < 4; i++ {
+		version, err := c.NetVersion(ctx)
+		require.NoError(t, err)
+		seen[version]++
+	}
+	require.Equal(t, map[string]int{"a": 2, "b": 2}, seen)
+
+	// with every endpoint down there is nothing left to try
+	a.setDown(true)
+	b.setDown(true)
+	lagging.setDown(true)
+	_, err = c.NetVersion(ctx)
+	require.Error(t, err)
+}
+
+func TestMultiClient_CallerErrors(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestNode(t, "a", 100)
+	b := newTestNode(t, "b", 100)
+
+	c, err := NewMultiClient(ctx, []Endpoint{{URL: a.server.URL}, {URL: b.server.URL}}, MultiConfig{HealthCheckInterval: time.Hour})
+	require.NoError(t, err)
+
+	// a rejected request is the caller's problem, so it is neither retried nor held against the endpoint
+	a.setStatus(http.StatusBadRequest)
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_sendRawTransaction", "0x00"))
+	require.Error(t, err)
+	require.Equal(t, 0, b.count("eth_sendRawTransaction"))
+
+	// a rate limited one goes elsewhere, but the endpoint stays in rotation
+	a.setStatus(http.StatusTooManyRequests)
+	version, err := c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "b", version)
+
+	a.setStatus(0)
+	version, err = c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", version)
+
+	// as does one whose caller gave up
+	cancelled, cancelNow := context.WithCancel(ctx)
+	cancelNow()
+	_, err = c.NetVersion(cancelled)
+	require.Error(t, err)
+
+	version, err = c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", version)
+}
+
+func TestMultiClient_Subscription(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestNode(t, "a", 100)
+	b := newTestNode(t, "b", 100)
+
+	c, err := NewMultiClient(ctx, []Endpoint{{URL: a.wsURL()}, {URL: b.wsURL()}}, MultiConfig{HealthCheckInterval: time.Hour})
+	require.NoError(t, err)
+	require.True(t, c.IsBidirectional())
+
+	sub, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0xa", sub.ID())
+
+	notify := func(conn *websocket.Conn, id string) {
+		require.NoError(t, conn.WriteJSON(&jsonrpc.Notification{
+			Method: "eth_subscription",
+			Params: json.RawMessage(`{"subscription":"` + id + `","result":{"number":"0x1"}}`),
+		}))
+	}
+
+	notify(<-a.subs, "0xa")
+	n := <-sub.Ch()
+	params := SubscriptionParams{}
+	require.NoError(t, json.Unmarshal(n.Params, &params))
+	require.Equal(t, "0xa", params.Subscription)
+
+	// the subscription moves to b when a goes away, keeping its ID
+	a.setDown(true)
+	select {
+	case conn := <-b.subs:
+		notify(conn, "0xb")
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not re-homed")
+	}
+
+	n = <-sub.Ch()
+	require.NoError(t, json.Unmarshal(n.Params, &params))
+	require.Equal(t, "0xa", params.Subscription)
+
+	require.NoError(t, sub.Unsubscribe(ctx))
+	<-sub.Done()
+	requireTerminated(t, sub, TerminationUnsubscribed)
+}
+
+func TestMultiClient_UnsubscribeWhileMoving(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newTestNode(t, "a", 100)
+	b := newTestNode(t, "b", 100)
+
+	c, err := NewMultiClient(ctx, []Endpoint{{URL: a.wsURL()}, {URL: b.wsURL()}}, MultiConfig{HealthCheckInterval: time.Hour})
+	require.NoError(t, err)
+
+	sub, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	<-a.subs
+
+	// hold the subscription on b until the caller has unsubscribed
+	b.mu.Lock()
+	b.gate = make(chan struct{})
+	b.mu.Unlock()
+
+	a.setDown(true)
+	select {
+	case <-b.gate:
+	case <-time.After(time.Second):
+		t.Fatal("subscription was not re-homed")
+	}
+
+	require.NoError(t, sub.Unsubscribe(ctx))
+	b.gate <- struct{}{}
+
+	// the subscription made on b is not left behind
+	deadline := time.Now().Add(time.Second)
+	for b.count("eth_unsubscribe") == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription on b was not unsubscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	<-sub.Done()
+	requireTerminated(t, sub, TerminationUnsubscribed)
+}
+
+func TestMultiClient_DialTimeout(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// accepts connections but never answers the websocket handshake
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			t.Cleanup(func() { _ = conn.Close() })
+		}
+	}()
+
+	a := newTestNode(t, "a", 100)
+	endpoints := []Endpoint{{URL: "ws://" + l.Addr().String()}, {URL: a.wsURL()}}
+
+	start := time.Now()
+	c, err := NewMultiClient(ctx, endpoints, MultiConfig{HealthCheckInterval: time.Hour, HealthCheckTimeout: 100 * time.Millisecond})
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < time.Second, "construction took %s", time.Since(start))
+
+	version, err := c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "a", version)
+}
@@ -0,0 +1,468 @@
+// Package nodetest provides an in-memory Ethereum node for testing code built on node.Client, as a stateful
+// alternative to the mocks in node/mocks.
+package nodetest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+	"github.com/justinwongcn/go-ethlibs/node"
+)
+
+const (
+	defaultChainID  = 1337
+	defaultGasPrice = 1000000000
+	defaultBaseFee  = 1000000000
+
+	blockGasLimit  = 30000000
+	transferGas    = 21000
+	genesisTime    = 1700000000
+	secondsPerSlot = 12
+
+	zeroAddress = "0x0000000000000000000000000000000000000000"
+	emptyRoot   = "0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421"
+	emptyUncles = "0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347"
+)
+
+// Config describes the chain a Backend simulates.
+type Config struct {
+	// ChainID is returned by eth_chainId and net_version.  Defaults to 1337.
+	ChainID uint64
+
+	// GasPrice is returned by eth_gasPrice and used for transactions mined without one.  Defaults to 1 gwei.
+	GasPrice uint64
+
+	// BaseFee is the base fee of every block.  Defaults to 1 gwei.
+	BaseFee uint64
+}
+
+// Tx is a transaction to mine, along with the logs it emits.  Fields the transaction is mined without, such as
+// its hash, sender and gas, are filled in with defaults, and the block related fields of it and its logs are set.
+type Tx struct {
+	Transaction eth.Transaction
+	Logs        []eth.Log
+
+	// Failed transactions are mined with a status of 0 and without their logs
+	Failed bool
+}
+
+// Backend is a node.Requester and node.Subscriber backed by an in-memory chain which the test builds up by
+// mining blocks.  It answers the eth_* methods used by node.Client, pushes newHeads, logs and newPendingTransactions
+// notifications as blocks are mined, and supports reorgs and injected errors.
+//
+// Account state set with SetBalance, SetCode and SetStorage is not historical, it is returned for every block.
+type Backend struct {
+	config Config
+
+	mu       sync.Mutex
+	chain    []*minedBlock
+	byHash   map[string]*minedBlock
+	txs      map[string]*minedTx
+	pending  []eth.Transaction
+	balances map[string]*big.Int
+	code     map[string]string
+	storage  map[string]map[string]*big.Int
+	failures map[string][]error
+	fork     int
+	txSeq    uint64
+	nextID   uint64
+
+	subscriptions map[string]*subscription
+	filters       map[string]*filter
+}
+
+type minedBlock struct {
+	block    eth.Block
+	receipts []eth.TransactionReceipt
+	logs     []eth.Log
+}
+
+// minedTx locates a transaction in the canonical chain
+type minedTx struct {
+	block *minedBlock
+	index int
+}
+
+// NewBackend returns a backend holding just a genesis block.
+func NewBackend(config Config) *Backend {
+	if config.ChainID == 0 {
+		config.ChainID = defaultChainID
+	}
+
+	if config.GasPrice == 0 {
+		config.GasPrice = defaultGasPrice
+	}
+
+	if config.BaseFee == 0 {
+		config.BaseFee = defaultBaseFee
+	}
+
+	b := Backend{
+		config:        config,
+		byHash:        make(map[string]*minedBlock),
+		txs:           make(map[string]*minedTx),
+		balances:      make(map[string]*big.Int),
+		code:          make(map[string]string),
+		storage:       make(map[string]map[string]*big.Int),
+		failures:      make(map[string][]error),
+		subscriptions: make(map[string]*subscription),
+		filters:       make(map[string]*filter),
+	}
+
+	b.mine(nil)
+	return &b
+}
+
+// Client returns a node.Client that talks to the backend.
+func (b *Backend) Client(opts ...node.Option) (node.Client, error) {
+	return node.NewCustomClient(b, b, opts...)
+}
+
+// Head returns the latest block in the canonical chain.
+func (b *Backend) Head() *eth.Block {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	block := b.head().block
+	return &block
+}
+
+// SetBalance sets the balance of address.
+func (b *Backend) SetBalance(address eth.Address, wei *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.balances[addressKey(address)] = new(big.Int).Set(wei)
+}
+
+// SetCode sets the code deployed at address.
+func (b *Backend) SetCode(address eth.Address, code string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.code[addressKey(address)] = code
+}
+
+// SetStorage sets the value of a storage slot of the account at address.
+func (b *Backend) SetStorage(address eth.Address, slot, value *big.Int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	key := addressKey(address)
+	if b.storage[key] == nil {
+		b.storage[key] = make(map[string]*big.Int)
+	}
+	b.storage[key][slot.String()] = new(big.Int).Set(value)
+}
+
+// FailNext makes the next request for method fail with err, use eth_subscribe for subscriptions.  A *jsonrpc.Error
+// is returned as an error response, anything else as a failure to send the request at all.  Calling it again
+// queues up more failures for the following requests.
+func (b *Backend) FailNext(method string, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures[method] = append(b.failures[method], err)
+}
+
+// DropSubscriptions ends every subscription as if the connection to the node was lost.
+func (b *Backend) DropSubscriptions() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for id, s := range b.subscriptions {
+		s.terminate(node.TerminationConnectionLost, errors.New("connection to backend lost"))
+		delete(b.subscriptions, id)
+	}
+}
+
+// Mine adds a block to the canonical chain holding the pending transactions followed by txs, and returns it.
+func (b *Backend) Mine(txs ...Tx) *eth.Block {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	all := make([]Tx, 0, len(b.pending)+len(txs))
+	for _, tx := range b.pending {
+		all = append(all, Tx{Transaction: tx})
+	}
+	b.pending = nil
+
+	block := b.mine(append(all, txs...)).block
+	return &block
+}
+
+// Reorg removes the newest depth blocks from the canonical chain, so the blocks mined next form a fork.  Logs
+// subscriptions and filters are sent the logs of the removed blocks again with removed set.  The transactions
+// in the removed blocks are dropped rather than returned to the pending pool, and the removed blocks can still
+// be fetched by hash.
+func (b *Backend) Reorg(depth int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if depth <= 0 || depth >= len(b.chain) {
+		return errors.Errorf("cannot reorg %d blocks of a chain with %d", depth, len(b.chain))
+	}
+
+	removed := b.chain[len(b.chain)-depth:]
+	b.chain = b.chain[:len(b.chain)-depth]
+	b.fork++
+
+	for i := len(removed) - 1; i >= 0; i-- {
+		mb := removed[i]
+		for _, tx := range mb.block.Transactions {
+			delete(b.txs, tx.Hash.String())
+		}
+
+		for j := len(mb.logs) - 1; j >= 0; j-- {
+			l := mb.logs[j]
+			l.Removed = true
+			b.notifyLog(l)
+		}
+	}
+
+	return nil
+}
+
+func (b *Backend) head() *minedBlock {
+	return b.chain[len(b.chain)-1]
+}
+
+// mine builds the next block from txs, b.mu must be held
+func (b *Backend) mine(txs []Tx) *minedBlock {
+	number := uint64(len(b.chain))
+	hash := eth.Hash(fmt.Sprintf("0xb1%054x%08x", number, b.fork))
+	n := eth.QuantityFromUInt64(number)
+
+	parent := eth.Hash(fmt.Sprintf("0x%064x", 0))
+	if number > 0 {
+		parent = *b.head().block.Hash
+	}
+
+	mb := minedBlock{}
+	bloom := eth.Bloom{}
+	gasUsed := uint64(0)
+	logIndex := uint64(0)
+	transactions := make([]eth.TxOrHash, 0, len(txs))
+
+	for i, tx := range txs {
+		t := b.prepare(tx.Transaction)
+		index := eth.QuantityFromUInt64(uint64(i))
+		t.BlockHash = &hash
+		t.BlockNumber = &n
+		t.Index = &index
+
+		gasUsed += t.Gas.UInt64()
+		cumulative := eth.QuantityFromUInt64(gasUsed)
+		price := b.effectiveGasPrice(&t)
+		status := eth.QuantityFromUInt64(1)
+		if tx.Failed {
+			status = eth.QuantityFromUInt64(0)
+		}
+
+		receiptBloom := eth.Bloom{}
+		logs := make([]eth.Log, 0, len(tx.Logs))
+		for _, l := range tx.Logs {
+			if tx.Failed {
+				break
+			}
+
+			txHash, blockNumber := t.Hash, n
+			li, ti := eth.QuantityFromUInt64(logIndex), index
+			l.TxHash = &txHash
+			l.BlockHash = &hash
+			l.BlockNumber = &blockNumber
+			l.LogIndex = &li
+			l.TxIndex = &ti
+			l.Address = *eth.MustAddress(string(l.Address))
+			if l.Data == "" {
+				l.Data = "0x"
+			}
+
+			logIndex++
+			bloom.AddLog(l)
+			receiptBloom.AddLog(l)
+			logs = append(logs, l)
+		}
+
+		mb.receipts = append(mb.receipts, eth.TransactionReceipt{
+			Type:              t.Type,
+			TransactionHash:   t.Hash,
+			TransactionIndex:  index,
+			BlockHash:         hash,
+			BlockNumber:       n,
+			From:              t.From,
+			To:                t.To,
+			CumulativeGasUsed: cumulative,
+			GasUsed:           t.Gas,
+			Logs:              logs,
+			LogsBloom:         receiptBloom.Value(),
+			Status:            &status,
+			EffectiveGasPrice: &price,
+		})
+		mb.logs = append(mb.logs, logs...)
+		transactions = append(transactions, eth.TxOrHash{Transaction: t, Populated: true})
+	}
+
+	baseFee := eth.QuantityFromUInt64(b.config.BaseFee)
+	mb.block = eth.Block{
+		Number:           &n,
+		Hash:             &hash,
+		ParentHash:       parent,
+		SHA3Uncles:       emptyUncles,
+		LogsBloom:        bloom.Value(),
+		TransactionsRoot: emptyRoot,
+		StateRoot:        emptyRoot,
+		ReceiptsRoot:     emptyRoot,
+		Miner:            zeroAddress,
+		Difficulty:       eth.QuantityFromUInt64(0),
+		TotalDifficulty:  eth.QuantityFromUInt64(0),
+		ExtraData:        "0x",
+		Size:             eth.QuantityFromUInt64(0x200),
+		GasLimit:         eth.QuantityFromUInt64(blockGasLimit),
+		GasUsed:          eth.QuantityFromUInt64(gasUsed),
+		Timestamp:        eth.QuantityFromUInt64(genesisTime + number*secondsPerSlot),
+		Transactions:     transactions,
+		Uncles:           []eth.Hash{},
+		BaseFeePerGas:    &baseFee,
+		Nonce:            eth.MustData8("0x0000000000000000"),
+		MixHash:          eth.MustData("0x" + strings.Repeat("00", 32)),
+	}
+
+	b.chain = append(b.chain, &mb)
+	b.byHash[hash.String()] = &mb
+	for i, tx := range transactions {
+		b.txs[tx.Hash.String()] = &minedTx{block: &mb, index: i}
+	}
+
+	if number > 0 {
+		b.notifyBlock(&mb)
+	}
+
+	return &mb
+}
+
+// prepare fills in the fields a transaction needs but the test didn't set, b.mu must be held
+func (b *Backend) prepare(t eth.Transaction) eth.Transaction {
+	if t.Hash == "" {
+		b.txSeq++
+		t.Hash = eth.Hash(fmt.Sprintf("0x7e%062x", b.txSeq))
+	}
+
+	if t.From == "" {
+		t.From = zeroAddress
+	}
+
+	if t.Input == "" {
+		t.Input = "0x"
+	}
+
+	if t.Gas.UInt64() == 0 {
+		t.Gas = eth.QuantityFromUInt64(transferGas)
+	}
+
+	if t.GasPrice == nil && t.MaxFeePerGas == nil {
+		price := eth.QuantityFromUInt64(b.config.GasPrice)
+		t.GasPrice = &price
+	}
+
+	return t
+}
+
+func (b *Backend) effectiveGasPrice(t *eth.Transaction) eth.Quantity {
+	if t.MaxFeePerGas == nil {
+		return *t.GasPrice
+	}
+
+	price := b.config.BaseFee
+	if t.MaxPriorityFeePerGas != nil {
+		price += t.MaxPriorityFeePerGas.UInt64()
+	}
+
+	if max := t.MaxFeePerGas.UInt64(); price > max {
+		price = max
+	}
+
+	return eth.QuantityFromUInt64(price)
+}
+
+// addPending adds a transaction to the pool mined by the next call to Mine, b.mu must be held
+func (b *Backend) addPending(t eth.Transaction) eth.Hash {
+	t = b.prepare(t)
+	t.BlockHash, t.BlockNumber, t.Index = nil, nil, nil
+	b.pending = append(b.pending, t)
+	b.notifyPending(t.Hash)
+	return t.Hash
+}
+
+func (b *Backend) nextIdentifier() string {
+	b.nextID++
+	return eth.QuantityFromUInt64(b.nextID).String()
+}
+
+func (b *Backend) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.failure(r.Method); err != nil {
+		e, ok := err.(*jsonrpc.Error)
+		if !ok {
+			return nil, err
+		}
+
+		return errorResponse(r, e), nil
+	}
+
+	result, e := b.handle(r)
+	if e != nil {
+		return errorResponse(r, e), nil
+	}
+
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode result")
+	}
+
+	return &jsonrpc.RawResponse{
+		JSONRPC: "2.0",
+		ID:      r.ID,
+		Result:  raw,
+	}, nil
+}
+
+// failure returns the next injected failure for method, if there is one, b.mu must be held
+func (b *Backend) failure(method string) error {
+	queued := b.failures[method]
+	if len(queued) == 0 {
+		return nil
+	}
+
+	b.failures[method] = queued[1:]
+	return queued[0]
+}
+
+func errorResponse(r *jsonrpc.Request, e *jsonrpc.Error) *jsonrpc.RawResponse {
+	raw, _ := json.Marshal(e)
+	msg := json.RawMessage(raw)
+	return &jsonrpc.RawResponse{
+		JSONRPC: "2.0",
+		ID:      r.ID,
+		Error:   &msg,
+	}
+}
+
+func addressKey(address eth.Address) string {
+	return strings.ToLower(string(address))
+}
+
+var (
+	_ node.Requester  = (*Backend)(nil)
+	_ node.Subscriber = (*Backend)(nil)
+)
@@ -0,0 +1,230 @@
+package nodetest
+
+import (
+	"context"
+	"math/big"
+	"testing"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+	"github.com/justinwongcn/go-ethlibs/node"
+)
+
+const (
+	testToken    = "0x6b175474e89094c44da98b954eedeac495271d0f"
+	testTransfer = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+	testSender   = "0x00000000000000000000000000000000000000aa"
+)
+
+func transfer() Tx {
+	return Tx{
+		Transaction: eth.Transaction{From: eth.Address(testSender), To: eth.MustAddress(testToken)},
+		Logs:        []eth.Log{{Address: eth.Address(testToken), Topics: []eth.Topic{eth.Topic(testTransfer)}}},
+	}
+}
+
+func TestBackend_Chain(t *testing.T) {
+	ctx := context.Background()
+	b := NewBackend(Config{ChainID: 5})
+	c, err := b.Client()
+	require.NoError(t, err)
+
+	chainID, err := c.ChainId(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x5", chainID)
+
+	version, err := c.NetVersion(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "5", version)
+
+	b.Mine()
+	mined := b.Mine(transfer(), Tx{Transaction: eth.Transaction{From: eth.Address(testSender)}, Failed: true})
+
+	n, err := c.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), n)
+
+	block, err := c.BlockByNumber(ctx, *eth.MustBlockNumberOrTag("0x2"), true)
+	require.NoError(t, err)
+	require.Equal(t, *mined.Hash, *block.Hash)
+	require.Len(t, block.Transactions, 2)
+	require.True(t, block.Transactions[0].Populated)
+
+	parent, err := c.BlockByHash(ctx, block.ParentHash.String(), false)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), parent.Number.UInt64())
+
+	hash := block.Transactions[0].Hash.String()
+	tx, err := c.TransactionByHash(ctx, hash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), tx.BlockNumber.UInt64())
+
+	receipt, err := c.TransactionReceipt(ctx, hash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), receipt.Status.UInt64())
+	require.Len(t, receipt.Logs, 1)
+
+	failed, err := c.TransactionReceipt(ctx, block.Transactions[1].Hash.String())
+	require.NoError(t, err)
+	require.Equal(t, uint64(0), failed.Status.UInt64())
+	require.Empty(t, failed.Logs)
+
+	count, err := c.GetTransactionCount(ctx, eth.Address(testSender), *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(2), count)
+
+	b.SetStorage(eth.Address(testToken), big.NewInt(1), big.NewInt(42))
+	value, err := c.GetStorageAt(ctx, eth.Address(testToken), "0x1", *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, eth.Data("0x000000000000000000000000000000000000000000000000000000000000002a"), value)
+
+	value, err = c.GetStorageAt(ctx, eth.Address(testToken), "0x0000000000000000000000000000000000000000000000000000000000000002", *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, eth.Data("0x0000000000000000000000000000000000000000000000000000000000000000"), value)
+
+	b.SetBalance(eth.Address(testSender), big.NewInt(100))
+	balance, err := c.GetBalance(ctx, *eth.MustAddress(testSender), *eth.MustBlockNumberOrTag("latest"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(100), balance)
+
+	logs, err := c.Logs(ctx, eth.LogFilter{
+		FromBlock: eth.MustBlockNumberOrTag("earliest"),
+		Address:   []eth.Address{*eth.MustAddress(testToken)},
+		Topics:    [][]eth.Topic{{eth.Topic(testTransfer)}},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	require.Equal(t, hash, logs[0].TxHash.String())
+
+	logs, err = c.Logs(ctx, eth.LogFilter{FromBlock: eth.MustBlockNumberOrTag("0x1"), ToBlock: eth.MustBlockNumberOrTag("0x1")})
+	require.NoError(t, err)
+	require.Empty(t, logs)
+
+	history, err := c.FeeHistory(ctx, 2, *eth.MustBlockNumberOrTag("latest"), []float64{50})
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), history.OldestBlock.UInt64())
+	require.Len(t, history.BaseFeePerGas, 3)
+	require.Zero(t, history.GasUsedRatio[0])
+	require.Len(t, history.Reward, 2)
+
+	s, err := node.NewFeeOracle(c, node.FeeOracleConfig{}).Suggest(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1000000000), s.BaseFee.Uint64())
+}
+
+func TestBackend_PendingTransactions(t *testing.T) {
+	ctx := context.Background()
+	b := NewBackend(Config{})
+	c, err := b.Client()
+	require.NoError(t, err)
+
+	f, err := c.NewPendingTransactionFilter(ctx)
+	require.NoError(t, err)
+
+	hash, err := c.SendTransaction(ctx, eth.Transaction{
+		From:  *eth.MustAddress(testSender),
+		To:    eth.MustAddress(testToken),
+		Input: "0x",
+	})
+	require.NoError(t, err)
+
+	count, err := c.GetTransactionCount(ctx, eth.Address(testSender), *eth.MustBlockNumberOrTag("pending"))
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), count)
+
+	changes, err := f.Changes(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []eth.Hash{eth.Hash(hash)}, changes.Hashes)
+
+	_, err = c.TransactionReceipt(ctx, hash)
+	require.Error(t, err)
+
+	b.Mine()
+	receipt, err := c.TransactionReceipt(ctx, hash)
+	require.NoError(t, err)
+	require.Equal(t, uint64(1), receipt.BlockNumber.UInt64())
+}
+
+func TestBackend_Subscriptions(t *testing.T) {
+	ctx := context.Background()
+	b := NewBackend(Config{})
+	c, err := b.Client()
+	require.NoError(t, err)
+
+	heads, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+
+	logs, err := c.SubscribeLogs(ctx, eth.LogFilter{Address: []eth.Address{*eth.MustAddress(testToken)}})
+	require.NoError(t, err)
+
+	blocks, err := c.NewBlockFilter(ctx)
+	require.NoError(t, err)
+
+	b.Mine()
+	mined := b.Mine(transfer())
+
+	for _, expected := range []uint64{1, 2} {
+		n := <-heads.Ch()
+		params := eth.NewHeadsNotificationParams{}
+		require.NoError(t, n.UnmarshalParamsInto(&params))
+		require.Equal(t, expected, params.Result.Number.UInt64())
+	}
+
+	n := <-logs.Ch()
+	added := eth.LogNotificationParams{}
+	require.NoError(t, n.UnmarshalParamsInto(&added))
+	require.False(t, added.Result.Removed)
+	require.Equal(t, *mined.Hash, *added.Result.BlockHash)
+
+	// the reorged block's logs come back as removed, and the fork gets new hashes
+	require.NoError(t, b.Reorg(1))
+	forked := b.Mine()
+	require.NotEqual(t, *mined.Hash, *forked.Hash)
+	require.Equal(t, mined.ParentHash, forked.ParentHash)
+
+	n = <-logs.Ch()
+	removed := eth.LogNotificationParams{}
+	require.NoError(t, n.UnmarshalParamsInto(&removed))
+	require.True(t, removed.Result.Removed)
+
+	changes, err := blocks.Changes(ctx)
+	require.NoError(t, err)
+	require.Len(t, changes.Hashes, 3)
+
+	require.NoError(t, heads.Unsubscribe(ctx))
+	<-heads.Done()
+	require.Equal(t, node.TerminationUnsubscribed, heads.Err().(*node.SubscriptionError).Reason)
+
+	b.DropSubscriptions()
+	<-logs.Done()
+	require.Equal(t, node.TerminationConnectionLost, logs.Err().(*node.SubscriptionError).Reason)
+
+	require.Error(t, b.Reorg(10))
+}
+
+func TestBackend_FailNext(t *testing.T) {
+	ctx := context.Background()
+	b := NewBackend(Config{})
+	c, err := b.Client()
+	require.NoError(t, err)
+
+	refused := errors.New("connection refused")
+	b.FailNext("eth_blockNumber", refused)
+	b.FailNext("eth_blockNumber", jsonrpc.ResourceUnavailable("try again"))
+	b.FailNext("eth_subscribe", refused)
+
+	_, err = c.BlockNumber(ctx)
+	require.Equal(t, refused, errors.Cause(err))
+
+	_, err = c.BlockNumber(ctx)
+	require.Contains(t, err.Error(), "try again")
+
+	_, err = c.BlockNumber(ctx)
+	require.NoError(t, err)
+
+	_, err = c.SubscribeNewHeads(ctx)
+	require.Equal(t, refused, errors.Cause(err))
+}
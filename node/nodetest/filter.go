@@ -0,0 +1,80 @@
+package nodetest
+
+import (
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+	"github.com/justinwongcn/go-ethlibs/node"
+)
+
+// filter collects changes between calls to eth_getFilterChanges
+type filter struct {
+	typ      node.FilterType
+	criteria eth.LogFilter
+	logs     []eth.Log
+	hashes   []eth.Hash
+}
+
+// handleFilter answers the eth_*Filter* methods, b.mu must be held
+func (b *Backend) handleFilter(r *jsonrpc.Request) (interface{}, *jsonrpc.Error) {
+	switch r.Method {
+	case "eth_newFilter":
+		criteria := eth.LogFilter{}
+		if err := r.Params.UnmarshalSingleParam(0, &criteria); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		return b.installFilter(&filter{typ: node.FilterTypeLogs, criteria: criteria}), nil
+
+	case "eth_newBlockFilter":
+		return b.installFilter(&filter{typ: node.FilterTypeBlocks}), nil
+
+	case "eth_newPendingTransactionFilter":
+		return b.installFilter(&filter{typ: node.FilterTypePendingTransactions}), nil
+	}
+
+	id := ""
+	if err := r.Params.UnmarshalSingleParam(0, &id); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+
+	f, ok := b.filters[id]
+
+	switch r.Method {
+	case "eth_uninstallFilter":
+		delete(b.filters, id)
+		return ok, nil
+
+	case "eth_getFilterLogs":
+		if !ok || f.typ != node.FilterTypeLogs {
+			return nil, jsonrpc.InvalidInput("filter not found")
+		}
+
+		return b.logs(f.criteria), nil
+	}
+
+	if !ok {
+		return nil, jsonrpc.InvalidInput("filter not found")
+	}
+
+	if f.typ == node.FilterTypeLogs {
+		logs := f.logs
+		f.logs = nil
+		if logs == nil {
+			logs = make([]eth.Log, 0)
+		}
+		return logs, nil
+	}
+
+	hashes := f.hashes
+	f.hashes = nil
+	if hashes == nil {
+		hashes = make([]eth.Hash, 0)
+	}
+	return hashes, nil
+}
+
+func (b *Backend) installFilter(f *filter) string {
+	id := b.nextIdentifier()
+	b.filters[id] = f
+	return id
+}
@@ -0,0 +1,450 @@
+package nodetest
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// handle answers a request from the chain, b.mu must be held
+func (b *Backend) handle(r *jsonrpc.Request) (interface{}, *jsonrpc.Error) {
+	switch r.Method {
+	case "eth_chainId":
+		return eth.QuantityFromUInt64(b.config.ChainID), nil
+
+	case "net_version":
+		return strconv.FormatUint(b.config.ChainID, 10), nil
+
+	case "eth_blockNumber":
+		return b.head().block.Number, nil
+
+	case "eth_gasPrice":
+		return eth.QuantityFromUInt64(b.config.GasPrice), nil
+
+	case "eth_maxPriorityFeePerGas":
+		if b.config.GasPrice < b.config.BaseFee {
+			return eth.QuantityFromUInt64(0), nil
+		}
+
+		return eth.QuantityFromUInt64(b.config.GasPrice - b.config.BaseFee), nil
+
+	case "eth_feeHistory":
+		return b.feeHistory(r)
+
+	case "eth_estimateGas":
+		return eth.QuantityFromUInt64(transferGas), nil
+
+	case "eth_call":
+		return "0x", nil
+
+	case "eth_getBalance":
+		address := eth.Address("")
+		if err := r.Params.UnmarshalSingleParam(0, &address); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		balance, ok := b.balances[addressKey(address)]
+		if !ok {
+			balance = new(big.Int)
+		}
+
+		return eth.QuantityFromBigInt(balance), nil
+
+	case "eth_getCode":
+		address := eth.Address("")
+		if err := r.Params.UnmarshalSingleParam(0, &address); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		code, ok := b.code[addressKey(address)]
+		if !ok {
+			code = "0x"
+		}
+
+		return code, nil
+
+	case "eth_getStorageAt":
+		address := eth.Address("")
+		slot := eth.Data("")
+		if err := r.Params.UnmarshalSingleParam(0, &address); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+		if err := r.Params.UnmarshalSingleParam(1, &slot); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		// slots may be given as quantities or as 32 byte words
+		index, ok := new(big.Int).SetString(slot.String()[2:], 16)
+		if !ok {
+			index = new(big.Int)
+		}
+
+		value, ok := b.storage[addressKey(address)][index.String()]
+		if !ok {
+			value = new(big.Int)
+		}
+
+		return fmt.Sprintf("0x%064x", value), nil
+
+	case "eth_getTransactionCount":
+		return b.transactionCount(r)
+
+	case "eth_sendRawTransaction":
+		raw := ""
+		if err := r.Params.UnmarshalSingleParam(0, &raw); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		t := eth.Transaction{}
+		if err := t.FromRaw(raw); err != nil {
+			return nil, jsonrpc.InvalidInput(err.Error())
+		}
+
+		return b.addPending(t), nil
+
+	case "eth_sendTransaction":
+		fields := make(map[string]json.RawMessage)
+		if err := r.Params.UnmarshalSingleParam(0, &fields); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		// node.Client sends every field of an eth.Transaction, including empty ones that won't decode, and
+		// the hash is ours to assign
+		for name, value := range fields {
+			if name == "hash" || string(value) == `""` || string(value) == "null" {
+				delete(fields, name)
+			}
+		}
+
+		cleaned, _ := json.Marshal(fields)
+		t := eth.Transaction{}
+		if err := json.Unmarshal(cleaned, &t); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		return b.addPending(t), nil
+
+	case "eth_getBlockByNumber":
+		mb, full, e := b.blockByNumberParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		return blockResult(mb, full), nil
+
+	case "eth_getBlockByHash":
+		mb, e := b.blockByHashParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		full := false
+		if len(r.Params) > 1 {
+			if err := r.Params.UnmarshalSingleParam(1, &full); err != nil {
+				return nil, jsonrpc.InvalidParams(err.Error())
+			}
+		}
+
+		return blockResult(mb, full), nil
+
+	case "eth_getBlockTransactionCountByNumber":
+		mb, _, e := b.blockByNumberParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		return eth.QuantityFromUInt64(uint64(len(mb.block.Transactions))), nil
+
+	case "eth_getBlockTransactionCountByHash":
+		mb, e := b.blockByHashParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		return eth.QuantityFromUInt64(uint64(len(mb.block.Transactions))), nil
+
+	case "eth_getTransactionByBlockNumberAndIndex":
+		mb, _, e := b.blockByNumberParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		return transactionAt(mb, r)
+
+	case "eth_getTransactionByBlockHashAndIndex":
+		mb, e := b.blockByHashParam(r)
+		if e != nil || mb == nil {
+			return nil, e
+		}
+
+		return transactionAt(mb, r)
+
+	case "eth_getUncleByBlockNumberAndIndex", "eth_getUncleByBlockHashAndIndex":
+		// we never mine uncles
+		return nil, nil
+
+	case "eth_getTransactionByHash":
+		hash := eth.Hash("")
+		if err := r.Params.UnmarshalSingleParam(0, &hash); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		if tx, ok := b.txs[hash.String()]; ok {
+			return tx.block.block.Transactions[tx.index].Transaction, nil
+		}
+
+		for _, t := range b.pending {
+			if t.Hash == hash {
+				return t, nil
+			}
+		}
+
+		return nil, nil
+
+	case "eth_getTransactionReceipt":
+		hash := eth.Hash("")
+		if err := r.Params.UnmarshalSingleParam(0, &hash); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		if tx, ok := b.txs[hash.String()]; ok {
+			return tx.block.receipts[tx.index], nil
+		}
+
+		return nil, nil
+
+	case "eth_getLogs":
+		criteria := eth.LogFilter{}
+		if err := r.Params.UnmarshalSingleParam(0, &criteria); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		return b.logs(criteria), nil
+
+	case "eth_newFilter", "eth_newBlockFilter", "eth_newPendingTransactionFilter",
+		"eth_getFilterChanges", "eth_getFilterLogs", "eth_uninstallFilter":
+		return b.handleFilter(r)
+
+	case "eth_unsubscribe":
+		id := ""
+		if err := r.Params.UnmarshalSingleParam(0, &id); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+
+		return b.unsubscribe(id), nil
+	}
+
+	return nil, jsonrpc.MethodNotFound(r)
+}
+
+func (b *Backend) transactionCount(r *jsonrpc.Request) (interface{}, *jsonrpc.Error) {
+	address := eth.Address("")
+	if err := r.Params.UnmarshalSingleParam(0, &address); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+
+	key := addressKey(address)
+	count := uint64(0)
+	for _, mb := range b.chain {
+		for _, tx := range mb.block.Transactions {
+			if addressKey(tx.From) == key {
+				count++
+			}
+		}
+	}
+
+	numberOrTag := eth.BlockNumberOrTag{}
+	if len(r.Params) > 1 && r.Params.UnmarshalSingleParam(1, &numberOrTag) == nil {
+		if tag, ok := numberOrTag.Tag(); ok && tag == eth.TagPending {
+			for _, t := range b.pending {
+				if addressKey(t.From) == key {
+					count++
+				}
+			}
+		}
+	}
+
+	return eth.QuantityFromUInt64(count), nil
+}
+
+// feeHistory answers eth_feeHistory, with rewards weighted by the gas used of each transaction as geth does
+func (b *Backend) feeHistory(r *jsonrpc.Request) (interface{}, *jsonrpc.Error) {
+	count := eth.Quantity{}
+	newest := eth.BlockNumberOrTag{}
+	percentiles := make([]float64, 0)
+	if err := r.Params.UnmarshalSingleParam(0, &count); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+	if err := r.Params.UnmarshalSingleParam(1, &newest); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+	if len(r.Params) > 2 {
+		if err := r.Params.UnmarshalSingleParam(2, &percentiles); err != nil {
+			return nil, jsonrpc.InvalidParams(err.Error())
+		}
+	}
+
+	last := b.resolve(&newest)
+	if last >= uint64(len(b.chain)) {
+		return nil, jsonrpc.InvalidParams("block not found")
+	}
+
+	first := uint64(0)
+	if n := count.UInt64(); n <= last {
+		first = last + 1 - n
+	}
+
+	history := eth.FeeHistory{OldestBlock: eth.QuantityFromUInt64(first)}
+	for _, mb := range b.chain[first : last+1] {
+		history.BaseFeePerGas = append(history.BaseFeePerGas, *mb.block.BaseFeePerGas)
+		history.GasUsedRatio = append(history.GasUsedRatio, float64(mb.block.GasUsed.UInt64())/float64(blockGasLimit))
+		if len(percentiles) > 0 {
+			history.Reward = append(history.Reward, rewards(mb, percentiles))
+		}
+	}
+	history.BaseFeePerGas = append(history.BaseFeePerGas, eth.QuantityFromUInt64(b.config.BaseFee))
+
+	return history, nil
+}
+
+// rewards returns the priority fee paid at each percentile of the gas used in a block
+func rewards(mb *minedBlock, percentiles []float64) []eth.Quantity {
+	type tip struct {
+		fee uint64
+		gas uint64
+	}
+
+	base := mb.block.BaseFeePerGas.UInt64()
+	tips := make([]tip, 0, len(mb.receipts))
+	for _, receipt := range mb.receipts {
+		fee := uint64(0)
+		if price := receipt.EffectiveGasPrice.UInt64(); price > base {
+			fee = price - base
+		}
+		tips = append(tips, tip{fee: fee, gas: receipt.GasUsed.UInt64()})
+	}
+	sort.Slice(tips, func(i, j int) bool { return tips[i].fee < tips[j].fee })
+
+	result := make([]eth.Quantity, len(percentiles))
+	for i, p := range percentiles {
+		threshold := uint64(p / 100 * float64(mb.block.GasUsed.UInt64()))
+		cumulative := uint64(0)
+		result[i] = eth.QuantityFromUInt64(0)
+		for _, t := range tips {
+			cumulative += t.gas
+			result[i] = eth.QuantityFromUInt64(t.fee)
+			if cumulative >= threshold {
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+// resolve turns a block number or tag into a block number, every tag but earliest means the head
+func (b *Backend) resolve(numberOrTag *eth.BlockNumberOrTag) uint64 {
+	if numberOrTag == nil {
+		return b.head().block.Number.UInt64()
+	}
+
+	if tag, ok := numberOrTag.Tag(); ok {
+		if tag == eth.TagEarliest {
+			return 0
+		}
+
+		return b.head().block.Number.UInt64()
+	}
+
+	q, _ := numberOrTag.Quantity()
+	return q.UInt64()
+}
+
+// blockByNumberParam returns the canonical block named by the first param, or nil if there isn't one, and
+// whether full transactions were asked for
+func (b *Backend) blockByNumberParam(r *jsonrpc.Request) (*minedBlock, bool, *jsonrpc.Error) {
+	numberOrTag := eth.BlockNumberOrTag{}
+	if err := r.Params.UnmarshalSingleParam(0, &numberOrTag); err != nil {
+		return nil, false, jsonrpc.InvalidParams(err.Error())
+	}
+
+	full := false
+	if r.Method == "eth_getBlockByNumber" && len(r.Params) > 1 {
+		if err := r.Params.UnmarshalSingleParam(1, &full); err != nil {
+			return nil, false, jsonrpc.InvalidParams(err.Error())
+		}
+	}
+
+	number := b.resolve(&numberOrTag)
+	if number >= uint64(len(b.chain)) {
+		return nil, full, nil
+	}
+
+	return b.chain[number], full, nil
+}
+
+// blockByHashParam returns any block ever mined with the hash in the first param, or nil if there isn't one
+func (b *Backend) blockByHashParam(r *jsonrpc.Request) (*minedBlock, *jsonrpc.Error) {
+	hash := eth.Hash("")
+	if err := r.Params.UnmarshalSingleParam(0, &hash); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+
+	return b.byHash[hash.String()], nil
+}
+
+func blockResult(mb *minedBlock, full bool) eth.Block {
+	block := mb.block
+	if !full {
+		block.Transactions = append([]eth.TxOrHash(nil), block.Transactions...)
+		block.DepopulateTransactions()
+	}
+
+	return block
+}
+
+func transactionAt(mb *minedBlock, r *jsonrpc.Request) (interface{}, *jsonrpc.Error) {
+	index := eth.Quantity{}
+	if err := r.Params.UnmarshalSingleParam(1, &index); err != nil {
+		return nil, jsonrpc.InvalidParams(err.Error())
+	}
+
+	if index.UInt64() >= uint64(len(mb.block.Transactions)) {
+		return nil, nil
+	}
+
+	return mb.block.Transactions[index.UInt64()].Transaction, nil
+}
+
+// logs returns the logs in the canonical chain matching criteria
+func (b *Backend) logs(criteria eth.LogFilter) []eth.Log {
+	matched := make([]eth.Log, 0)
+
+	if criteria.BlockHash != nil {
+		if mb, ok := b.byHash[criteria.BlockHash.String()]; ok {
+			for _, l := range mb.logs {
+				if criteria.Matches(l) {
+					matched = append(matched, l)
+				}
+			}
+		}
+
+		return matched
+	}
+
+	from, to := b.resolve(criteria.FromBlock), b.resolve(criteria.ToBlock)
+	for n := from; n <= to && n < uint64(len(b.chain)); n++ {
+		for _, l := range b.chain[n].logs {
+			if criteria.Matches(l) {
+				matched = append(matched, l)
+			}
+		}
+	}
+
+	return matched
+}
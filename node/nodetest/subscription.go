@@ -0,0 +1,240 @@
+package nodetest
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+	"github.com/justinwongcn/go-ethlibs/node"
+)
+
+// subscription queues notifications so that mining never waits on a slow reader
+type subscription struct {
+	backend  *Backend
+	id       string
+	kind     string
+	criteria eth.LogFilter
+	response *jsonrpc.RawResponse
+
+	ch     chan *jsonrpc.Notification
+	doneCh chan struct{}
+	wake   chan struct{}
+	stopCh chan struct{}
+	once   sync.Once
+
+	mu    sync.Mutex
+	queue []*jsonrpc.Notification
+	err   *node.SubscriptionError
+}
+
+func (b *Backend) Subscribe(ctx context.Context, r *jsonrpc.Request) (node.Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.failure(r.Method); err != nil {
+		return nil, err
+	}
+
+	kind := ""
+	if err := r.Params.UnmarshalSingleParam(0, &kind); err != nil {
+		return nil, errors.Wrap(err, "could not decode subscription type")
+	}
+
+	s := subscription{
+		backend: b,
+		kind:    kind,
+		ch:      make(chan *jsonrpc.Notification),
+		doneCh:  make(chan struct{}),
+		wake:    make(chan struct{}, 1),
+		stopCh:  make(chan struct{}),
+	}
+
+	switch kind {
+	case "newHeads", "newPendingTransactions":
+	case "logs":
+		if len(r.Params) > 1 {
+			if err := r.Params.UnmarshalSingleParam(1, &s.criteria); err != nil {
+				return nil, errors.Wrap(err, "could not decode log filter")
+			}
+		}
+	default:
+		return nil, errors.Errorf("unsupported subscription type %s", kind)
+	}
+
+	s.id = b.nextIdentifier()
+	result, _ := json.Marshal(s.id)
+	s.response = &jsonrpc.RawResponse{
+		JSONRPC: "2.0",
+		ID:      r.ID,
+		Result:  result,
+	}
+
+	b.subscriptions[s.id] = &s
+	go s.run()
+	return &s, nil
+}
+
+// unsubscribe ends the subscription with id, returning false if there was none, b.mu must be held
+func (b *Backend) unsubscribe(id string) bool {
+	s, ok := b.subscriptions[id]
+	if !ok {
+		return false
+	}
+
+	delete(b.subscriptions, id)
+	s.terminate(node.TerminationUnsubscribed, nil)
+	return true
+}
+
+// notifyBlock sends a newly mined block to the subscriptions and filters interested in it, b.mu must be held
+func (b *Backend) notifyBlock(mb *minedBlock) {
+	head := eth.NewHeadsResult{}
+	head.FromBlock(&mb.block)
+
+	for _, s := range b.subscriptions {
+		if s.kind == "newHeads" {
+			s.notify(head)
+		}
+	}
+
+	for _, f := range b.filters {
+		if f.typ == node.FilterTypeBlocks {
+			f.hashes = append(f.hashes, *mb.block.Hash)
+		}
+	}
+
+	for _, l := range mb.logs {
+		b.notifyLog(l)
+	}
+}
+
+// notifyLog sends a log that was added or removed to the subscriptions and filters that match it, b.mu must be held
+func (b *Backend) notifyLog(l eth.Log) {
+	for _, s := range b.subscriptions {
+		if s.kind == "logs" && s.criteria.Matches(l) {
+			s.notify(l)
+		}
+	}
+
+	for _, f := range b.filters {
+		if f.typ == node.FilterTypeLogs && f.criteria.Matches(l) {
+			f.logs = append(f.logs, l)
+		}
+	}
+}
+
+// notifyPending sends the hash of a new pending transaction to the subscriptions and filters watching for them, b.mu must be held
+func (b *Backend) notifyPending(hash eth.Hash) {
+	for _, s := range b.subscriptions {
+		if s.kind == "newPendingTransactions" {
+			s.notify(hash)
+		}
+	}
+
+	for _, f := range b.filters {
+		if f.typ == node.FilterTypePendingTransactions {
+			f.hashes = append(f.hashes, hash)
+		}
+	}
+}
+
+func (s *subscription) notify(result interface{}) {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	params, _ := json.Marshal(&node.SubscriptionParams{Subscription: s.id, Result: raw})
+
+	s.mu.Lock()
+	s.queue = append(s.queue, &jsonrpc.Notification{
+		JSONRPC: "2.0",
+		Method:  "eth_subscription",
+		Params:  params,
+	})
+	s.mu.Unlock()
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run delivers queued notifications in order until the subscription is terminated
+func (s *subscription) run() {
+	defer close(s.doneCh)
+	defer close(s.ch)
+
+	for {
+		s.mu.Lock()
+		var next *jsonrpc.Notification
+		if len(s.queue) > 0 {
+			next, s.queue = s.queue[0], s.queue[1:]
+		}
+		s.mu.Unlock()
+
+		if next == nil {
+			select {
+			case <-s.wake:
+				continue
+			case <-s.stopCh:
+				return
+			}
+		}
+
+		select {
+		case s.ch <- next:
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+func (s *subscription) terminate(reason node.TerminationReason, cause error) {
+	s.mu.Lock()
+	if s.err == nil {
+		s.err = &node.SubscriptionError{Reason: reason, Err: cause}
+	}
+	s.mu.Unlock()
+
+	s.once.Do(func() { close(s.stopCh) })
+}
+
+func (s *subscription) Response() *jsonrpc.RawResponse {
+	return s.response
+}
+
+func (s *subscription) ID() string {
+	return s.id
+}
+
+func (s *subscription) Ch() <-chan *jsonrpc.Notification {
+	return s.ch
+}
+
+func (s *subscription) Unsubscribe(ctx context.Context) error {
+	s.backend.mu.Lock()
+	defer s.backend.mu.Unlock()
+
+	s.backend.unsubscribe(s.id)
+	return nil
+}
+
+func (s *subscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+func (s *subscription) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err == nil {
+		return nil
+	}
+
+	return s.err
+}
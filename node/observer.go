@@ -0,0 +1,223 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync/atomic"
+	"time"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// RequestEvent describes a request, batch or subscription made by a client.
+type RequestEvent struct {
+	// Method is the JSON-RPC method, or "batch" for a batch request
+	Method string
+
+	// BatchSize is the number of requests in a batch, or 0 for a single request
+	BatchSize int
+
+	// InFlight is how many requests the client had outstanding when this one started, including itself
+	InFlight int
+
+	// Duration is how long the request took, only set once it has finished
+	Duration time.Duration
+
+	// Err is set if the request failed, either to be sent or with an error response
+	Err error
+
+	// ErrorCode is the code of the JSON-RPC error response, or of the first failed request in a batch, or 0
+	ErrorCode int
+}
+
+// NotificationEvent describes a subscription notification read from a websocket or IPC connection.
+type NotificationEvent struct {
+	// SubscriptionID is the backend's ID for the subscription the notification belongs to
+	SubscriptionID string
+
+	// Backlog is how many notifications read from the connection are waiting to be delivered to subscribers,
+	// including this one
+	Backlog int
+}
+
+// Observer receives events about the traffic of a client, for example to record Prometheus metrics or
+// OpenTelemetry spans.  Methods are called synchronously and must not block.  Embed NopObserver to
+// implement only some of them.
+type Observer interface {
+	// RequestStarted is called before a request, batch or subscription is sent.  The context it returns
+	// is used for the request and passed to RequestFinished, so it can carry a span.
+	RequestStarted(ctx context.Context, e RequestEvent) context.Context
+
+	// RequestFinished is called once the request has a response or has failed.
+	RequestFinished(ctx context.Context, e RequestEvent)
+
+	// NotificationReceived is called for every subscription notification read from the connection.
+	NotificationReceived(e NotificationEvent)
+
+	// NotificationDropped is called for notifications that could not be delivered, because their
+	// subscription was unknown or had already ended.
+	NotificationDropped(e NotificationEvent)
+
+	// Reconnect is called for every event of a client using WithReconnect.
+	Reconnect(e ReconnectEvent)
+}
+
+// NopObserver ignores every event, and can be embedded to implement only part of Observer.
+type NopObserver struct{}
+
+func (NopObserver) RequestStarted(ctx context.Context, e RequestEvent) context.Context { return ctx }
+func (NopObserver) RequestFinished(ctx context.Context, e RequestEvent)                {}
+func (NopObserver) NotificationReceived(e NotificationEvent)                           {}
+func (NopObserver) NotificationDropped(e NotificationEvent)                            {}
+func (NopObserver) Reconnect(e ReconnectEvent)                                         {}
+
+// WithObserver reports the client's requests, batches and subscriptions to observer, along with the
+// notifications and reconnects of websocket and IPC connections.  Requests are observed as they pass
+// through the interceptor chain, so interceptors added before the observer are included in the timing.
+// More than one observer may be added.
+func WithObserver(observer Observer) Option {
+	return func(o *clientOptions) {
+		ob := observingInterceptors{observer: observer}
+		o.observers = append(o.observers, observer)
+		o.requestInterceptors = append(o.requestInterceptors, ob.interceptRequest)
+		o.batchInterceptors = append(o.batchInterceptors, ob.interceptBatch)
+		o.subscribeInterceptors = append(o.subscribeInterceptors, ob.interceptSubscribe)
+	}
+}
+
+// observer returns an Observer reporting to every observer added, or nil if there are none
+func (o *clientOptions) observer() Observer {
+	switch len(o.observers) {
+	case 0:
+		return nil
+	case 1:
+		return o.observers[0]
+	default:
+		return multiObserver(o.observers)
+	}
+}
+
+// observeReconnects returns a copy of config which reports events to observer as well as to OnEvent
+func observeReconnects(config *ReconnectConfig, observer Observer) *ReconnectConfig {
+	if config == nil || observer == nil {
+		return config
+	}
+
+	observed := *config
+	observed.OnEvent = func(e ReconnectEvent) {
+		observer.Reconnect(e)
+		if config.OnEvent != nil {
+			config.OnEvent(e)
+		}
+	}
+
+	return &observed
+}
+
+type observingInterceptors struct {
+	observer Observer
+	inFlight int64
+}
+
+// observe reports a request to the observer around calling send
+func (ob *observingInterceptors) observe(ctx context.Context, e RequestEvent, send func(ctx context.Context) (int, error)) {
+	e.InFlight = int(atomic.AddInt64(&ob.inFlight, 1))
+	defer atomic.AddInt64(&ob.inFlight, -1)
+
+	ctx = ob.observer.RequestStarted(ctx, e)
+	start := time.Now()
+	e.ErrorCode, e.Err = send(ctx)
+	e.Duration = time.Since(start)
+	ob.observer.RequestFinished(ctx, e)
+}
+
+func (ob *observingInterceptors) interceptRequest(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (response *jsonrpc.RawResponse, err error) {
+	ob.observe(ctx, RequestEvent{Method: r.Method}, func(ctx context.Context) (int, error) {
+		response, err = next(ctx, r)
+		if err != nil {
+			return 0, err
+		}
+
+		return responseError(response)
+	})
+
+	return response, err
+}
+
+func (ob *observingInterceptors) interceptBatch(ctx context.Context, batch jsonrpc.BatchRequest, next BatchInvoker) (responses jsonrpc.BatchRawResponse, err error) {
+	ob.observe(ctx, RequestEvent{Method: "batch", BatchSize: len(batch)}, func(ctx context.Context) (int, error) {
+		responses, err = next(ctx, batch)
+		if err != nil {
+			return 0, err
+		}
+
+		for _, response := range responses {
+			if code, err := responseError(response); err != nil {
+				return code, err
+			}
+		}
+
+		return 0, nil
+	})
+
+	return responses, err
+}
+
+func (ob *observingInterceptors) interceptSubscribe(ctx context.Context, r *jsonrpc.Request, next SubscribeInvoker) (sub Subscription, err error) {
+	ob.observe(ctx, RequestEvent{Method: r.Method}, func(ctx context.Context) (int, error) {
+		sub, err = next(ctx, r)
+		return 0, err
+	})
+
+	return sub, err
+}
+
+// responseError returns the code and message of an error response
+func responseError(response *jsonrpc.RawResponse) (int, error) {
+	if response == nil || response.Error == nil {
+		return 0, nil
+	}
+
+	e := jsonrpc.Error{}
+	if json.Unmarshal(*response.Error, &e) != nil {
+		return 0, jsonrpc.InternalError(string(*response.Error))
+	}
+
+	return int(e.Code), &e
+}
+
+// multiObserver reports every event to each of its observers in turn
+type multiObserver []Observer
+
+func (m multiObserver) RequestStarted(ctx context.Context, e RequestEvent) context.Context {
+	for _, o := range m {
+		ctx = o.RequestStarted(ctx, e)
+	}
+	return ctx
+}
+
+func (m multiObserver) RequestFinished(ctx context.Context, e RequestEvent) {
+	for _, o := range m {
+		o.RequestFinished(ctx, e)
+	}
+}
+
+func (m multiObserver) NotificationReceived(e NotificationEvent) {
+	for _, o := range m {
+		o.NotificationReceived(e)
+	}
+}
+
+func (m multiObserver) NotificationDropped(e NotificationEvent) {
+	for _, o := range m {
+		o.NotificationDropped(e)
+	}
+}
+
+func (m multiObserver) Reconnect(e ReconnectEvent) {
+	for _, o := range m {
+		o.Reconnect(e)
+	}
+}
+
+var _ Observer = NopObserver{}
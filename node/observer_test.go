@@ -0,0 +1,154 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+type spanKey struct{}
+
+// recordingObserver keeps every event, and puts a span in the context of each request
+type recordingObserver struct {
+	NopObserver
+	mu       sync.Mutex
+	finished []RequestEvent
+	spans    []interface{}
+	received []NotificationEvent
+	dropped  []NotificationEvent
+}
+
+func (o *recordingObserver) RequestStarted(ctx context.Context, e RequestEvent) context.Context {
+	return context.WithValue(ctx, spanKey{}, e.Method)
+}
+
+func (o *recordingObserver) RequestFinished(ctx context.Context, e RequestEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finished = append(o.finished, e)
+	o.spans = append(o.spans, ctx.Value(spanKey{}))
+}
+
+func (o *recordingObserver) NotificationReceived(e NotificationEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.received = append(o.received, e)
+}
+
+func (o *recordingObserver) NotificationDropped(e NotificationEvent) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.dropped = append(o.dropped, e)
+}
+
+func (o *recordingObserver) droppedCount() int {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return len(o.dropped)
+}
+
+func TestObserver(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	observer := &recordingObserver{}
+	tr, backend := newObservedPipeTransport(t, ctx, observer, NopLogger{})
+	c := &client{transport: intercept(tr, newClientOptions(WithObserver(observer)))}
+
+	go func() {
+		r := backend.next()
+		backend.respond(r, "0x1")
+
+		r = backend.next()
+		backend.send(&jsonrpc.RawResponse{ID: r.ID, Error: rawError(`{"code":-32000,"message":"execution reverted"}`)})
+
+		for i := 0; i < 3; i++ {
+			r = backend.next()
+			backend.respond(r, "0x1")
+		}
+
+		r = backend.next()
+		backend.respond(r, "0x9")
+	}()
+
+	_, err := c.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.NoError(t, err)
+
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_call"))
+	require.NoError(t, err)
+
+	_, err = c.RequestBatch(ctx, newTestBatch())
+	require.NoError(t, err)
+
+	sub, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+
+	observer.mu.Lock()
+	require.Len(t, observer.finished, 4)
+	require.Equal(t, "eth_blockNumber", observer.finished[0].Method)
+	require.Equal(t, 1, observer.finished[0].InFlight)
+	require.NoError(t, observer.finished[0].Err)
+
+	require.Equal(t, -32000, observer.finished[1].ErrorCode)
+	require.EqualError(t, observer.finished[1].Err, "execution reverted")
+
+	require.Equal(t, "batch", observer.finished[2].Method)
+	require.Equal(t, 3, observer.finished[2].BatchSize)
+	require.Equal(t, "eth_subscribe", observer.finished[3].Method)
+
+	// the context returned by RequestStarted is the one the request was made with
+	require.Equal(t, []interface{}{"eth_blockNumber", "eth_call", "batch", "eth_subscribe"}, observer.spans)
+	observer.mu.Unlock()
+
+	backend.send(&jsonrpc.Notification{Method: "eth_subscription", Params: json.RawMessage(`{"subscription":"0x9","result":{}}`)})
+	<-sub.Ch()
+
+	// nobody is subscribed to this one
+	backend.send(&jsonrpc.Notification{Method: "eth_subscription", Params: json.RawMessage(`{"subscription":"0xdead","result":{}}`)})
+
+	deadline := time.Now().Add(time.Second)
+	for observer.droppedCount() == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	observer.mu.Lock()
+	defer observer.mu.Unlock()
+	require.Len(t, observer.received, 2)
+	require.Equal(t, 1, observer.received[0].Backlog)
+	require.Equal(t, []NotificationEvent{{SubscriptionID: "0xdead", Backlog: 1}}, observer.dropped)
+}
+
+func TestObserver_Reconnect(t *testing.T) {
+	events := make([]ReconnectEventType, 0)
+	observer := &reconnectObserver{events: &events}
+
+	onEvent := 0
+	config := ReconnectConfig{OnEvent: func(ReconnectEvent) { onEvent++ }}
+	o := newClientOptions(WithReconnect(config), WithObserver(observer))
+	require.Equal(t, Observer(observer), o.observer())
+
+	// both the observer and OnEvent hear about it, without the options being changed
+	observeReconnects(o.reconnect, o.observer()).OnEvent(ReconnectEvent{Type: ReconnectEventReconnected})
+	require.Equal(t, []ReconnectEventType{ReconnectEventReconnected}, events)
+	require.Equal(t, 1, onEvent)
+	o.reconnect.OnEvent(ReconnectEvent{})
+	require.Len(t, events, 1)
+
+	require.Nil(t, newClientOptions().observer())
+	require.IsType(t, multiObserver{}, newClientOptions(WithObserver(observer), WithObserver(NopObserver{})).observer())
+}
+
+type reconnectObserver struct {
+	NopObserver
+	events *[]ReconnectEventType
+}
+
+func (o *reconnectObserver) Reconnect(e ReconnectEvent) {
+	*o.events = append(*o.events, e.Type)
+}
@@ -0,0 +1,264 @@
+package node
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/pkg/errors"
+)
+
+const defaultHTTPTimeout = 120 * time.Second
+
+// Option configures a client created by NewClientWithOptions.
+type Option func(o *clientOptions)
+
+type clientOptions struct {
+	httpClient  *http.Client
+	httpTimeout time.Duration
+	dialTimeout time.Duration
+	dialer      *websocket.Dialer
+	headers     http.Header
+	auth        func() (string, error)
+
+	reconnect *ReconnectConfig
+	polling   *PollingConfig
+
+	requestInterceptors   []RequestInterceptor
+	batchInterceptors     []BatchInterceptor
+	subscribeInterceptors []SubscribeInterceptor
+
+	observers []Observer
+	logger    Logger
+}
+
+func newClientOptions(opts ...Option) *clientOptions {
+	o := clientOptions{
+		httpTimeout: defaultHTTPTimeout,
+		headers:     make(http.Header),
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &o
+}
+
+// WithHTTPClient makes HTTP transports send requests with c instead of a client of their own, for example to
+// use a proxy or TLS client certificates.  The timeout set by WithHTTPTimeout does not apply to c.
+func WithHTTPClient(c *http.Client) Option {
+	return func(o *clientOptions) {
+		o.httpClient = c
+	}
+}
+
+// WithHTTPTimeout sets the timeout of each HTTP request, which defaults to 120s.
+func WithHTTPTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.httpTimeout = d
+	}
+}
+
+// WithDialTimeout limits how long establishing a websocket or IPC connection may take.
+func WithDialTimeout(d time.Duration) Option {
+	return func(o *clientOptions) {
+		o.dialTimeout = d
+	}
+}
+
+// WithWebsocketDialer makes websocket transports connect with d instead of websocket.DefaultDialer.
+func WithWebsocketDialer(d *websocket.Dialer) Option {
+	return func(o *clientOptions) {
+		o.dialer = d
+	}
+}
+
+// WithHeader adds a header to every HTTP request and to the websocket handshake.
+func WithHeader(key, value string) Option {
+	return func(o *clientOptions) {
+		o.headers.Add(key, value)
+	}
+}
+
+// WithHeaders adds all of h to every HTTP request and to the websocket handshake.
+func WithHeaders(h http.Header) Option {
+	return func(o *clientOptions) {
+		for key, values := range h {
+			for _, value := range values {
+				o.headers.Add(key, value)
+			}
+		}
+	}
+}
+
+// WithBasicAuth authenticates HTTP requests and the websocket handshake with a username and password.
+func WithBasicAuth(username, password string) Option {
+	return func(o *clientOptions) {
+		o.auth = func() (string, error) {
+			return "Basic " + base64.StdEncoding.EncodeToString([]byte(username+":"+password)), nil
+		}
+	}
+}
+
+// WithBearerToken authenticates HTTP requests and the websocket handshake with a static bearer token.
+func WithBearerToken(token string) Option {
+	return func(o *clientOptions) {
+		o.auth = func() (string, error) {
+			return "Bearer " + token, nil
+		}
+	}
+}
+
+// WithJWTAuth authenticates HTTP requests and the websocket handshake with a HS256 JWT signed by secret,
+// as the engine API requires.  A fresh token with the current time as its iat claim is created for every
+// HTTP request and every websocket connection.
+func WithJWTAuth(secret []byte) Option {
+	return func(o *clientOptions) {
+		o.auth = func() (string, error) {
+			token, err := newJWT(secret, time.Now())
+			if err != nil {
+				return "", err
+			}
+
+			return "Bearer " + token, nil
+		}
+	}
+}
+
+// WithReconnect makes websocket and IPC clients reconnect and resubscribe after losing their connection,
+// as described by NewReconnectingClient.
+func WithReconnect(config ReconnectConfig) Option {
+	return func(o *clientOptions) {
+		o.reconnect = &config
+	}
+}
+
+// WithPolling makes HTTP clients emulate subscriptions by polling filters, as described by NewPollingClient.
+func WithPolling(config PollingConfig) Option {
+	return func(o *clientOptions) {
+		o.polling = &config
+	}
+}
+
+// NewClientWithOptions is like NewClient, but the transport for rawURL is configured by opts.
+func NewClientWithOptions(ctx context.Context, rawURL string, opts ...Option) (Client, error) {
+	transport, err := newTransport(ctx, rawURL, newClientOptions(opts...))
+	if err != nil {
+		return nil, err
+	}
+
+	return &client{
+		transport: transport,
+		rawURL:    rawURL,
+	}, nil
+}
+
+// newTransport creates the transport for rawURL according to its scheme
+func newTransport(ctx context.Context, rawURL string, o *clientOptions) (transport, error) {
+	parsedURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not parse url")
+	}
+
+	var transport transport
+
+	reconnect := observeReconnects(logReconnects(o.reconnect, o.loggerFor(rawURL)), o.observer())
+
+	switch parsedURL.Scheme {
+	case "http", "https":
+		transport, err = newHTTPTransport(ctx, parsedURL, o)
+		if err == nil && o.polling != nil {
+			transport = &customTransport{
+				requester:  transport,
+				subscriber: NewPollingSubscriber(ctx, transport, *o.polling),
+			}
+		}
+	case "wss", "ws":
+		if reconnect != nil {
+			transport, err = newReconnectingTransport(ctx, func(ctx context.Context) (*loopingTransport, error) {
+				t, err := newWebsocketTransport(ctx, parsedURL, o)
+				if err != nil {
+					return nil, err
+				}
+				return t.loopingTransport, nil
+			}, *reconnect)
+		} else {
+			transport, err = newWebsocketTransport(ctx, parsedURL, o)
+		}
+	default:
+		if reconnect != nil {
+			transport, err = newReconnectingTransport(ctx, func(ctx context.Context) (*loopingTransport, error) {
+				t, err := newIPCTransport(ctx, parsedURL, o)
+				if err != nil {
+					return nil, err
+				}
+				return t.loopingTransport, nil
+			}, *reconnect)
+		} else {
+			transport, err = newIPCTransport(ctx, parsedURL, o)
+		}
+	}
+
+	if err != nil {
+		return nil, errors.Wrap(err, "could not create client transport")
+	}
+
+	return intercept(transport, o), nil
+}
+
+// header returns the headers to send with a request or handshake, including a fresh Authorization header
+func (o *clientOptions) header() (http.Header, error) {
+	h := make(http.Header, len(o.headers)+1)
+	for key, values := range o.headers {
+		h[key] = append([]string(nil), values...)
+	}
+
+	if o.auth != nil {
+		authorization, err := o.auth()
+		if err != nil {
+			return nil, errors.Wrap(err, "could not create authorization header")
+		}
+		h.Set("Authorization", authorization)
+	}
+
+	return h, nil
+}
+
+// dialContext returns the context to establish a connection with, which is bounded by the dial timeout
+func (o *clientOptions) dialContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if o.dialTimeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, o.dialTimeout)
+}
+
+// newJWT returns a HS256 signed JWT whose only claim is the time it was issued at
+func newJWT(secret []byte, now time.Time) (string, error) {
+	if len(secret) == 0 {
+		return "", errors.New("jwt secret is empty")
+	}
+
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := json.Marshal(map[string]int64{"iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
@@ -0,0 +1,146 @@
+package node
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/require"
+)
+
+// headerServer answers eth_blockNumber over HTTP and websockets, passing each request's headers to seen
+func headerServer(t *testing.T, seen chan<- http.Header) *httptest.Server {
+	upgrader := websocket.Upgrader{}
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		seen <- r.Header.Clone()
+
+		if websocket.IsWebSocketUpgrade(r) {
+			conn, err := upgrader.Upgrade(w, r, nil)
+			require.NoError(t, err)
+			_ = conn.Close()
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+}
+
+func TestNewClientWithOptions_Headers(t *testing.T) {
+	ctx := context.Background()
+	seen := make(chan http.Header, 1)
+	server := headerServer(t, seen)
+	defer server.Close()
+
+	c, err := NewClientWithOptions(ctx, server.URL,
+		WithHeader("X-Api-Key", "secret"),
+		WithHeaders(http.Header{"X-Other": []string{"a", "b"}}),
+		WithBasicAuth("user", "pass"),
+	)
+	require.NoError(t, err)
+
+	n, err := c.BlockNumber(ctx)
+	require.NoError(t, err)
+	require.Equal(t, uint64(0x10), n)
+
+	h := <-seen
+	require.Equal(t, "secret", h.Get("X-Api-Key"))
+	require.Equal(t, []string{"a", "b"}, h["X-Other"])
+	require.Equal(t, "application/json", h.Get("Content-Type"))
+
+	user, pass, ok := (&http.Request{Header: h}).BasicAuth()
+	require.True(t, ok)
+	require.Equal(t, "user", user)
+	require.Equal(t, "pass", pass)
+
+	// the websocket handshake carries the same headers
+	wsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	_, err = NewClientWithOptions(wsCtx, "ws"+strings.TrimPrefix(server.URL, "http"),
+		WithHeader("X-Api-Key", "secret"),
+		WithBearerToken("token"),
+		WithDialTimeout(time.Second),
+	)
+	require.NoError(t, err)
+
+	h = <-seen
+	require.Equal(t, "secret", h.Get("X-Api-Key"))
+	require.Equal(t, "Bearer token", h.Get("Authorization"))
+}
+
+func TestNewClientWithOptions_JWT(t *testing.T) {
+	ctx := context.Background()
+	seen := make(chan http.Header, 1)
+	server := headerServer(t, seen)
+	defer server.Close()
+
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	c, err := NewClientWithOptions(ctx, server.URL, WithJWTAuth(secret))
+	require.NoError(t, err)
+
+	_, err = c.BlockNumber(ctx)
+	require.NoError(t, err)
+
+	authorization := (<-seen).Get("Authorization")
+	require.True(t, strings.HasPrefix(authorization, "Bearer "))
+
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	require.Len(t, parts, 3)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	require.Equal(t, base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), parts[2])
+
+	b, err := base64.RawURLEncoding.DecodeString(parts[1])
+	require.NoError(t, err)
+	claims := map[string]int64{}
+	require.NoError(t, json.Unmarshal(b, &claims))
+	require.InDelta(t, time.Now().Unix(), claims["iat"], 5)
+
+	_, err = newJWT(nil, time.Now())
+	require.Error(t, err)
+}
+
+type countingRoundTripper struct {
+	count int
+}
+
+func (rt *countingRoundTripper) RoundTrip(r *http.Request) (*http.Response, error) {
+	rt.count++
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+func TestNewClientWithOptions_HTTPClient(t *testing.T) {
+	ctx := context.Background()
+	seen := make(chan http.Header, 1)
+	server := headerServer(t, seen)
+	defer server.Close()
+
+	rt := &countingRoundTripper{}
+	c, err := NewClientWithOptions(ctx, server.URL, WithHTTPClient(&http.Client{Transport: rt}))
+	require.NoError(t, err)
+
+	_, err = c.BlockNumber(ctx)
+	require.NoError(t, err)
+	<-seen
+	require.Equal(t, 1, rt.count)
+
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer slow.Close()
+
+	c, err = NewClientWithOptions(ctx, slow.URL, WithHTTPTimeout(10*time.Millisecond))
+	require.NoError(t, err)
+
+	_, err = c.BlockNumber(ctx)
+	require.Error(t, err)
+}
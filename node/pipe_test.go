@@ -0,0 +1,97 @@
+package node
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// pipeBackend is the far end of an in-memory connection used to drive a loopingTransport in tests.
+type pipeBackend struct {
+	t        *testing.T
+	conn     net.Conn
+	requests chan *jsonrpc.Request
+	writeMu  sync.Mutex
+}
+
+func newPipeTransport(t *testing.T, ctx context.Context) (*loopingTransport, *pipeBackend) {
+	return newObservedPipeTransport(t, ctx, nil, NopLogger{})
+}
+
+// newObservedPipeTransport is like newPipeTransport, but reports notifications to observer and logs to logger
+func newObservedPipeTransport(t *testing.T, ctx context.Context, observer Observer, logger Logger) (*loopingTransport, *pipeBackend) {
+	clientConn, backendConn := net.Pipe()
+
+	scanner := bufio.NewScanner(clientConn)
+	readMessage := func() ([]byte, error) {
+		if !scanner.Scan() {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, net.ErrClosed
+		}
+
+		return []byte(scanner.Text()), nil
+	}
+
+	writeMessage := func(payload []byte) error {
+		_, err := clientConn.Write(payload)
+		return err
+	}
+
+	b := pipeBackend{
+		t:        t,
+		conn:     backendConn,
+		requests: make(chan *jsonrpc.Request, 100),
+	}
+
+	go func() {
+		defer close(b.requests)
+		decoder := json.NewDecoder(backendConn)
+		for {
+			r := jsonrpc.Request{}
+			if err := decoder.Decode(&r); err != nil {
+				return
+			}
+			b.requests <- &r
+		}
+	}()
+
+	return newLoopingTransport(ctx, clientConn, readMessage, writeMessage, observer, logger), &b
+}
+
+// next returns the next request the transport sent to the backend.
+func (b *pipeBackend) next() *jsonrpc.Request {
+	r, ok := <-b.requests
+	require.True(b.t, ok, "backend connection closed while waiting for a request")
+	return r
+}
+
+// send writes a single message to the transport.
+func (b *pipeBackend) send(msg interface{}) {
+	payload, err := json.Marshal(msg)
+	require.NoError(b.t, err)
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+	_, err = b.conn.Write(append(payload, '\n'))
+	require.NoError(b.t, err)
+}
+
+// respond replies to request r with the passed in result.
+func (b *pipeBackend) respond(r *jsonrpc.Request, result interface{}) {
+	raw, err := json.Marshal(result)
+	require.NoError(b.t, err)
+
+	b.send(&jsonrpc.RawResponse{
+		ID:     r.ID,
+		Result: raw,
+	})
+}
@@ -0,0 +1,369 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// PollingConfig controls how subscriptions are emulated with filters over transports without eth_subscribe.
+type PollingConfig struct {
+	// Interval between eth_getFilterChanges polls.  Defaults to 4s.
+	Interval time.Duration
+
+	// MaxConsecutiveErrors is the number of polls in a row that may fail before the subscription is
+	// terminated with TerminationConnectionLost, or 0 to keep polling until unsubscribed.
+	MaxConsecutiveErrors int
+}
+
+const defaultPollingInterval = 4 * time.Second
+
+// NewPollingClient is like NewClient, but for HTTP URLs the returned client emulates eth_subscribe for
+// newHeads, logs and newPendingTransactions by polling filters, delivering notifications in the same shape
+// as a real subscription.  Websocket and IPC URLs support subscriptions natively and get a regular client.
+func NewPollingClient(ctx context.Context, rawURL string, config PollingConfig) (Client, error) {
+	return NewClientWithOptions(ctx, rawURL, WithPolling(config))
+}
+
+// NewPollingSubscriber returns a Subscriber that emulates eth_subscribe on top of the filter methods of requester,
+// which can be passed to NewCustomClient.  Subscriptions are polled until they are unsubscribed or ctx finishes.
+func NewPollingSubscriber(ctx context.Context, requester Requester, config PollingConfig) Subscriber {
+	if config.Interval <= 0 {
+		config.Interval = defaultPollingInterval
+	}
+
+	return &pollingSubscriber{
+		ctx:       ctx,
+		requester: requester,
+		client:    &client{transport: &customTransport{requester: requester}},
+		config:    config,
+	}
+}
+
+type pollingSubscriber struct {
+	ctx       context.Context
+	requester Requester
+	client    *client
+	config    PollingConfig
+}
+
+// changesFunc turns the result of eth_getFilterChanges into the results of individual notifications
+type changesFunc func(ctx context.Context, changes json.RawMessage) ([]json.RawMessage, error)
+
+func (p *pollingSubscriber) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
+	if r.Method != "eth_subscribe" {
+		return nil, errors.New("request is not a subscription request")
+	}
+
+	kind := ""
+	err := r.Params.UnmarshalSingleParam(0, &kind)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode subscription type")
+	}
+
+	var install func(ctx context.Context) (string, error)
+	var expand changesFunc
+
+	switch kind {
+	case "newHeads":
+		install = func(ctx context.Context) (string, error) {
+			return installFilter(ctx, p.requester, "eth_newBlockFilter")
+		}
+		expand = p.expandNewHeads
+	case "logs":
+		filter := eth.LogFilter{}
+		if len(r.Params) > 1 {
+			err = r.Params.UnmarshalSingleParam(1, &filter)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not decode log filter")
+			}
+		}
+
+		install = func(ctx context.Context) (string, error) {
+			return installFilter(ctx, p.requester, "eth_newFilter", filter)
+		}
+		expand = expandArray
+	case "newPendingTransactions":
+		full := false
+		if len(r.Params) > 1 {
+			err = r.Params.UnmarshalSingleParam(1, &full)
+			if err != nil {
+				return nil, errors.Wrap(err, "could not decode full transactions flag")
+			}
+		}
+
+		install = func(ctx context.Context) (string, error) {
+			return installFilter(ctx, p.requester, "eth_newPendingTransactionFilter")
+		}
+		expand = expandArray
+		if full {
+			expand = p.expandTransactions
+		}
+	default:
+		return nil, errors.Errorf("%s subscriptions can not be emulated by polling", kind)
+	}
+
+	id, err := install(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not install filter")
+	}
+
+	result, err := json.Marshal(id)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not encode subscription id")
+	}
+
+	sub := pollingSubscription{
+		requester: p.requester,
+		interval:  p.config.Interval,
+		maxErrors: p.config.MaxConsecutiveErrors,
+		response: &jsonrpc.RawResponse{
+			JSONRPC: "2.0",
+			ID:      r.ID,
+			Result:  result,
+		},
+		id:              id,
+		filterID:        id,
+		install:         install,
+		expand:          expand,
+		notificationsCh: make(chan *jsonrpc.Notification),
+		doneCh:          make(chan struct{}),
+	}
+	sub.ctx, sub.cancel = context.WithCancel(p.ctx)
+
+	go sub.poll()
+	return &sub, nil
+}
+
+func (p *pollingSubscriber) expandNewHeads(ctx context.Context, changes json.RawMessage) ([]json.RawMessage, error) {
+	hashes := make([]eth.Hash, 0)
+	err := json.Unmarshal(changes, &hashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode block hashes")
+	}
+
+	results := make([]json.RawMessage, 0, len(hashes))
+	for _, hash := range hashes {
+		block, err := p.client.BlockByHash(ctx, hash.String(), false)
+		if err == ErrBlockNotFound {
+			// the block was reorged away before we got to it
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		head := eth.NewHeadsResult{}
+		head.FromBlock(block)
+		b, err := json.Marshal(&head)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not encode new head")
+		}
+
+		results = append(results, b)
+	}
+
+	return results, nil
+}
+
+func (p *pollingSubscriber) expandTransactions(ctx context.Context, changes json.RawMessage) ([]json.RawMessage, error) {
+	hashes := make([]eth.Hash, 0)
+	err := json.Unmarshal(changes, &hashes)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode transaction hashes")
+	}
+
+	results := make([]json.RawMessage, 0, len(hashes))
+	for _, hash := range hashes {
+		tx, err := p.client.TransactionByHash(ctx, hash.String())
+		if err == ErrTransactionNotFound {
+			// the transaction has already left the pool
+			continue
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := json.Marshal(tx)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not encode transaction")
+		}
+
+		results = append(results, b)
+	}
+
+	return results, nil
+}
+
+// expandArray passes each element of the changes through as-is, which is all that's needed for logs and hashes
+func expandArray(ctx context.Context, changes json.RawMessage) ([]json.RawMessage, error) {
+	results := make([]json.RawMessage, 0)
+	err := json.Unmarshal(changes, &results)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not decode filter changes")
+	}
+
+	return results, nil
+}
+
+type pollingSubscription struct {
+	requester Requester
+	interval  time.Duration
+	maxErrors int
+	response  *jsonrpc.RawResponse
+
+	// id is the ID of the first filter, which stays the subscription ID even if the filter has to be reinstalled
+	id       string
+	filterID string
+	filterMu sync.Mutex
+	install  func(ctx context.Context) (string, error)
+	expand   changesFunc
+
+	notificationsCh chan *jsonrpc.Notification
+	doneCh          chan struct{}
+	ctx             context.Context
+	cancel          context.CancelFunc
+	err             *SubscriptionError
+	errOnce         sync.Once
+}
+
+func (s *pollingSubscription) Response() *jsonrpc.RawResponse {
+	return s.response
+}
+
+func (s *pollingSubscription) ID() string {
+	return s.id
+}
+
+func (s *pollingSubscription) Ch() <-chan *jsonrpc.Notification {
+	return s.notificationsCh
+}
+
+func (s *pollingSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+func (s *pollingSubscription) Err() error {
+	select {
+	case <-s.doneCh:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *pollingSubscription) Unsubscribe(ctx context.Context) error {
+	s.terminate(TerminationUnsubscribed, nil)
+
+	s.filterMu.Lock()
+	id := s.filterID
+	s.filterMu.Unlock()
+
+	_, err := filterRequest(ctx, s.requester, "eth_uninstallFilter", id)
+	if err != nil && errors.Cause(err) != ErrFilterNotFound {
+		return errors.Wrap(err, "unsubscribe failed")
+	}
+
+	return nil
+}
+
+// terminate records why the subscription is ending and stops polling.  Only the first reason is kept.
+func (s *pollingSubscription) terminate(reason TerminationReason, cause error) {
+	s.errOnce.Do(func() {
+		s.err = &SubscriptionError{Reason: reason, Err: cause}
+	})
+	s.cancel()
+}
+
+func (s *pollingSubscription) poll() {
+	defer func() {
+		close(s.notificationsCh)
+		close(s.doneCh)
+	}()
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	failures := 0
+	for {
+		select {
+		case <-s.ctx.Done():
+			s.terminate(TerminationClosed, s.ctx.Err())
+			return
+		case <-ticker.C:
+		}
+
+		results, err := s.changes()
+		if err != nil {
+			if s.ctx.Err() != nil {
+				continue
+			}
+
+			failures++
+			if s.maxErrors > 0 && failures >= s.maxErrors {
+				s.terminate(TerminationConnectionLost, err)
+				return
+			}
+
+			continue
+		}
+
+		failures = 0
+		for _, result := range results {
+			params, err := json.Marshal(&SubscriptionParams{
+				Subscription: s.id,
+				Result:       result,
+			})
+			if err != nil {
+				continue
+			}
+
+			n := jsonrpc.Notification{
+				JSONRPC: "2.0",
+				Method:  "eth_subscription",
+				Params:  params,
+			}
+
+			select {
+			case s.notificationsCh <- &n:
+			case <-s.ctx.Done():
+				s.terminate(TerminationClosed, s.ctx.Err())
+				return
+			}
+		}
+	}
+}
+
+func (s *pollingSubscription) changes() ([]json.RawMessage, error) {
+	s.filterMu.Lock()
+	id := s.filterID
+	s.filterMu.Unlock()
+
+	changes, err := filterRequest(s.ctx, s.requester, "eth_getFilterChanges", id)
+	if errors.Cause(err) == ErrFilterNotFound {
+		// the backend forgot about our filter (e.g. it restarted or expired it), so start over with a new one
+		id, err = s.install(s.ctx)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not reinstall filter")
+		}
+
+		s.filterMu.Lock()
+		s.filterID = id
+		s.filterMu.Unlock()
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s.expand(s.ctx, changes)
+}
@@ -0,0 +1,173 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// filterBackend answers the filter methods with changes queued up by the test
+type filterBackend struct {
+	t       *testing.T
+	mu      sync.Mutex
+	filters map[string][]json.RawMessage
+	next    int
+	calls   []string
+}
+
+func (b *filterBackend) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.calls = append(b.calls, r.Method)
+
+	var result interface{}
+	switch r.Method {
+	case "eth_newBlockFilter", "eth_newFilter", "eth_newPendingTransactionFilter":
+		b.next++
+		id := eth.QuantityFromUInt64(uint64(b.next)).String()
+		b.filters[id] = nil
+		result = id
+	case "eth_getFilterChanges":
+		id := ""
+		require.NoError(b.t, r.Params.UnmarshalInto(&id))
+		changes, ok := b.filters[id]
+		if !ok {
+			return &jsonrpc.RawResponse{ID: r.ID, Error: rawError(`{"code":-32000,"message":"filter not found"}`)}, nil
+		}
+		b.filters[id] = nil
+		if changes == nil {
+			changes = []json.RawMessage{}
+		}
+		result = changes
+	case "eth_getFilterLogs":
+		id := ""
+		require.NoError(b.t, r.Params.UnmarshalInto(&id))
+		logs, ok := b.filters[id]
+		if !ok {
+			return &jsonrpc.RawResponse{ID: r.ID, Error: rawError(`{"code":-32000,"message":"filter not found"}`)}, nil
+		}
+		if logs == nil {
+			logs = []json.RawMessage{}
+		}
+		result = logs
+	case "eth_uninstallFilter":
+		id := ""
+		require.NoError(b.t, r.Params.UnmarshalInto(&id))
+		_, ok := b.filters[id]
+		delete(b.filters, id)
+		result = ok
+	case "eth_getBlockByHash":
+		hash := ""
+		require.NoError(b.t, r.Params.UnmarshalInto(&hash))
+		result = json.RawMessage(`{"number":"0x10","hash":"` + hash + `","parentHash":"0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb",` +
+			`"sha3Uncles":"0x1dcc4de8dec75d7aab85b567b6ccd41ad312451b948a7413f0a142fd40d49347","logsBloom":"0x` + strings.Repeat("00", 256) + `",` +
+			`"transactionsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","stateRoot":"0x4d301d25429a2114965ce829e2c8b96b69ab826aca09aafa7fe71428d5545651",` +
+			`"receiptsRoot":"0x56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421","miner":"0x007733a1fe69cf3f2cf989f81c7b4cac1693387a","difficulty":"0x0",` +
+			`"totalDifficulty":"0x0","extraData":"0x","size":"0x200","gasLimit":"0x1c9c380","gasUsed":"0x0","timestamp":"0x5c6341f4","transactions":[],"uncles":[]}`)
+	default:
+		return nil, errors.Errorf("unexpected method %s", r.Method)
+	}
+
+	raw, err := json.Marshal(result)
+	require.NoError(b.t, err)
+	return &jsonrpc.RawResponse{ID: r.ID, Result: raw}, nil
+}
+
+func (b *filterBackend) push(id string, changes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, c := range changes {
+		b.filters[id] = append(b.filters[id], json.RawMessage(c))
+	}
+}
+
+func TestPollingSubscriber_NewHeads(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend := &filterBackend{t: t, filters: make(map[string][]json.RawMessage)}
+	c, err := NewCustomClient(backend, NewPollingSubscriber(ctx, backend, PollingConfig{Interval: 5 * time.Millisecond}))
+	require.NoError(t, err)
+	require.True(t, c.IsBidirectional())
+
+	sub, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+	require.Equal(t, "0x1", sub.ID())
+
+	backend.push("0x1", `"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd"`)
+
+	n := <-sub.Ch()
+	require.Equal(t, "eth_subscription", n.Method)
+
+	params := eth.NewHeadsNotificationParams{}
+	require.NoError(t, n.UnmarshalParamsInto(&params))
+	require.Equal(t, "0x1", params.Subscription)
+	require.Equal(t, uint64(0x10), params.Result.Number.UInt64())
+	require.Equal(t, "0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd", params.Result.Hash.String())
+
+	require.NoError(t, sub.Unsubscribe(ctx))
+	<-sub.Done()
+	require.Equal(t, TerminationUnsubscribed, sub.Err().(*SubscriptionError).Reason)
+
+	backend.mu.Lock()
+	require.Empty(t, backend.filters)
+	backend.mu.Unlock()
+}
+
+func TestPollingSubscriber_LogsReinstall(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backend := &filterBackend{t: t, filters: make(map[string][]json.RawMessage)}
+	c, err := NewCustomClient(backend, NewPollingSubscriber(ctx, backend, PollingConfig{Interval: 5 * time.Millisecond}))
+	require.NoError(t, err)
+
+	sub, err := c.SubscribeLogs(ctx, eth.LogFilter{})
+	require.NoError(t, err)
+	logs := WrapLogs(sub)
+
+	// the backend forgets the filter, which should get reinstalled under a new ID
+	backend.mu.Lock()
+	delete(backend.filters, "0x1")
+	backend.mu.Unlock()
+
+	for deadline := time.Now().Add(time.Second); ; {
+		backend.mu.Lock()
+		_, ok := backend.filters["0x2"]
+		backend.mu.Unlock()
+		if ok {
+			break
+		}
+
+		require.True(t, time.Now().Before(deadline), "filter was not reinstalled")
+		time.Sleep(time.Millisecond)
+	}
+
+	backend.push("0x2", `{"address":"0x8b406b4708a45f115347fc2d020735196f994c5f","blockHash":"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd","blockNumber":"0xa4c6b2","data":"0x","logIndex":"0x0","removed":false,"topics":[],"transactionHash":"0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb","transactionIndex":"0xa"}`)
+
+	l := <-logs.Ch()
+	require.Equal(t, uint64(0xa4c6b2), l.BlockNumber.UInt64())
+	require.Equal(t, "0x1", logs.ID())
+
+	cancel()
+	<-logs.Done()
+	require.Equal(t, TerminationClosed, logs.Err().(*SubscriptionError).Reason)
+}
+
+func TestPollingSubscriber_Unsupported(t *testing.T) {
+	backend := &filterBackend{t: t, filters: make(map[string][]json.RawMessage)}
+	c, err := NewCustomClient(backend, NewPollingSubscriber(context.Background(), backend, PollingConfig{}))
+	require.NoError(t, err)
+
+	_, err = c.SubscribeSyncing(context.Background())
+	require.Error(t, err)
+}
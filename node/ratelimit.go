@@ -0,0 +1,190 @@
+package node
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/semaphore"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// RateLimitConfig limits how hard a client pushes its backend.  Requests over either limit wait for
+// capacity until their context finishes, rather than failing right away.
+type RateLimitConfig struct {
+	// RequestsPerSecond is how much weight may be sent per second on average, or 0 for no rate limit
+	RequestsPerSecond float64
+
+	// Burst is how much weight may be sent at once after a quiet period.  Defaults to RequestsPerSecond, and at least 1.
+	Burst int
+
+	// MaxInFlight is how many requests may be outstanding at once, or 0 for no limit.  A batch counts as
+	// one request per item, up to MaxInFlight.
+	MaxInFlight int
+
+	// Weights is the cost of each method against RequestsPerSecond, for example to make eth_getLogs count
+	// as 10 requests.  Methods that aren't listed cost 1.
+	Weights map[string]float64
+}
+
+// WithRateLimit adds a token bucket rate limit and a limit on concurrent requests to the client, which apply
+// to requests, batches and subscription requests alike.  Every client created with the option gets its own limits.
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(o *clientOptions) {
+		l := newRateLimiter(config)
+		o.requestInterceptors = append(o.requestInterceptors, l.interceptRequest)
+		o.batchInterceptors = append(o.batchInterceptors, l.interceptBatch)
+		o.subscribeInterceptors = append(o.subscribeInterceptors, l.interceptSubscribe)
+	}
+}
+
+type rateLimiter struct {
+	config   RateLimitConfig
+	bucket   *tokenBucket
+	inFlight *semaphore.Weighted
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	l := rateLimiter{config: config}
+
+	if config.RequestsPerSecond > 0 {
+		burst := float64(config.Burst)
+		if burst <= 0 {
+			burst = math.Max(1, math.Ceil(config.RequestsPerSecond))
+		}
+		l.bucket = newTokenBucket(config.RequestsPerSecond, burst)
+	}
+
+	if config.MaxInFlight > 0 {
+		l.inFlight = semaphore.NewWeighted(int64(config.MaxInFlight))
+	}
+
+	return &l
+}
+
+func (l *rateLimiter) weight(method string) float64 {
+	if w, ok := l.config.Weights[method]; ok {
+		return w
+	}
+
+	return 1
+}
+
+// acquire waits until weight may be sent and n more requests may be in flight, returning a func to release them
+func (l *rateLimiter) acquire(ctx context.Context, weight float64, n int64) (func(), error) {
+	if l.bucket != nil {
+		if err := l.bucket.wait(ctx, weight); err != nil {
+			return nil, errors.Wrap(err, "context finished waiting for rate limit")
+		}
+	}
+
+	if l.inFlight == nil {
+		return func() {}, nil
+	}
+
+	if n > int64(l.config.MaxInFlight) {
+		n = int64(l.config.MaxInFlight)
+	}
+
+	if err := l.inFlight.Acquire(ctx, n); err != nil {
+		return nil, errors.Wrap(err, "context finished waiting for in flight requests")
+	}
+
+	return func() { l.inFlight.Release(n) }, nil
+}
+
+func (l *rateLimiter) interceptRequest(ctx context.Context, r *jsonrpc.Request, next RequestInvoker) (*jsonrpc.RawResponse, error) {
+	release, err := l.acquire(ctx, l.weight(r.Method), 1)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return next(ctx, r)
+}
+
+func (l *rateLimiter) interceptBatch(ctx context.Context, batch jsonrpc.BatchRequest, next BatchInvoker) (jsonrpc.BatchRawResponse, error) {
+	weight := 0.0
+	for _, r := range batch {
+		if r != nil {
+			weight += l.weight(r.Method)
+		}
+	}
+
+	release, err := l.acquire(ctx, weight, int64(len(batch)))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return next(ctx, batch)
+}
+
+func (l *rateLimiter) interceptSubscribe(ctx context.Context, r *jsonrpc.Request, next SubscribeInvoker) (Subscription, error) {
+	release, err := l.acquire(ctx, l.weight(r.Method), 1)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return next(ctx, r)
+}
+
+// tokenBucket refills at rate tokens per second up to burst.  Callers take tokens immediately, which may
+// leave the bucket in debt, and then wait for the debt they caused to be paid off.
+type tokenBucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+func newTokenBucket(rate, burst float64) *tokenBucket {
+	return &tokenBucket{
+		rate:   rate,
+		burst:  burst,
+		tokens: burst,
+		last:   time.Now(),
+	}
+}
+
+// take removes n tokens and returns how long until the bucket is out of debt, b.mu must be held
+func (b *tokenBucket) take(now time.Time, n float64) time.Duration {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+	b.tokens -= n
+
+	if b.tokens >= 0 {
+		return 0
+	}
+
+	return time.Duration(-b.tokens / b.rate * float64(time.Second))
+}
+
+func (b *tokenBucket) wait(ctx context.Context, n float64) error {
+	b.mu.Lock()
+	delay := b.take(time.Now(), n)
+	b.mu.Unlock()
+
+	if delay == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		// we never sent anything, so give back what we took
+		b.mu.Lock()
+		b.tokens = math.Min(b.burst, b.tokens+n)
+		b.mu.Unlock()
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,116 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// blockingRequester holds every request until release is closed, tracking how many are outstanding
+type blockingRequester struct {
+	release  chan struct{}
+	inFlight int32
+	peak     int32
+}
+
+func (b *blockingRequester) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	n := atomic.AddInt32(&b.inFlight, 1)
+	defer atomic.AddInt32(&b.inFlight, -1)
+
+	for {
+		peak := atomic.LoadInt32(&b.peak)
+		if n <= peak || atomic.CompareAndSwapInt32(&b.peak, peak, n) {
+			break
+		}
+	}
+
+	<-b.release
+	return &jsonrpc.RawResponse{ID: r.ID, Result: json.RawMessage(`"0x1"`)}, nil
+}
+
+func TestTokenBucket(t *testing.T) {
+	start := time.Now()
+	b := newTokenBucket(10, 2)
+	b.last = start
+
+	require.Equal(t, time.Duration(0), b.take(start, 1))
+	require.Equal(t, time.Duration(0), b.take(start, 1))
+	require.Equal(t, 100*time.Millisecond, b.take(start, 1))
+	require.Equal(t, 1100*time.Millisecond, b.take(start, 10))
+
+	// a second later all of the debt but 0.1s has been paid off
+	require.Equal(t, 200*time.Millisecond, b.take(start.Add(time.Second), 1))
+
+	// and tokens never pile up beyond the burst
+	require.Equal(t, time.Duration(0), b.take(start.Add(time.Hour), 2))
+	require.Equal(t, 100*time.Millisecond, b.take(start.Add(time.Hour), 1))
+}
+
+func TestRateLimit_MaxInFlight(t *testing.T) {
+	ctx := context.Background()
+	requester := &blockingRequester{release: make(chan struct{})}
+	c, err := NewCustomClient(requester, nil, WithRateLimit(RateLimitConfig{MaxInFlight: 2}))
+	require.NoError(t, err)
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+			require.NoError(t, err)
+		}()
+	}
+
+	// over the limit requests wait rather than fail, and give up when their context does
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&requester.inFlight) < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+
+	short, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	_, err = c.Request(short, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.Equal(t, context.DeadlineExceeded, errors.Cause(err))
+
+	close(requester.release)
+	wg.Wait()
+	require.Equal(t, int32(2), atomic.LoadInt32(&requester.peak))
+}
+
+func TestRateLimit_Weights(t *testing.T) {
+	ctx := context.Background()
+	requester := &blockingRequester{release: make(chan struct{})}
+	close(requester.release)
+
+	c, err := NewCustomClient(requester, nil, WithRateLimit(RateLimitConfig{
+		RequestsPerSecond: 20,
+		Burst:             2,
+		Weights:           map[string]float64{"eth_getLogs": 4},
+	}))
+	require.NoError(t, err)
+
+	start := time.Now()
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.NoError(t, err)
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.NoError(t, err)
+	require.True(t, time.Since(start) < 50*time.Millisecond)
+
+	// eth_getLogs puts the bucket 4 tokens in debt, which takes 200ms to pay off
+	_, err = c.Request(ctx, jsonrpc.MustRequest(1, "eth_getLogs"))
+	require.NoError(t, err)
+	require.True(t, time.Since(start) >= 190*time.Millisecond)
+
+	_, err = c.RequestBatch(ctx, jsonrpc.BatchRequest{jsonrpc.MustRequest(1, "eth_getLogs")})
+	require.NoError(t, err)
+	require.True(t, time.Since(start) >= 390*time.Millisecond)
+}
@@ -0,0 +1,580 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// ReconnectEventType identifies what happened to the connection of a reconnecting client.
+type ReconnectEventType int
+
+const (
+	// ReconnectEventDisconnected is reported when the connection to the backend is lost
+	ReconnectEventDisconnected ReconnectEventType = iota
+	// ReconnectEventDialFailed is reported for every redial attempt that fails
+	ReconnectEventDialFailed
+	// ReconnectEventReconnected is reported once a new connection to the backend has been established
+	ReconnectEventReconnected
+	// ReconnectEventResubscribeFailed is reported when a live subscription could not be re-issued after reconnecting,
+	// in which case that subscription is terminated
+	ReconnectEventResubscribeFailed
+	// ReconnectEventGaveUp is reported when ReconnectConfig.MaxAttempts redials have failed in a row,
+	// after which the client is permanently closed
+	ReconnectEventGaveUp
+)
+
+func (t ReconnectEventType) String() string {
+	switch t {
+	case ReconnectEventDisconnected:
+		return "disconnected"
+	case ReconnectEventDialFailed:
+		return "dial failed"
+	case ReconnectEventReconnected:
+		return "reconnected"
+	case ReconnectEventResubscribeFailed:
+		return "resubscribe failed"
+	case ReconnectEventGaveUp:
+		return "gave up"
+	default:
+		return "unknown"
+	}
+}
+
+// ReconnectEvent describes a change in the connection state of a reconnecting client.
+type ReconnectEvent struct {
+	Type ReconnectEventType
+	// Attempt is the number of the redial attempt, starting at 1, for dial related events
+	Attempt int
+	// SubscriptionID is the caller facing ID of the subscription for ReconnectEventResubscribeFailed
+	SubscriptionID string
+	// Err holds the underlying error, if any
+	Err error
+}
+
+// ReconnectConfig controls how a reconnecting client redials its backend.
+type ReconnectConfig struct {
+	// MinBackoff is the delay after the first failed redial, which doubles after every
+	// further failure up to MaxBackoff.  Defaults to 500ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff caps the delay between redials.  Defaults to 30s.
+	MaxBackoff time.Duration
+
+	// MaxAttempts is the number of consecutive failed redials after which the client gives up,
+	// or 0 to keep trying until the client context finishes.
+	MaxAttempts int
+
+	// OnEvent, if set, is called synchronously for every ReconnectEvent, so it should not block.
+	OnEvent func(ReconnectEvent)
+}
+
+const (
+	defaultMinReconnectBackoff = 500 * time.Millisecond
+	defaultMaxReconnectBackoff = 30 * time.Second
+)
+
+// NewReconnectingClient is like NewClient, but for websocket and IPC URLs the returned client transparently
+// redials the backend when the connection drops and re-issues eth_subscribe for any live subscriptions,
+// keeping their ID() and Ch() stable.  Requests made while disconnected wait for the next connection.
+// HTTP has no persistent connection to lose, so HTTP URLs get a regular client.
+func NewReconnectingClient(ctx context.Context, rawURL string, config ReconnectConfig) (Client, error) {
+	return NewClientWithOptions(ctx, rawURL, WithReconnect(config))
+}
+
+type dialFunc func(ctx context.Context) (*loopingTransport, error)
+
+type reconnectingTransport struct {
+	ctx    context.Context
+	dial   dialFunc
+	config ReconnectConfig
+
+	// conn is the live connection, or nil while reconnecting, in which case chReady
+	// will be closed once a new connection is available or the transport has shut down.
+	conn       *loopingTransport
+	generation uint64
+	chReady    chan struct{}
+	err        error
+	mu         sync.RWMutex
+
+	subscriptions   map[string]*reconnectingSubscription
+	subscriptionsMu sync.Mutex
+}
+
+func newReconnectingTransport(ctx context.Context, dial dialFunc, config ReconnectConfig) (*reconnectingTransport, error) {
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinReconnectBackoff
+	}
+
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = defaultMaxReconnectBackoff
+		if config.MaxBackoff < config.MinBackoff {
+			config.MaxBackoff = config.MinBackoff
+		}
+	}
+
+	conn, err := dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	t := reconnectingTransport{
+		ctx:           ctx,
+		dial:          dial,
+		config:        config,
+		conn:          conn,
+		generation:    1,
+		chReady:       make(chan struct{}),
+		subscriptions: make(map[string]*reconnectingSubscription),
+	}
+	close(t.chReady)
+
+	go t.supervise(conn)
+	return &t, nil
+}
+
+func (t *reconnectingTransport) emit(e ReconnectEvent) {
+	if t.config.OnEvent != nil {
+		t.config.OnEvent(e)
+	}
+}
+
+// supervise waits for the current connection to fail and then redials it until it
+// succeeds, the transport context finishes, or the configured attempts are exhausted.
+func (t *reconnectingTransport) supervise(conn *loopingTransport) {
+	for {
+		select {
+		case <-conn.Done():
+		case <-t.ctx.Done():
+			<-conn.Done()
+			t.shutdown(errors.Wrap(t.ctx.Err(), "transport context finished"))
+			return
+		}
+
+		if t.ctx.Err() != nil {
+			t.shutdown(errors.Wrap(t.ctx.Err(), "transport context finished"))
+			return
+		}
+
+		t.mu.Lock()
+		t.conn = nil
+		t.chReady = make(chan struct{})
+		t.mu.Unlock()
+		t.emit(ReconnectEvent{Type: ReconnectEventDisconnected, Err: conn.Err()})
+
+		next, err := t.redial()
+		if err != nil {
+			t.shutdown(err)
+			return
+		}
+
+		t.mu.Lock()
+		t.conn = next
+		t.generation++
+		generation := t.generation
+		close(t.chReady)
+		t.mu.Unlock()
+
+		t.resubscribe(next, generation)
+		conn = next
+	}
+}
+
+func (t *reconnectingTransport) redial() (*loopingTransport, error) {
+	backoff := t.config.MinBackoff
+	for attempt := 1; ; attempt++ {
+		conn, err := t.dial(t.ctx)
+		if err == nil {
+			t.emit(ReconnectEvent{Type: ReconnectEventReconnected, Attempt: attempt})
+			return conn, nil
+		}
+
+		t.emit(ReconnectEvent{Type: ReconnectEventDialFailed, Attempt: attempt, Err: err})
+		if t.config.MaxAttempts > 0 && attempt >= t.config.MaxAttempts {
+			err = errors.Wrapf(err, "could not reconnect after %d attempts", attempt)
+			t.emit(ReconnectEvent{Type: ReconnectEventGaveUp, Attempt: attempt, Err: err})
+			return nil, err
+		}
+
+		// add up to 20% jitter so many clients don't all redial a restarted node at once
+		delay := backoff + time.Duration(rand.Int63n(int64(backoff)/5+1))
+		select {
+		case <-time.After(delay):
+		case <-t.ctx.Done():
+			return nil, errors.Wrap(t.ctx.Err(), "transport context finished while reconnecting")
+		}
+
+		backoff *= 2
+		if backoff > t.config.MaxBackoff {
+			backoff = t.config.MaxBackoff
+		}
+	}
+}
+
+func (t *reconnectingTransport) resubscribe(conn *loopingTransport, generation uint64) {
+	t.subscriptionsMu.Lock()
+	subs := make([]*reconnectingSubscription, 0, len(t.subscriptions))
+	for _, sub := range t.subscriptions {
+		subs = append(subs, sub)
+	}
+	t.subscriptionsMu.Unlock()
+
+	for _, sub := range subs {
+		err := sub.resubscribe(t.ctx, conn, generation)
+		if err != nil {
+			t.emit(ReconnectEvent{Type: ReconnectEventResubscribeFailed, SubscriptionID: sub.id, Err: err})
+			t.remove(sub.id)
+			sub.terminate(TerminationBackend, err)
+		}
+	}
+}
+
+// shutdown permanently closes the transport, terminating all subscriptions and failing any waiting requests.
+func (t *reconnectingTransport) shutdown(err error) {
+	t.mu.Lock()
+	t.conn = nil
+	t.err = err
+	select {
+	case <-t.chReady:
+	default:
+		close(t.chReady)
+	}
+	t.mu.Unlock()
+
+	reason := TerminationConnectionLost
+	if t.ctx.Err() != nil {
+		reason = TerminationClosed
+	}
+
+	t.subscriptionsMu.Lock()
+	for id, sub := range t.subscriptions {
+		sub.terminate(reason, err)
+		delete(t.subscriptions, id)
+	}
+	t.subscriptionsMu.Unlock()
+}
+
+// connection returns the live connection, waiting for a reconnect if necessary.
+func (t *reconnectingTransport) connection(ctx context.Context) (*loopingTransport, uint64, error) {
+	for {
+		t.mu.RLock()
+		conn, generation, ready, err := t.conn, t.generation, t.chReady, t.err
+		t.mu.RUnlock()
+
+		if err != nil {
+			return nil, 0, err
+		}
+
+		if conn != nil {
+			return conn, generation, nil
+		}
+
+		select {
+		case <-ready:
+			continue
+		case <-ctx.Done():
+			return nil, 0, errors.Wrap(ctx.Err(), "context finished waiting for reconnect")
+		}
+	}
+}
+
+func (t *reconnectingTransport) generationNow() uint64 {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.generation
+}
+
+// connected returns true if the connection with the given generation is still live.
+func (t *reconnectingTransport) connected(generation uint64) bool {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	return t.conn != nil && t.generation == generation
+}
+
+func (t *reconnectingTransport) remove(id string) {
+	t.subscriptionsMu.Lock()
+	delete(t.subscriptions, id)
+	t.subscriptionsMu.Unlock()
+}
+
+func (t *reconnectingTransport) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	if r.Method == "eth_unsubscribe" {
+		// callers only know the stable subscription IDs, so a manual eth_unsubscribe has to be routed
+		// to the subscription rather than sent as-is to a backend that has never heard of that ID
+		var id string
+		if r.Params.UnmarshalInto(&id) == nil {
+			t.subscriptionsMu.Lock()
+			sub, ok := t.subscriptions[id]
+			t.subscriptionsMu.Unlock()
+
+			if ok {
+				if err := sub.Unsubscribe(ctx); err != nil {
+					return nil, err
+				}
+
+				return &jsonrpc.RawResponse{
+					JSONRPC: "2.0",
+					ID:      r.ID,
+					Result:  json.RawMessage(`true`),
+				}, nil
+			}
+		}
+	}
+
+	conn, _, err := t.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.Request(ctx, r)
+}
+
+func (t *reconnectingTransport) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	conn, _, err := t.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.RequestBatch(ctx, batch)
+}
+
+func (t *reconnectingTransport) Subscribe(ctx context.Context, r *jsonrpc.Request) (Subscription, error) {
+	owned, err := copyRequest(r)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, generation, err := t.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	inner, err := conn.Subscribe(ctx, &owned)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := newReconnectingSubscription(t, &owned, inner, generation)
+	t.subscriptionsMu.Lock()
+	t.subscriptions[sub.id] = sub
+	t.subscriptionsMu.Unlock()
+
+	// if the connection was replaced while we were subscribing, the supervisor may already have
+	// re-issued the live subscriptions without this one, so we have to catch up ourselves.
+	if t.generationNow() != generation {
+		conn, generation, err = t.connection(ctx)
+		if err == nil {
+			err = sub.resubscribe(ctx, conn, generation)
+		}
+
+		if err != nil {
+			err = errors.Wrap(err, "connection lost while subscribing")
+			t.remove(sub.id)
+			sub.terminate(TerminationConnectionLost, err)
+			return nil, err
+		}
+	}
+
+	return sub, nil
+}
+
+func (t *reconnectingTransport) IsBidirectional() bool {
+	return true
+}
+
+// reconnectingSubscription is the caller facing side of a subscription on a reconnecting transport.
+// It outlives the subscriptions on each individual connection and forwards their notifications
+// on a single stable channel, under the ID of the very first subscription.
+type reconnectingSubscription struct {
+	transport *reconnectingTransport
+	request   *jsonrpc.Request
+	response  *jsonrpc.RawResponse
+	id        string
+
+	notificationsCh chan *jsonrpc.Notification
+	innerCh         chan Subscription
+	stopCh          chan struct{}
+	stopOnce        sync.Once
+	doneCh          chan struct{}
+	err             *SubscriptionError
+
+	// inner is the subscription on the connection with the given generation, guarded by mu
+	inner      Subscription
+	generation uint64
+	mu         sync.Mutex
+}
+
+func newReconnectingSubscription(t *reconnectingTransport, request *jsonrpc.Request, inner Subscription, generation uint64) *reconnectingSubscription {
+	s := reconnectingSubscription{
+		transport:       t,
+		request:         request,
+		response:        inner.Response(),
+		id:              inner.ID(),
+		notificationsCh: make(chan *jsonrpc.Notification),
+		innerCh:         make(chan Subscription, 1),
+		stopCh:          make(chan struct{}),
+		doneCh:          make(chan struct{}),
+		inner:           inner,
+		generation:      generation,
+	}
+
+	s.innerCh <- inner
+	go s.forward()
+	return &s
+}
+
+func (s *reconnectingSubscription) Response() *jsonrpc.RawResponse {
+	return s.response
+}
+
+func (s *reconnectingSubscription) ID() string {
+	return s.id
+}
+
+func (s *reconnectingSubscription) Ch() <-chan *jsonrpc.Notification {
+	return s.notificationsCh
+}
+
+func (s *reconnectingSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+func (s *reconnectingSubscription) Err() error {
+	select {
+	case <-s.doneCh:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *reconnectingSubscription) Unsubscribe(ctx context.Context) error {
+	s.transport.remove(s.id)
+	s.terminate(TerminationUnsubscribed, nil)
+
+	s.mu.Lock()
+	inner, generation := s.inner, s.generation
+	s.mu.Unlock()
+
+	if !s.transport.connected(generation) {
+		// the connection this subscription lived on is already gone, so there is nothing to unsubscribe from
+		return nil
+	}
+
+	return inner.Unsubscribe(ctx)
+}
+
+// resubscribe re-issues the original subscription request on conn, unless that has already happened for this generation.
+func (s *reconnectingSubscription) resubscribe(ctx context.Context, conn *loopingTransport, generation uint64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.generation >= generation {
+		return nil
+	}
+
+	inner, err := conn.Subscribe(ctx, s.request)
+	if err != nil {
+		return errors.Wrapf(err, "could not resubscribe %s", s.id)
+	}
+
+	s.inner = inner
+	s.generation = generation
+
+	select {
+	case <-s.innerCh:
+		// drop an inner subscription that was never picked up, it belonged to a dead connection
+	default:
+	}
+	s.innerCh <- inner
+	return nil
+}
+
+// terminate stops forwarding notifications, only the first reason is kept.
+func (s *reconnectingSubscription) terminate(reason TerminationReason, cause error) {
+	s.stopOnce.Do(func() {
+		s.err = &SubscriptionError{Reason: reason, Err: cause}
+		close(s.stopCh)
+	})
+}
+
+// forward moves notifications from the current inner subscription to the caller facing channel,
+// switching over whenever a resubscription hands it a new inner subscription.
+func (s *reconnectingSubscription) forward() {
+	defer func() {
+		close(s.notificationsCh)
+		close(s.doneCh)
+	}()
+
+	var inner Subscription
+	for {
+		if inner == nil {
+			select {
+			case inner = <-s.innerCh:
+				continue
+			case <-s.stopCh:
+				return
+			}
+		}
+
+		select {
+		case n, ok := <-inner.Ch():
+			if !ok {
+				if err, ok := inner.Err().(*SubscriptionError); ok && err.Reason != TerminationConnectionLost {
+					// the subscription itself ended rather than the connection, so there is nothing to resume
+					s.transport.remove(s.id)
+					s.terminate(err.Reason, err.Err)
+					return
+				}
+
+				// the connection dropped, wait for the supervisor to resubscribe us
+				inner = nil
+				continue
+			}
+
+			n = s.patch(n, inner.ID())
+			select {
+			case s.notificationsCh <- n:
+			case <-s.stopCh:
+				return
+			}
+		case next := <-s.innerCh:
+			inner = next
+		case <-s.stopCh:
+			return
+		}
+	}
+}
+
+// patch rewrites the subscription ID in the notification params from the current backend ID to the stable one.
+func (s *reconnectingSubscription) patch(n *jsonrpc.Notification, innerID string) *jsonrpc.Notification {
+	if innerID == s.id {
+		return n
+	}
+
+	return rewriteSubscriptionID(n, s.id)
+}
+
+// rewriteSubscriptionID returns a copy of n whose params name the subscription id instead.
+func rewriteSubscriptionID(n *jsonrpc.Notification, id string) *jsonrpc.Notification {
+	sp := SubscriptionParams{}
+	if err := json.Unmarshal(n.Params, &sp); err != nil {
+		return n
+	}
+
+	sp.Subscription = id
+	params, err := json.Marshal(&sp)
+	if err != nil {
+		return n
+	}
+
+	patched := *n
+	patched.Params = params
+	return &patched
+}
@@ -0,0 +1,136 @@
+package node
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func TestReconnectingTransport_Resubscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	backends := make(chan *pipeBackend, 2)
+	dial := func(ctx context.Context) (*loopingTransport, error) {
+		conn, backend := newPipeTransport(t, ctx)
+		backends <- backend
+		return conn, nil
+	}
+
+	var eventsMu sync.Mutex
+	var events []ReconnectEventType
+	tr, err := newReconnectingTransport(ctx, dial, ReconnectConfig{
+		MinBackoff: time.Millisecond,
+		OnEvent: func(e ReconnectEvent) {
+			eventsMu.Lock()
+			events = append(events, e.Type)
+			eventsMu.Unlock()
+		},
+	})
+	require.NoError(t, err)
+
+	first := <-backends
+	go func() {
+		r := first.next()
+		require.Equal(t, "eth_subscribe", r.Method)
+		first.respond(r, "0xaaaa")
+	}()
+
+	sub, err := tr.Subscribe(ctx, jsonrpc.MustRequest(1, "eth_subscribe", "newHeads"))
+	require.NoError(t, err)
+	require.Equal(t, "0xaaaa", sub.ID())
+
+	notify := func(b *pipeBackend, id string, result string) {
+		b.send(&jsonrpc.Notification{
+			Method: "eth_subscription",
+			Params: []byte(`{"subscription":"` + id + `","result":"` + result + `"}`),
+		})
+	}
+
+	notify(first, "0xaaaa", "one")
+	n := <-sub.Ch()
+	require.JSONEq(t, `{"subscription":"0xaaaa","result":"one"}`, string(n.Params))
+
+	// drop the connection, the transport should redial and re-issue the subscription
+	require.NoError(t, first.conn.Close())
+
+	second := <-backends
+	r := second.next()
+	require.Equal(t, "eth_subscribe", r.Method)
+	second.respond(r, "0xbbbb")
+
+	notify(second, "0xbbbb", "two")
+	n = <-sub.Ch()
+	require.JSONEq(t, `{"subscription":"0xaaaa","result":"two"}`, string(n.Params))
+	require.Equal(t, "0xaaaa", sub.ID())
+
+	// requests flow over the new connection
+	go func() {
+		r := second.next()
+		second.respond(r, "0x10")
+	}()
+
+	response, err := tr.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.NoError(t, err)
+	require.Equal(t, `"0x10"`, string(response.Result))
+
+	eventsMu.Lock()
+	require.Equal(t, []ReconnectEventType{ReconnectEventDisconnected, ReconnectEventReconnected}, events)
+	eventsMu.Unlock()
+
+	// unsubscribing sends the current backend ID and closes the stable channel
+	go func() {
+		r := second.next()
+		require.Equal(t, "eth_unsubscribe", r.Method)
+		require.Equal(t, `"0xbbbb"`, string(r.Params[0]))
+		second.respond(r, true)
+	}()
+
+	require.NoError(t, sub.Unsubscribe(ctx))
+	requireTerminated(t, sub, TerminationUnsubscribed)
+}
+
+func TestReconnectingTransport_GiveUp(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	dialed := false
+	var first *pipeBackend
+	dial := func(ctx context.Context) (*loopingTransport, error) {
+		if dialed {
+			return nil, errors.New("connection refused")
+		}
+
+		dialed = true
+		conn, backend := newPipeTransport(t, ctx)
+		first = backend
+		return conn, nil
+	}
+
+	gaveUp := make(chan ReconnectEvent, 1)
+	tr, err := newReconnectingTransport(ctx, dial, ReconnectConfig{
+		MinBackoff:  time.Millisecond,
+		MaxAttempts: 3,
+		OnEvent: func(e ReconnectEvent) {
+			if e.Type == ReconnectEventGaveUp {
+				gaveUp <- e
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	require.NoError(t, first.conn.Close())
+
+	e := <-gaveUp
+	require.Equal(t, 3, e.Attempt)
+
+	_, err = tr.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "connection refused")
+}
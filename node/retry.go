@@ -0,0 +1,311 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+const (
+	defaultRetryAttempts   = 3
+	defaultMinRetryBackoff = 100 * time.Millisecond
+	defaultMaxRetryBackoff = 5 * time.Second
+)
+
+// nonIdempotentMethods change state on the backend, so sending them twice is not the same as sending them once
+var nonIdempotentMethods = map[string]bool{
+	"eth_sendRawTransaction":            true,
+	"eth_sendRawTransactionConditional": true,
+	"eth_sendTransaction":               true,
+	"personal_sendTransaction":          true,
+	"eth_submitWork":                    true,
+	"eth_submitHashrate":                true,
+	"eth_subscribe":                     true,
+	"eth_unsubscribe":                   true,
+	"eth_newFilter":                     true,
+	"eth_newBlockFilter":                true,
+	"eth_newPendingTransactionFilter":   true,
+	"eth_getFilterChanges":              true,
+	"eth_uninstallFilter":               true,
+}
+
+// IsIdempotent returns true if sending a request for method more than once has the same effect as sending it once,
+// which is the case for reads such as eth_getBlockByNumber but not for eth_sendRawTransaction or the filter methods.
+func IsIdempotent(method string) bool {
+	return !nonIdempotentMethods[method]
+}
+
+// RetryPolicy controls which failed requests a retrying Requester sends again, and when.
+//
+// Requests that the backend rejected with a 429 status are always retried.  Transport errors, temporary HTTP
+// errors, -32005 limit exceeded errors and the RetryableCodes may have happened after the backend acted on the
+// request, so they are only retried for idempotent methods.
+type RetryPolicy struct {
+	// MaxAttempts is the most times a request is sent, including the first.  Defaults to 3.
+	MaxAttempts int
+
+	// MinBackoff is the wait before the first retry, which doubles with every attempt up to MaxBackoff.
+	// Each wait is jittered to between half and all of the backoff.  Defaults to 100ms and 5s.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// RetryableCodes are the JSON-RPC error codes worth retrying for idempotent methods.  Defaults to
+	// resource unavailable and internal error.
+	RetryableCodes []jsonrpc.ErrorCode
+
+	// Idempotent reports whether a method is safe to send again.  Defaults to IsIdempotent.
+	Idempotent func(method string) bool
+
+	// Budget, if set, limits retries across every request that shares it.
+	Budget *RetryBudget
+}
+
+// RetryBudget throttles retries when most requests are failing, so that retries don't make an overloaded
+// backend worse.  Every retryable failure costs a token and every success earns back a fraction of one,
+// and retries are only allowed while more than half of the tokens are left.
+type RetryBudget struct {
+	max    float64
+	ratio  float64
+	tokens float64
+	mu     sync.Mutex
+}
+
+// NewRetryBudget returns a full RetryBudget holding maxTokens, where each success earns ratio tokens.
+func NewRetryBudget(maxTokens int, ratio float64) *RetryBudget {
+	return &RetryBudget{
+		max:    float64(maxTokens),
+		ratio:  ratio,
+		tokens: float64(maxTokens),
+	}
+}
+
+func (b *RetryBudget) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += b.ratio
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+}
+
+// failure records a retryable failure and returns true if the budget still allows retrying it
+func (b *RetryBudget) failure() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens--
+	if b.tokens < 0 {
+		b.tokens = 0
+	}
+
+	return b.tokens > b.max/2
+}
+
+// NewRetryingRequester wraps requester so that failed requests are retried according to policy.  If requester
+// is also a BatchRequester so is the result, and batches are retried as a whole when they fail outright and every
+// request in them is idempotent.  To retry the requests of a client, pass the result to NewCustomClient along with
+// the client as its Subscriber.
+func NewRetryingRequester(requester Requester, policy RetryPolicy) Requester {
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaultRetryAttempts
+	}
+
+	if policy.MinBackoff <= 0 {
+		policy.MinBackoff = defaultMinRetryBackoff
+	}
+
+	if policy.MaxBackoff < policy.MinBackoff {
+		policy.MaxBackoff = defaultMaxRetryBackoff
+		if policy.MaxBackoff < policy.MinBackoff {
+			policy.MaxBackoff = policy.MinBackoff
+		}
+	}
+
+	if policy.RetryableCodes == nil {
+		policy.RetryableCodes = []jsonrpc.ErrorCode{jsonrpc.ErrCodeResourceUnavailable, jsonrpc.ErrCodeInternalError}
+	}
+
+	if policy.Idempotent == nil {
+		policy.Idempotent = IsIdempotent
+	}
+
+	r := retryingRequester{
+		requester: requester,
+		policy:    policy,
+	}
+
+	if _, ok := requester.(BatchRequester); ok {
+		return &retryingBatchRequester{&r}
+	}
+
+	return &r
+}
+
+type retryingRequester struct {
+	requester Requester
+	policy    RetryPolicy
+}
+
+type retryingBatchRequester struct {
+	*retryingRequester
+}
+
+// retryClass says under which circumstances a failed request may be sent again
+type retryClass int
+
+const (
+	retryNever retryClass = iota
+	retryIfIdempotent
+	retryAlways
+)
+
+func (r *retryingRequester) Request(ctx context.Context, request *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	idempotent := r.policy.Idempotent(request.Method)
+
+	var response *jsonrpc.RawResponse
+	var err error
+	for attempt := 1; ; attempt++ {
+		response, err = r.requester.Request(ctx, request)
+		if !r.retry(ctx, attempt, r.classify(response, err), idempotent, err) {
+			return response, err
+		}
+	}
+}
+
+func (r *retryingBatchRequester) RequestBatch(ctx context.Context, batch jsonrpc.BatchRequest) (jsonrpc.BatchRawResponse, error) {
+	requester := r.requester.(BatchRequester)
+
+	idempotent := true
+	for _, request := range batch {
+		if request != nil && !r.policy.Idempotent(request.Method) {
+			idempotent = false
+		}
+	}
+
+	var responses jsonrpc.BatchRawResponse
+	var err error
+	for attempt := 1; ; attempt++ {
+		responses, err = requester.RequestBatch(ctx, batch)
+		if err == ErrEmptyBatch {
+			return responses, err
+		}
+
+		class := retryNever
+		if err != nil {
+			class = r.classify(nil, err)
+		}
+
+		if !r.retry(ctx, attempt, class, idempotent, err) {
+			return responses, err
+		}
+	}
+}
+
+// retry decides if another attempt should be made after the given one, and waits for it if so
+func (r *retryingRequester) retry(ctx context.Context, attempt int, class retryClass, idempotent bool, err error) bool {
+	if class == retryNever {
+		if err == nil && r.policy.Budget != nil {
+			r.policy.Budget.success()
+		}
+		return false
+	}
+
+	if class == retryIfIdempotent && !idempotent {
+		return false
+	}
+
+	if r.policy.Budget != nil && !r.policy.Budget.failure() {
+		return false
+	}
+
+	if attempt >= r.policy.MaxAttempts {
+		return false
+	}
+
+	wait := r.backoff(attempt)
+	if e, ok := errors.Cause(err).(*HTTPError); ok && e.RetryAfter > wait {
+		wait = e.RetryAfter
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+		// we'd run out of time before the next attempt, so give up now with the error we have
+		return false
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// backoff returns how long to wait after the given attempt
+func (r *retryingRequester) backoff(attempt int) time.Duration {
+	backoff := r.policy.MinBackoff
+	for i := 1; i < attempt && backoff < r.policy.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > r.policy.MaxBackoff {
+		backoff = r.policy.MaxBackoff
+	}
+
+	half := backoff / 2
+	return half + time.Duration(rand.Int63n(int64(backoff-half)+1))
+}
+
+func (r *retryingRequester) classify(response *jsonrpc.RawResponse, err error) retryClass {
+	if err != nil {
+		cause := errors.Cause(err)
+		if cause == context.Canceled || cause == context.DeadlineExceeded {
+			return retryNever
+		}
+
+		if e, ok := cause.(*HTTPError); ok {
+			switch {
+			case e.IsRateLimited():
+				return retryAlways
+			case e.Temporary():
+				return retryIfIdempotent
+			default:
+				return retryNever
+			}
+		}
+
+		// the connection failed, and we can't know if the backend saw the request before it did
+		return retryIfIdempotent
+	}
+
+	if response == nil || response.Error == nil {
+		return retryNever
+	}
+
+	e := jsonrpc.Error{}
+	if json.Unmarshal(*response.Error, &e) != nil {
+		return retryNever
+	}
+
+	if e.Code == jsonrpc.ErrCodeLimitExceeded {
+		// backends also use this for limits hit while running a request, so it may well have been acted on
+		return retryIfIdempotent
+	}
+
+	for _, code := range r.policy.RetryableCodes {
+		if e.Code == code {
+			return retryIfIdempotent
+		}
+	}
+
+	return retryNever
+}
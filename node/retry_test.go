@@ -0,0 +1,209 @@
+package node
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// scriptedRequester fails the first len(failures) requests with the scripted failure, then succeeds
+type scriptedRequester struct {
+	mu       sync.Mutex
+	failures []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error)
+	calls    int
+}
+
+func (s *scriptedRequester) Request(ctx context.Context, r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.calls <= len(s.failures) {
+		return s.failures[s.calls-1](r)
+	}
+
+	return &jsonrpc.RawResponse{ID: r.ID, Result: json.RawMessage(`"0x10"`)}, nil
+}
+
+func failWith(err error) func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	return func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		return nil, err
+	}
+}
+
+func failWithCode(code jsonrpc.ErrorCode) func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+	return func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error) {
+		b, err := json.Marshal(jsonrpc.NewError(code, "failed"))
+		if err != nil {
+			return nil, err
+		}
+		raw := json.RawMessage(b)
+		return &jsonrpc.RawResponse{ID: r.ID, Error: &raw}, nil
+	}
+}
+
+func TestRetryingRequester(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, MinBackoff: time.Millisecond, MaxBackoff: 2 * time.Millisecond}
+
+	tests := []struct {
+		name     string
+		method   string
+		failures []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error)
+		calls    int
+		success  bool
+	}{
+		{
+			name:     "transport error on a read",
+			method:   "eth_getBlockByNumber",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(io.EOF), failWith(&HTTPError{StatusCode: 503})},
+			calls:    3,
+			success:  true,
+		},
+		{
+			name:     "transport error on a send",
+			method:   "eth_sendRawTransaction",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(io.EOF)},
+			calls:    1,
+		},
+		{
+			name:     "rate limited send",
+			method:   "eth_sendRawTransaction",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(errors.Wrap(&HTTPError{StatusCode: 429}, "could not dispatch request"))},
+			calls:    2,
+			success:  true,
+		},
+		{
+			name:     "limit exceeded on a read",
+			method:   "eth_getLogs",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWithCode(jsonrpc.ErrCodeLimitExceeded)},
+			calls:    2,
+			success:  true,
+		},
+		{
+			name:     "limit exceeded on a send",
+			method:   "eth_sendRawTransaction",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWithCode(jsonrpc.ErrCodeLimitExceeded)},
+			calls:    1,
+		},
+		{
+			name:     "internal error on a read",
+			method:   "eth_call",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWithCode(jsonrpc.ErrCodeInternalError)},
+			calls:    2,
+			success:  true,
+		},
+		{
+			name:     "invalid params",
+			method:   "eth_call",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWithCode(jsonrpc.ErrCodeInvalidParams)},
+			calls:    1,
+		},
+		{
+			name:     "bad request",
+			method:   "eth_call",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(&HTTPError{StatusCode: 400})},
+			calls:    1,
+		},
+		{
+			name:     "gives up",
+			method:   "eth_blockNumber",
+			failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(io.EOF), failWith(io.EOF), failWith(io.EOF), failWith(io.EOF)},
+			calls:    3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &scriptedRequester{failures: tt.failures}
+			r := NewRetryingRequester(s, policy)
+
+			response, err := r.Request(ctx, jsonrpc.MustRequest(1, tt.method))
+			require.Equal(t, tt.calls, s.calls)
+			if tt.success {
+				require.NoError(t, err)
+				require.Nil(t, response.Error)
+			} else {
+				require.True(t, err != nil || response.Error != nil)
+			}
+		})
+	}
+}
+
+func TestRetryingRequester_Budget(t *testing.T) {
+	ctx := context.Background()
+	budget := NewRetryBudget(4, 1)
+	policy := RetryPolicy{MaxAttempts: 10, MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Budget: budget}
+
+	failures := make([]func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error), 10)
+	for i := range failures {
+		failures[i] = failWith(io.EOF)
+	}
+
+	// the budget allows one retry before dropping to half
+	s := &scriptedRequester{failures: failures}
+	_, err := NewRetryingRequester(s, policy).Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.Error(t, err)
+	require.Equal(t, 2, s.calls)
+
+	// successes refill it
+	for i := 0; i < 4; i++ {
+		budget.success()
+	}
+	s = &scriptedRequester{failures: failures[:1]}
+	_, err = NewRetryingRequester(s, policy).Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.NoError(t, err)
+	require.Equal(t, 2, s.calls)
+}
+
+func TestRetryingRequester_Context(t *testing.T) {
+	s := &scriptedRequester{failures: []func(r *jsonrpc.Request) (*jsonrpc.RawResponse, error){failWith(&HTTPError{StatusCode: 429, RetryAfter: time.Minute})}}
+	r := NewRetryingRequester(s, RetryPolicy{})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// waiting for Retry-After would outlive the deadline, so it fails right away
+	_, err := r.Request(ctx, jsonrpc.MustRequest(1, "eth_blockNumber"))
+	require.True(t, IsRateLimited(err))
+	require.Equal(t, 1, s.calls)
+}
+
+func TestRetryingRequester_Batch(t *testing.T) {
+	_, ok := NewRetryingRequester(&scriptedRequester{}, RetryPolicy{}).(BatchRequester)
+	require.False(t, ok)
+
+	calls := 0
+	tr := newTestHTTPTransport(t, func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write([]byte(`[{"jsonrpc":"2.0","id":0,"result":"0x1"},{"jsonrpc":"2.0","id":1,"result":"0x2"},{"jsonrpc":"2.0","id":2,"result":"0x3"}]`))
+	})
+
+	r, ok := NewRetryingRequester(tr, RetryPolicy{MinBackoff: time.Millisecond}).(BatchRequester)
+	require.True(t, ok)
+
+	responses, err := r.RequestBatch(context.Background(), newTestBatch())
+	require.NoError(t, err)
+	require.Len(t, responses, 3)
+	require.Equal(t, 2, calls)
+
+	// a batch with a send in it is not retried
+	calls = 0
+	batch := append(newTestBatch(), jsonrpc.MustRequest(1, "eth_sendRawTransaction", "0x00"))
+	_, err = r.RequestBatch(context.Background(), batch)
+	require.True(t, IsUpstreamError(err))
+	require.Equal(t, 1, calls)
+}
@@ -3,12 +3,67 @@ package node
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 
 	"github.com/pkg/errors"
 
 	"github.com/justinwongcn/go-ethlibs/jsonrpc"
 )
 
+// TerminationReason describes why a Subscription stopped delivering notifications.
+type TerminationReason int
+
+const (
+	// TerminationUnsubscribed means the subscription was ended by eth_unsubscribe
+	TerminationUnsubscribed TerminationReason = iota + 1
+	// TerminationConnectionLost means the connection to the backend failed
+	TerminationConnectionLost
+	// TerminationClosed means the client was shut down by finishing its context
+	TerminationClosed
+	// TerminationBackend means the backend ended the subscription while the connection stayed up
+	TerminationBackend
+	// TerminationDecodeFailed means a typed subscription received a notification it could not decode
+	TerminationDecodeFailed
+)
+
+func (r TerminationReason) String() string {
+	switch r {
+	case TerminationUnsubscribed:
+		return "unsubscribed"
+	case TerminationConnectionLost:
+		return "connection lost"
+	case TerminationClosed:
+		return "client closed"
+	case TerminationBackend:
+		return "terminated by backend"
+	case TerminationDecodeFailed:
+		return "notification decoding failed"
+	default:
+		return "unknown"
+	}
+}
+
+// SubscriptionError is returned by Subscription.Err once a subscription has terminated.
+type SubscriptionError struct {
+	Reason TerminationReason
+	// Err is the underlying cause, if there is one
+	Err error
+}
+
+func (e *SubscriptionError) Error() string {
+	if e.Err == nil {
+		return fmt.Sprintf("subscription %s", e.Reason)
+	}
+
+	return fmt.Sprintf("subscription %s: %s", e.Reason, e.Err.Error())
+}
+
+// Cause returns the underlying cause so SubscriptionError works with errors.Cause
+func (e *SubscriptionError) Cause() error {
+	return e.Err
+}
+
 type subscription struct {
 	response        *jsonrpc.RawResponse
 	subscriptionID  string
@@ -16,7 +71,11 @@ type subscription struct {
 	dispatchCh      chan *jsonrpc.Notification
 	signalCh        chan struct{}
 	stoppedCh       chan struct{}
+	doneCh          chan struct{}
 	conn            Requester
+
+	err   *SubscriptionError
+	errMu sync.RWMutex
 }
 
 func (s *subscription) Response() *jsonrpc.RawResponse {
@@ -31,9 +90,29 @@ func (s *subscription) Ch() <-chan *jsonrpc.Notification {
 	return s.notificationsCh
 }
 
+// Done returns a channel that is closed once the subscription has terminated and Ch() has been closed.
+func (s *subscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// Err returns nil while the subscription is active, and a *SubscriptionError once it has terminated.
+func (s *subscription) Err() error {
+	s.errMu.RLock()
+	defer s.errMu.RUnlock()
+
+	if s.err == nil {
+		return nil
+	}
+
+	return s.err
+}
+
 type SubscriptionParams struct {
 	Subscription string          `json:"subscription"`
 	Result       json.RawMessage `json:"result"`
+
+	// Error is set by backends that end a subscription with an error notification
+	Error *json.RawMessage `json:"error,omitempty"`
 }
 
 func (s *subscription) Unsubscribe(ctx context.Context) error {
@@ -65,6 +144,7 @@ func newSubscription(response *jsonrpc.RawResponse, id string, r Requester) *sub
 		dispatchCh:      make(chan *jsonrpc.Notification),
 		signalCh:        make(chan struct{}),
 		stoppedCh:       make(chan struct{}),
+		doneCh:          make(chan struct{}),
 		conn:            r,
 	}
 
@@ -82,6 +162,9 @@ func newSubscription(response *jsonrpc.RawResponse, id string, r Requester) *sub
 			// then close the notifications channel so any consumers of
 			// subscription.Ch() are unblocked
 			close(s.notificationsCh)
+
+			// and finally signal consumers of subscription.Done(), by which point .Err() is set
+			close(s.doneCh)
 		}()
 
 		for {
@@ -106,7 +189,8 @@ func newSubscription(response *jsonrpc.RawResponse, id string, r Requester) *sub
 	return &s
 }
 
-func (s *subscription) dispatch(ctx context.Context, n jsonrpc.Notification) {
+// dispatch queues n for delivery on Ch(), returning false if it was abandoned instead
+func (s *subscription) dispatch(ctx context.Context, n jsonrpc.Notification) bool {
 	// we've been given a notification that needs to be dispatched to notificationsCh
 	// however we can't do it directly here, since we only want one goroutine sending
 	// notifications on this channel, so that it can be stopped cleanly.
@@ -120,17 +204,28 @@ func (s *subscription) dispatch(ctx context.Context, n jsonrpc.Notification) {
 	case <-ctx.Done():
 		// the parent context .dispatch was called ended, we can go ahead and give up
 		// on dispatching this notification
-		return
+		return false
 	case s.dispatchCh <- &n:
 		// the notification is now in the internal dispatch channel and will be processed
 		// by the goroutine created in newSubscription above
-		return
+		return true
 	case <-s.stoppedCh:
 		// this subscription has been stopped so we can abandon any writes to it
-		return
+		return false
 	}
 }
 
+// terminate records why the subscription is ending and then stops it.  Only the first reason is kept.
+func (s *subscription) terminate(ctx context.Context, reason TerminationReason, cause error) {
+	s.errMu.Lock()
+	if s.err == nil {
+		s.err = &SubscriptionError{Reason: reason, Err: cause}
+	}
+	s.errMu.Unlock()
+
+	s.stop(ctx)
+}
+
 func (s *subscription) stop(ctx context.Context) {
 	select {
 	case <-ctx.Done():
@@ -0,0 +1,90 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func subscribeOverPipe(t *testing.T, ctx context.Context, tr *loopingTransport, backend *pipeBackend, id string) Subscription {
+	go func() {
+		r := backend.next()
+		backend.respond(r, id)
+	}()
+
+	sub, err := tr.Subscribe(ctx, jsonrpc.MustRequest(1, "eth_subscribe", "newHeads"))
+	require.NoError(t, err)
+	require.Nil(t, sub.Err())
+	return sub
+}
+
+func requireTerminated(t *testing.T, sub Subscription, reason TerminationReason) *SubscriptionError {
+	<-sub.Done()
+
+	_, ok := <-sub.Ch()
+	require.False(t, ok, "notifications channel should be closed once done")
+
+	err, ok := sub.Err().(*SubscriptionError)
+	require.True(t, ok, "Err() should return a *SubscriptionError")
+	require.Equal(t, reason, err.Reason)
+	return err
+}
+
+func TestSubscription_Termination(t *testing.T) {
+	t.Run("unsubscribed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tr, backend := newPipeTransport(t, ctx)
+		sub := subscribeOverPipe(t, ctx, tr, backend, "0x1")
+
+		go func() {
+			r := backend.next()
+			backend.respond(r, true)
+		}()
+
+		require.NoError(t, sub.Unsubscribe(ctx))
+		err := requireTerminated(t, sub, TerminationUnsubscribed)
+		require.Nil(t, err.Err)
+	})
+
+	t.Run("backend", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tr, backend := newPipeTransport(t, ctx)
+		sub := subscribeOverPipe(t, ctx, tr, backend, "0x1")
+
+		backend.send(&jsonrpc.Notification{
+			Method: "eth_subscription",
+			Params: []byte(`{"subscription":"0x1","error":{"code":-32000,"message":"subscription limit reached"}}`),
+		})
+
+		err := requireTerminated(t, sub, TerminationBackend)
+		require.Contains(t, err.Error(), "subscription limit reached")
+	})
+
+	t.Run("connection lost", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		tr, backend := newPipeTransport(t, ctx)
+		sub := subscribeOverPipe(t, ctx, tr, backend, "0x1")
+
+		require.NoError(t, backend.conn.Close())
+		err := requireTerminated(t, sub, TerminationConnectionLost)
+		require.NotNil(t, err.Err)
+	})
+
+	t.Run("closed", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		tr, backend := newPipeTransport(t, ctx)
+		sub := subscribeOverPipe(t, ctx, tr, backend, "0x1")
+
+		cancel()
+		requireTerminated(t, sub, TerminationClosed)
+	})
+}
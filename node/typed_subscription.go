@@ -0,0 +1,214 @@
+package node
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+// typedUnsubscribeTimeout bounds how long a typed subscription waits to unsubscribe after failing to decode a notification
+const typedUnsubscribeTimeout = 10 * time.Second
+
+// typedSubscription decodes the notifications of a raw Subscription in its own goroutine.  If a notification
+// can't be decoded the raw subscription is unsubscribed and Err() reports a TerminationDecodeFailed error.
+type typedSubscription struct {
+	sub    Subscription
+	doneCh chan struct{}
+	err    error
+}
+
+// notificationDecoder decodes the params of a notification and returns the result to deliver
+type notificationDecoder func(n *jsonrpc.Notification) (interface{}, error)
+
+func newTypedSubscription(sub Subscription) typedSubscription {
+	return typedSubscription{
+		sub:    sub,
+		doneCh: make(chan struct{}),
+	}
+}
+
+func (s *typedSubscription) Response() *jsonrpc.RawResponse {
+	return s.sub.Response()
+}
+
+func (s *typedSubscription) ID() string {
+	return s.sub.ID()
+}
+
+func (s *typedSubscription) Unsubscribe(ctx context.Context) error {
+	return s.sub.Unsubscribe(ctx)
+}
+
+// Done returns a channel that is closed once the subscription has terminated and Ch() has been closed.
+func (s *typedSubscription) Done() <-chan struct{} {
+	return s.doneCh
+}
+
+// Err returns nil while the subscription is active, and a *SubscriptionError once it has terminated.
+func (s *typedSubscription) Err() error {
+	select {
+	case <-s.doneCh:
+		if s.err != nil {
+			return s.err
+		}
+		return s.sub.Err()
+	default:
+		return nil
+	}
+}
+
+// run sends each decoded notification on ch, a channel of the type decode returns, until the raw subscription
+// ends or a notification can't be decoded, and then closes ch before Done() is closed.  A send nobody reads is
+// abandoned once the raw subscription is done.
+func (s *typedSubscription) run(kind string, typed interface{}, decode notificationDecoder) {
+	ch := reflect.ValueOf(typed)
+	defer func() {
+		ch.Close()
+		close(s.doneCh)
+	}()
+
+	for n := range s.sub.Ch() {
+		result, err := decode(n)
+		if err != nil {
+			s.err = &SubscriptionError{Reason: TerminationDecodeFailed, Err: errors.Wrapf(err, "could not decode %s notification", kind)}
+
+			// nobody is reading the raw notifications anymore, so the backend should stop sending them
+			ctx, cancel := context.WithTimeout(context.Background(), typedUnsubscribeTimeout)
+			_ = s.sub.Unsubscribe(ctx)
+			cancel()
+			return
+		}
+
+		reflect.Select([]reflect.SelectCase{
+			{Dir: reflect.SelectSend, Chan: ch, Send: reflect.ValueOf(result)},
+			{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(s.sub.Done())},
+		})
+	}
+}
+
+// NewHeadsSubscription delivers the decoded results of a newHeads subscription.
+type NewHeadsSubscription struct {
+	typedSubscription
+	ch chan *eth.NewHeadsResult
+}
+
+// WrapNewHeads decodes the notifications of a subscription created with SubscribeNewHeads.
+func WrapNewHeads(sub Subscription) *NewHeadsSubscription {
+	s := &NewHeadsSubscription{
+		typedSubscription: newTypedSubscription(sub),
+		ch:                make(chan *eth.NewHeadsResult),
+	}
+
+	go s.run("newHeads", s.ch, func(n *jsonrpc.Notification) (interface{}, error) {
+		params := eth.NewHeadsNotificationParams{}
+		return &params.Result, n.UnmarshalParamsInto(&params)
+	})
+	return s
+}
+
+func (s *NewHeadsSubscription) Ch() <-chan *eth.NewHeadsResult {
+	return s.ch
+}
+
+// LogsSubscription delivers the decoded results of a logs subscription.
+type LogsSubscription struct {
+	typedSubscription
+	ch chan eth.Log
+}
+
+// WrapLogs decodes the notifications of a subscription created with SubscribeLogs.
+func WrapLogs(sub Subscription) *LogsSubscription {
+	s := &LogsSubscription{
+		typedSubscription: newTypedSubscription(sub),
+		ch:                make(chan eth.Log),
+	}
+
+	go s.run("logs", s.ch, func(n *jsonrpc.Notification) (interface{}, error) {
+		params := eth.LogNotificationParams{}
+		err := n.UnmarshalParamsInto(&params)
+		return params.Result, err
+	})
+	return s
+}
+
+func (s *LogsSubscription) Ch() <-chan eth.Log {
+	return s.ch
+}
+
+// PendingTransactionsSubscription delivers the transaction hashes of a newPendingTransactions subscription.
+type PendingTransactionsSubscription struct {
+	typedSubscription
+	ch chan eth.Hash
+}
+
+// WrapNewPendingTransactions decodes the notifications of a subscription created with SubscribeNewPendingTransactions.
+func WrapNewPendingTransactions(sub Subscription) *PendingTransactionsSubscription {
+	s := &PendingTransactionsSubscription{
+		typedSubscription: newTypedSubscription(sub),
+		ch:                make(chan eth.Hash),
+	}
+
+	go s.run("newPendingTransactions", s.ch, func(n *jsonrpc.Notification) (interface{}, error) {
+		params := eth.NewPendingTxNotificationParams{}
+		err := n.UnmarshalParamsInto(&params)
+		return params.Result, err
+	})
+	return s
+}
+
+func (s *PendingTransactionsSubscription) Ch() <-chan eth.Hash {
+	return s.ch
+}
+
+// PendingTransactionBodiesSubscription delivers the full transactions of a newPendingTransactions subscription.
+type PendingTransactionBodiesSubscription struct {
+	typedSubscription
+	ch chan *eth.Transaction
+}
+
+// WrapNewPendingTransactionBodies decodes the notifications of a subscription created with SubscribeNewPendingTransactionBodies.
+func WrapNewPendingTransactionBodies(sub Subscription) *PendingTransactionBodiesSubscription {
+	s := &PendingTransactionBodiesSubscription{
+		typedSubscription: newTypedSubscription(sub),
+		ch:                make(chan *eth.Transaction),
+	}
+
+	go s.run("newPendingTransactions", s.ch, func(n *jsonrpc.Notification) (interface{}, error) {
+		params := eth.NewPendingTxBodyNotificationParams{}
+		return &params.Result, n.UnmarshalParamsInto(&params)
+	})
+	return s
+}
+
+func (s *PendingTransactionBodiesSubscription) Ch() <-chan *eth.Transaction {
+	return s.ch
+}
+
+// SyncingSubscription delivers the decoded results of a syncing subscription.
+type SyncingSubscription struct {
+	typedSubscription
+	ch chan *eth.SyncStatus
+}
+
+// WrapSyncing decodes the notifications of a subscription created with SubscribeSyncing.
+func WrapSyncing(sub Subscription) *SyncingSubscription {
+	s := &SyncingSubscription{
+		typedSubscription: newTypedSubscription(sub),
+		ch:                make(chan *eth.SyncStatus),
+	}
+
+	go s.run("syncing", s.ch, func(n *jsonrpc.Notification) (interface{}, error) {
+		params := eth.SyncingNotificationParams{}
+		return &params.Result, n.UnmarshalParamsInto(&params)
+	})
+	return s
+}
+
+func (s *SyncingSubscription) Ch() <-chan *eth.SyncStatus {
+	return s.ch
+}
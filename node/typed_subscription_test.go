@@ -0,0 +1,100 @@
+package node
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+)
+
+func TestClient_SubscribeLogs(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr, backend := newPipeTransport(t, ctx)
+	c := &client{transport: tr}
+
+	go func() {
+		r := backend.next()
+		require.Equal(t, "eth_subscribe", r.Method)
+		require.JSONEq(t, `"logs"`, string(r.Params[0]))
+		require.JSONEq(t, `{"address":["0x8b406b4708a45f115347fc2d020735196f994c5f"]}`, string(r.Params[1]))
+		backend.respond(r, "0x1")
+	}()
+
+	sub, err := c.SubscribeLogs(ctx, eth.LogFilter{Address: []eth.Address{*eth.MustAddress("0x8b406b4708a45f115347fc2d020735196f994c5f")}})
+	require.NoError(t, err)
+
+	logs := WrapLogs(sub)
+	require.Equal(t, "0x1", logs.ID())
+
+	// a block range is refused before anything is sent
+	_, err = c.SubscribeLogs(ctx, eth.LogFilter{FromBlock: eth.MustBlockNumberOrTag("0x1")})
+	require.Equal(t, ErrLogsSubscriptionRange, err)
+
+	backend.send(&jsonrpc.Notification{
+		Method: "eth_subscription",
+		Params: []byte(`{"subscription":"0x1","result":{"address":"0x8b406b4708a45f115347fc2d020735196f994c5f","blockHash":"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd","blockNumber":"0xa4c6b2","data":"0x","logIndex":"0x0","removed":false,"topics":[],"transactionHash":"0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb","transactionIndex":"0xa"}}`),
+	})
+
+	l := <-logs.Ch()
+	require.Equal(t, uint64(0xa4c6b2), l.BlockNumber.UInt64())
+	require.Equal(t, *eth.MustAddress("0x8b406b4708a45f115347fc2d020735196f994c5f"), l.Address)
+
+	// a notification that can't be decoded ends the typed subscription and unsubscribes
+	go func() {
+		r := backend.next()
+		require.Equal(t, "eth_unsubscribe", r.Method)
+		backend.respond(r, true)
+	}()
+
+	backend.send(&jsonrpc.Notification{
+		Method: "eth_subscription",
+		Params: []byte(`{"subscription":"0x1","result":{"blockNumber":"not a number"}}`),
+	})
+
+	<-logs.Done()
+	_, ok := <-logs.Ch()
+	require.False(t, ok)
+
+	serr, ok := logs.Err().(*SubscriptionError)
+	require.True(t, ok)
+	require.Equal(t, TerminationDecodeFailed, serr.Reason)
+}
+
+func TestClient_SubscribeNewHeads_Typed(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tr, backend := newPipeTransport(t, ctx)
+	c := &client{transport: tr}
+
+	go func() {
+		r := backend.next()
+		backend.respond(r, "0x2")
+	}()
+
+	sub, err := c.SubscribeNewHeads(ctx)
+	require.NoError(t, err)
+
+	heads := WrapNewHeads(sub)
+	backend.send(&jsonrpc.Notification{
+		Method: "eth_subscription",
+		Params: []byte(`{"subscription":"0x2","result":{"number":"0x10","hash":"0x2cdf35a15eaab70f694b1ef15b6375793848336e00b76b0551082b1fb6130ccd","parentHash":"0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb"}}`),
+	})
+
+	head := <-heads.Ch()
+	require.Equal(t, uint64(0x10), head.Number.UInt64())
+	require.Equal(t, "0x9cd71724c1bad4c8e09a52b5bc1d8f037d5c08f4b78626236110ce5e6e1e8cfb", head.ParentHash.String())
+
+	// terminating the raw subscription ends the typed one with the same reason
+	require.NoError(t, backend.conn.Close())
+	<-heads.Done()
+
+	serr, ok := heads.Err().(*SubscriptionError)
+	require.True(t, ok)
+	require.Equal(t, TerminationConnectionLost, serr.Reason)
+}
@@ -15,8 +15,21 @@ type websocketTransport struct {
 
 // newWebsocketTransport creates a Connection to the passed in URL.  Use the supplied Context to shutdown the connection by
 // cancelling or otherwise aborting the context.
-func newWebsocketTransport(ctx context.Context, addr *url.URL) (transport, error) {
-	wsConn, _, err := websocket.DefaultDialer.DialContext(ctx, addr.String(), nil)
+func newWebsocketTransport(ctx context.Context, addr *url.URL, opts *clientOptions) (*websocketTransport, error) {
+	dialer := opts.dialer
+	if dialer == nil {
+		dialer = websocket.DefaultDialer
+	}
+
+	header, err := opts.header()
+	if err != nil {
+		return nil, err
+	}
+
+	dialCtx, cancel := opts.dialContext(ctx)
+	defer cancel()
+
+	wsConn, _, err := dialer.DialContext(dialCtx, addr.String(), header)
 	if err != nil {
 		return nil, err
 	}
@@ -45,7 +58,7 @@ func newWebsocketTransport(ctx context.Context, addr *url.URL) (transport, error
 	}
 
 	t := websocketTransport{
-		loopingTransport: newLoopingTransport(ctx, wsConn, readMessage, writeMessage),
+		loopingTransport: newLoopingTransport(ctx, wsConn, readMessage, writeMessage, opts.observer(), opts.loggerFor(addr.String())),
 	}
 
 	return &t, nil
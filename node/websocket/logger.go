@@ -0,0 +1,10 @@
+package websocket
+
+// Logger receives the messages a connection logs, followed by alternating keys and values.  It has the same
+// methods as node.Logger, so the same loggers work with both.
+type Logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+	Error(msg string, args ...interface{})
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/justinwongcn/go-ethlibs/eth"
 	"github.com/justinwongcn/go-ethlibs/jsonrpc"
+	"github.com/justinwongcn/go-ethlibs/node/internal/logging"
 )
 
 // Connection represents a websocket connection to a backend ethereum client node.
@@ -68,9 +69,13 @@ var (
 )
 
 type connection struct {
-	url  string
-	conn *websocket.Conn
-	ctx  context.Context
+	url    string
+	conn   *websocket.Conn
+	ctx    context.Context
+	logger Logger
+
+	// loggedURL is url without its password, if it has one, for log messages
+	loggedURL string
 
 	counter                uint64
 	chToBackend            chan jsonrpc.Request
@@ -100,6 +105,11 @@ type outboundRequest struct {
 // NewConnection creates a Connection to the passed in URL.  Use the supplied Context to shutdown the connection by
 // cancelling or otherwise aborting the context.
 func NewConnection(ctx context.Context, url string) (Connection, error) {
+	return NewConnectionWithLogger(ctx, url, logging.NewStdLogger(log.Default(), false))
+}
+
+// NewConnectionWithLogger is like NewConnection, but sends the messages of the connection to logger.
+func NewConnectionWithLogger(ctx context.Context, url string, logger Logger) (Connection, error) {
 	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
 	if err != nil {
 		return nil, err
@@ -109,6 +119,8 @@ func NewConnection(ctx context.Context, url string) (Connection, error) {
 		conn:                   conn,
 		url:                    url,
 		ctx:                    ctx,
+		logger:                 logger,
+		loggedURL:              logging.RedactURL(url),
 		counter:                rand.Uint64(),
 		chToBackend:            make(chan jsonrpc.Request),
 		chSubscriptionRequests: make(chan *subscriptionRequest),
@@ -131,7 +143,7 @@ func (c *connection) loop() {
 			typ, r, err := c.conn.NextReader()
 			if err != nil {
 				if ctx.Err() == context.Canceled {
-					log.Printf("[DEBUG] Context cancelled during read")
+					c.logger.Debug("context cancelled during read", "url", c.loggedURL)
 					return nil
 				}
 				return errors.Wrap(err, "error reading from backend websocket connection")
@@ -144,7 +156,7 @@ func (c *connection) loop() {
 			payload, err := ioutil.ReadAll(r)
 			if err != nil {
 				if ctx.Err() == context.Canceled {
-					log.Printf("[DEBUG] Context cancelled during read")
+					c.logger.Debug("context cancelled during read", "url", c.loggedURL)
 					return nil
 				}
 
@@ -254,7 +266,7 @@ func (c *connection) loop() {
 				sp := SubscriptionParams{}
 				err := json.Unmarshal(msg.Params, &sp)
 				if err != nil {
-					log.Printf("[WARN] eth_subscription Notification not decoded: %v", err)
+					c.logger.Warn("eth_subscription notification not decoded", "url", c.loggedURL, "error", err)
 					continue
 				}
 
@@ -290,7 +302,7 @@ func (c *connection) loop() {
 				err = c.conn.WriteMessage(websocket.TextMessage, b)
 				if err != nil {
 					if ctx.Err() == context.Canceled {
-						log.Printf("[DEBUG] Context cancelled during write")
+						c.logger.Debug("context cancelled during write", "url", c.loggedURL)
 						return nil
 					}
 
@@ -353,7 +365,7 @@ func (c *connection) loop() {
 	// Aborter
 	g.Go(func() error {
 		<-ctx.Done()
-		log.Printf("[DEBUG] Context done, setting deadlines to now")
+		c.logger.Debug("context done, setting deadlines to now", "url", c.loggedURL)
 		_ = c.conn.SetReadDeadline(time.Now())
 		_ = c.conn.SetWriteDeadline(time.Now())
 		return nil
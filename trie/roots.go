@@ -0,0 +1,67 @@
+package trie
+
+import (
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+)
+
+// DeriveRoot returns the root of the trie holding values keyed by the RLP encoding of their index, the way a
+// block commits to its transactions, receipts and withdrawals.
+func DeriveRoot(values [][]byte) (eth.Hash, error) {
+	t := New()
+	for i, value := range values {
+		key, err := encode(eth.QuantityFromUInt64(uint64(i)).RLP())
+		if err != nil {
+			return "", err
+		}
+
+		if err := t.Put(key, value); err != nil {
+			return "", errors.Wrapf(err, "could not put item %d", i)
+		}
+	}
+
+	return t.Hash()
+}
+
+// TransactionsRoot returns the transactionsRoot of a block holding txs, in block order.
+func TransactionsRoot(txs []eth.Transaction) (eth.Hash, error) {
+	values := make([][]byte, len(txs))
+	for i := range txs {
+		raw, err := txs[i].RawRepresentation()
+		if err != nil {
+			return "", errors.Wrapf(err, "could not encode transaction %d", i)
+		}
+		values[i] = raw.Bytes()
+	}
+
+	return DeriveRoot(values)
+}
+
+// ReceiptsRoot returns the receiptsRoot of a block whose transactions produced receipts, in block order.
+func ReceiptsRoot(receipts []eth.TransactionReceipt) (eth.Hash, error) {
+	values := make([][]byte, len(receipts))
+	for i := range receipts {
+		raw, err := receipts[i].RawRepresentation()
+		if err != nil {
+			return "", errors.Wrapf(err, "could not encode receipt %d", i)
+		}
+		values[i] = raw.Bytes()
+	}
+
+	return DeriveRoot(values)
+}
+
+// WithdrawalsRoot returns the EIP-4895 withdrawalsRoot of a block holding withdrawals, in block order.
+func WithdrawalsRoot(withdrawals []eth.Withdrawal) (eth.Hash, error) {
+	values := make([][]byte, len(withdrawals))
+	for i := range withdrawals {
+		b, err := encode(withdrawals[i].RLP())
+		if err != nil {
+			return "", errors.Wrapf(err, "could not encode withdrawal %d", i)
+		}
+		values[i] = b
+	}
+
+	return DeriveRoot(values)
+}
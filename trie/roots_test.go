@@ -0,0 +1,114 @@
+package trie_test
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/trie"
+)
+
+// The blocks in testdata are the same ones the eth package decodes in its tests, so their headers commit to
+// bodies we know how to parse.
+
+func readBlock(t *testing.T, name string) eth.Block {
+	contents, err := os.ReadFile("testdata/" + name)
+	require.NoError(t, err)
+
+	block := eth.Block{}
+	if strings.HasSuffix(name, ".json") {
+		require.NoError(t, json.Unmarshal(contents, &block))
+	} else {
+		require.NoError(t, block.FromRaw(strings.TrimSpace(string(contents))))
+	}
+
+	return block
+}
+
+func transactions(block eth.Block) []eth.Transaction {
+	txs := make([]eth.Transaction, len(block.Transactions))
+	for i := range block.Transactions {
+		txs[i] = block.Transactions[i].Transaction
+	}
+	return txs
+}
+
+func TestTransactionsRoot(t *testing.T) {
+	tests := []struct {
+		name  string
+		file  string
+		count int
+	}{
+		{"legacy", "mainnet_9684306.txt", 21},
+		{"access list", "ropsten_eip2930.txt", 14},
+		{"blob", "dencun_devnet_8_172491.json", 3},
+		{"no transactions", "zhejiang_43023.txt", 0},
+	}
+
+	for _, test := range tests {
+		block := readBlock(t, test.file)
+		require.Len(t, block.Transactions, test.count, test.name)
+
+		root, err := trie.TransactionsRoot(transactions(block))
+		require.NoError(t, err, test.name)
+		require.Equal(t, block.TransactionsRoot.String(), root.String(), test.name)
+	}
+
+	// a body that does not match its header is caught
+	block := readBlock(t, "mainnet_9684306.txt")
+	txs := transactions(block)
+	txs[0], txs[1] = txs[1], txs[0]
+	root, err := trie.TransactionsRoot(txs)
+	require.NoError(t, err)
+	require.NotEqual(t, block.TransactionsRoot.String(), root.String())
+}
+
+func TestReceiptsRoot(t *testing.T) {
+	// the three blob transactions of the devnet block each paid for a plain transfer, so their receipts only differ
+	// in the cumulative gas used
+	block := readBlock(t, "dencun_devnet_8_172491.json")
+
+	receipts := make([]eth.TransactionReceipt, len(block.Transactions))
+	for i := range receipts {
+		receipts[i] = eth.TransactionReceipt{
+			Type:              block.Transactions[i].Type,
+			Status:            eth.OptionalQuantityFromInt(1),
+			CumulativeGasUsed: eth.QuantityFromInt64(int64(21000 * (i + 1))),
+			LogsBloom:         eth.Data256("0x" + strings.Repeat("00", 256)),
+			Logs:              []eth.Log{},
+		}
+	}
+
+	root, err := trie.ReceiptsRoot(receipts)
+	require.NoError(t, err)
+	require.Equal(t, block.ReceiptsRoot.String(), root.String())
+
+	// a failed transaction commits to a different root
+	receipts[1].Status = eth.OptionalQuantityFromInt(0)
+	root, err = trie.ReceiptsRoot(receipts)
+	require.NoError(t, err)
+	require.NotEqual(t, block.ReceiptsRoot.String(), root.String())
+
+	receipts[1].Status = nil
+	_, err = trie.ReceiptsRoot(receipts)
+	require.EqualError(t, err, "could not encode receipt 1: receipt has neither a status nor a root")
+}
+
+func TestWithdrawalsRoot(t *testing.T) {
+	block := readBlock(t, "zhejiang_43023.txt")
+	require.Len(t, block.Withdrawals, 16)
+	require.NotNil(t, block.WithdrawalsRoot)
+
+	root, err := trie.WithdrawalsRoot(block.Withdrawals)
+	require.NoError(t, err)
+	require.Equal(t, block.WithdrawalsRoot.String(), root.String())
+
+	// no withdrawals at all is the empty root
+	root, err = trie.WithdrawalsRoot(nil)
+	require.NoError(t, err)
+	require.Equal(t, eth.EmptyRootHash, root)
+}
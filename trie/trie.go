@@ -0,0 +1,334 @@
+// Package trie implements an in-memory Merkle-Patricia trie, enough to compute the roots committed to in block
+// headers and to produce proofs for its keys.
+package trie
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	"github.com/pkg/errors"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/rlp"
+)
+
+// ErrEmptyValue is returned when putting an empty value, which the trie can not tell apart from a missing key.
+var ErrEmptyValue = errors.New("trie values must not be empty")
+
+// Trie is a Merkle-Patricia trie kept entirely in memory.  Keys are used as is, so callers wanting a secure trie
+// like the state trie must hash them first.  The zero value is an empty trie.
+type Trie struct {
+	root node
+}
+
+// node is one of *leafNode, *extensionNode or *branchNode, or nil for an empty trie or branch slot.
+type node interface{}
+
+type leafNode struct {
+	path  []byte
+	value []byte
+}
+
+type extensionNode struct {
+	path  []byte
+	child node
+}
+
+type branchNode struct {
+	children [16]node
+	value    []byte
+}
+
+// New returns an empty trie.
+func New() *Trie {
+	return &Trie{}
+}
+
+// Put stores value under key, replacing any value already there.
+func (t *Trie) Put(key, value []byte) error {
+	if len(value) == 0 {
+		return ErrEmptyValue
+	}
+
+	t.root = insert(t.root, keybytesToNibbles(key), append([]byte(nil), value...))
+	return nil
+}
+
+// PutValue stores the RLP encoding of value under key.
+func (t *Trie) PutValue(key []byte, value rlp.Value) error {
+	b, err := encode(value)
+	if err != nil {
+		return err
+	}
+
+	return t.Put(key, b)
+}
+
+// Get returns the value stored under key, or nil if there is none.
+func (t *Trie) Get(key []byte) []byte {
+	n := t.root
+	path := keybytesToNibbles(key)
+	for {
+		switch current := n.(type) {
+		case *leafNode:
+			if bytes.Equal(current.path, path) {
+				return current.value
+			}
+			return nil
+		case *extensionNode:
+			if !bytes.HasPrefix(path, current.path) {
+				return nil
+			}
+			n, path = current.child, path[len(current.path):]
+		case *branchNode:
+			if len(path) == 0 {
+				return current.value
+			}
+			n, path = current.children[path[0]], path[1:]
+		default:
+			return nil
+		}
+	}
+}
+
+// Hash returns the root hash of the trie, which is eth.EmptyRootHash for an empty trie.
+func (t *Trie) Hash() (eth.Hash, error) {
+	if t.root == nil {
+		return eth.EmptyRootHash, nil
+	}
+
+	v, err := nodeValue(t.root)
+	if err != nil {
+		return "", err
+	}
+
+	// unlike nodes further down, the root is always referenced by its hash, even when short
+	sum, err := v.HashToBytes()
+	if err != nil {
+		return "", err
+	}
+
+	return eth.Hash("0x" + hex.EncodeToString(sum)), nil
+}
+
+// Proof returns the RLP encoded nodes on the path from the root to key, in the form eth_getProof returns them.
+// For a key that is not in the trie the nodes prove its absence.
+func (t *Trie) Proof(key []byte) ([]eth.Data, error) {
+	proof := make([]eth.Data, 0)
+	n := t.root
+	path := keybytesToNibbles(key)
+	for n != nil {
+		v, err := nodeValue(n)
+		if err != nil {
+			return nil, err
+		}
+
+		encoded, err := v.Encode()
+		if err != nil {
+			return nil, err
+		}
+
+		// nodes shorter than a hash are embedded in their parent, so only the root and hashed nodes are listed
+		if len(proof) == 0 || len(encoded) >= 2+2*32 {
+			proof = append(proof, eth.Data(encoded))
+		}
+
+		switch current := n.(type) {
+		case *leafNode:
+			n = nil
+		case *extensionNode:
+			if !bytes.HasPrefix(path, current.path) {
+				n = nil
+				break
+			}
+			n, path = current.child, path[len(current.path):]
+		case *branchNode:
+			if len(path) == 0 {
+				n = nil
+				break
+			}
+			n, path = current.children[path[0]], path[1:]
+		}
+	}
+
+	return proof, nil
+}
+
+// insert returns n with value stored at path
+func insert(n node, path []byte, value []byte) node {
+	switch current := n.(type) {
+	case nil:
+		return &leafNode{path: path, value: value}
+
+	case *leafNode:
+		if bytes.Equal(current.path, path) {
+			return &leafNode{path: path, value: value}
+		}
+
+		// split the leaf into a branch, behind an extension for the nibbles both paths share
+		shared := prefixLength(current.path, path)
+		branch := &branchNode{}
+		branch.put(current.path[shared:], current.value)
+		branch.put(path[shared:], value)
+		return extend(path[:shared], branch)
+
+	case *extensionNode:
+		shared := prefixLength(current.path, path)
+		if shared == len(current.path) {
+			return &extensionNode{path: current.path, child: insert(current.child, path[shared:], value)}
+		}
+
+		// the path leaves the extension early, so a branch takes over where they diverge
+		branch := &branchNode{}
+		branch.children[current.path[shared]] = extend(current.path[shared+1:], current.child)
+		branch.put(path[shared:], value)
+		return extend(path[:shared], branch)
+
+	case *branchNode:
+		branch := *current
+		if len(path) == 0 {
+			branch.value = value
+		} else {
+			branch.children[path[0]] = insert(branch.children[path[0]], path[1:], value)
+		}
+		return &branch
+	}
+
+	panic("unknown trie node")
+}
+
+// put stores value in the branch, either as its own value or in a new leaf below it
+func (b *branchNode) put(path []byte, value []byte) {
+	if len(path) == 0 {
+		b.value = value
+		return
+	}
+
+	b.children[path[0]] = &leafNode{path: path[1:], value: value}
+}
+
+// extend puts child behind an extension for path, unless path is empty
+func extend(path []byte, child node) node {
+	if len(path) == 0 {
+		return child
+	}
+
+	return &extensionNode{path: path, child: child}
+}
+
+// nodeValue returns the RLP value of n, with its children referenced by hash or embedded when shorter than one
+func nodeValue(n node) (rlp.Value, error) {
+	switch current := n.(type) {
+	case *leafNode:
+		return rlp.Value{List: []rlp.Value{
+			bytesValue(compact(current.path, true)),
+			bytesValue(current.value),
+		}}, nil
+
+	case *extensionNode:
+		child, err := reference(current.child)
+		if err != nil {
+			return rlp.Value{}, err
+		}
+
+		return rlp.Value{List: []rlp.Value{
+			bytesValue(compact(current.path, false)),
+			child,
+		}}, nil
+
+	case *branchNode:
+		items := make([]rlp.Value, 17)
+		for i, c := range current.children {
+			child, err := reference(c)
+			if err != nil {
+				return rlp.Value{}, err
+			}
+			items[i] = child
+		}
+		items[16] = bytesValue(current.value)
+		return rlp.Value{List: items}, nil
+	}
+
+	return rlp.Value{}, errors.New("unknown trie node")
+}
+
+// reference returns how a parent refers to n
+func reference(n node) (rlp.Value, error) {
+	if n == nil {
+		return bytesValue(nil), nil
+	}
+
+	v, err := nodeValue(n)
+	if err != nil {
+		return rlp.Value{}, err
+	}
+
+	b, err := encode(v)
+	if err != nil {
+		return rlp.Value{}, err
+	}
+
+	if len(b) < 32 {
+		return v, nil
+	}
+
+	sum, err := v.HashToBytes()
+	if err != nil {
+		return rlp.Value{}, err
+	}
+
+	return bytesValue(sum), nil
+}
+
+// compact hex-prefix encodes a path of nibbles, flagging whether it ends in a leaf
+func compact(path []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+
+	// an odd path takes the first nibble along in the flag byte, an even one is padded with a zero nibble
+	nibbles := append([]byte{flag, 0}, path...)
+	if len(path)%2 == 1 {
+		nibbles = append([]byte{flag + 1}, path...)
+	}
+
+	b := make([]byte, len(nibbles)/2)
+	for i := range b {
+		b[i] = nibbles[i*2]<<4 | nibbles[i*2+1]
+	}
+
+	return b
+}
+
+func keybytesToNibbles(b []byte) []byte {
+	nibbles := make([]byte, len(b)*2)
+	for i, v := range b {
+		nibbles[i*2] = v >> 4
+		nibbles[i*2+1] = v & 0x0f
+	}
+
+	return nibbles
+}
+
+func prefixLength(a, b []byte) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+
+	return i
+}
+
+func bytesValue(b []byte) rlp.Value {
+	return rlp.Value{String: "0x" + hex.EncodeToString(b)}
+}
+
+func encode(v rlp.Value) ([]byte, error) {
+	encoded, err := v.Encode()
+	if err != nil {
+		return nil, err
+	}
+
+	return hex.DecodeString(encoded[2:])
+}
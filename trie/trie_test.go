@@ -0,0 +1,142 @@
+package trie_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/justinwongcn/go-ethlibs/eth"
+	"github.com/justinwongcn/go-ethlibs/rlp"
+	"github.com/justinwongcn/go-ethlibs/trie"
+)
+
+func keccak(b []byte) []byte {
+	return eth.Data("0x" + hex.EncodeToString(b)).Hash().Bytes()
+}
+
+func TestTrie_Hash(t *testing.T) {
+	tr := trie.New()
+	root, err := tr.Hash()
+	require.NoError(t, err)
+	require.Equal(t, eth.EmptyRootHash, root)
+
+	// vectors from the trie tests of go-ethereum, with a branch value, extensions and embedded nodes
+	require.NoError(t, tr.Put([]byte("doe"), []byte("reindeer")))
+	require.NoError(t, tr.Put([]byte("dog"), []byte("puppy")))
+	require.NoError(t, tr.Put([]byte("dogglesworth"), []byte("cat")))
+	root, err = tr.Hash()
+	require.NoError(t, err)
+	require.Equal(t, eth.Hash("0x8aad789dff2f538bca5d8ea56e8abe10f4c7ba3a5dea95fea4cd6e7c3a1168d3"), root)
+
+	tr = trie.New()
+	require.NoError(t, tr.Put([]byte("A"), []byte("aaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa")))
+	root, err = tr.Hash()
+	require.NoError(t, err)
+	require.Equal(t, eth.Hash("0xd23786fb4a010da3ce639d66d5e904a11dbc02746d1ce25029e53290cabf28ab"), root)
+
+	// the order of insertion does not matter
+	a, b := trie.New(), trie.New()
+	for i := 0; i < 300; i++ {
+		require.NoError(t, a.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i))))
+		require.NoError(t, b.Put([]byte(fmt.Sprintf("key-%d", 299-i)), []byte(fmt.Sprintf("value-%d", 299-i))))
+	}
+	rootA, err := a.Hash()
+	require.NoError(t, err)
+	rootB, err := b.Hash()
+	require.NoError(t, err)
+	require.Equal(t, rootA, rootB)
+}
+
+func TestTrie_PutGet(t *testing.T) {
+	tr := trie.New()
+	require.Nil(t, tr.Get([]byte("dog")))
+
+	require.NoError(t, tr.Put([]byte("do"), []byte("verb")))
+	require.NoError(t, tr.Put([]byte("dog"), []byte("puppy")))
+	require.NoError(t, tr.Put([]byte("doge"), []byte("coin")))
+	require.NoError(t, tr.Put([]byte("horse"), []byte("stallion")))
+
+	require.Equal(t, []byte("verb"), tr.Get([]byte("do")))
+	require.Equal(t, []byte("puppy"), tr.Get([]byte("dog")))
+	require.Equal(t, []byte("coin"), tr.Get([]byte("doge")))
+	require.Equal(t, []byte("stallion"), tr.Get([]byte("horse")))
+	require.Nil(t, tr.Get([]byte("d")))
+	require.Nil(t, tr.Get([]byte("dogs")))
+	require.Nil(t, tr.Get([]byte("cat")))
+
+	// a later put replaces the value
+	require.NoError(t, tr.Put([]byte("dog"), []byte("hound")))
+	require.Equal(t, []byte("hound"), tr.Get([]byte("dog")))
+
+	require.Equal(t, trie.ErrEmptyValue, tr.Put([]byte("cat"), nil))
+
+	// values put as RLP are stored encoded
+	require.NoError(t, tr.PutValue([]byte("list"), rlp.Value{List: []rlp.Value{{String: "0x01"}, {String: "0x"}}}))
+	require.Equal(t, []byte{0xc2, 0x01, 0x80}, tr.Get([]byte("list")))
+}
+
+func TestTrie_Proof(t *testing.T) {
+	// keys hashed the same way as the state trie, so the proofs can be checked like eth_getProof results
+	tr := trie.New()
+	for i := 0; i < 100; i++ {
+		value := []byte(fmt.Sprintf("value-%d", i))
+		if i%10 == 0 {
+			// some values long enough that their leaves are hashed rather than embedded
+			value = append(value, make([]byte, 40)...)
+		}
+		require.NoError(t, tr.Put(keccak([]byte(fmt.Sprintf("key-%d", i))), value))
+	}
+
+	root, err := tr.Hash()
+	require.NoError(t, err)
+
+	for i := 0; i < 100; i++ {
+		key := []byte(fmt.Sprintf("key-%d", i))
+		proof, err := tr.Proof(keccak(key))
+		require.NoError(t, err)
+
+		value, err := eth.VerifyProof(root, key, proof)
+		require.NoError(t, err, "key %d", i)
+		require.Equal(t, tr.Get(keccak(key)), value, "key %d", i)
+	}
+
+	// a key that is not there is proven absent
+	proof, err := tr.Proof(keccak([]byte("missing")))
+	require.NoError(t, err)
+	value, err := eth.VerifyProof(root, []byte("missing"), proof)
+	require.NoError(t, err)
+	require.Nil(t, value)
+
+	// and an empty trie needs no proof at all
+	proof, err = trie.New().Proof(keccak([]byte("missing")))
+	require.NoError(t, err)
+	require.Empty(t, proof)
+}
+
+func TestDeriveRoot(t *testing.T) {
+	root, err := trie.DeriveRoot(nil)
+	require.NoError(t, err)
+	require.Equal(t, eth.EmptyRootHash, root)
+
+	// more than 128 items puts keys of two bytes next to single byte ones
+	values := make([][]byte, 300)
+	tr := trie.New()
+	for i := range values {
+		values[i] = []byte(fmt.Sprintf("item-%d", i))
+
+		key, err := eth.QuantityFromInt64(int64(i)).RLP().Encode()
+		require.NoError(t, err)
+		require.NoError(t, tr.Put(eth.Data(key).Bytes(), values[i]))
+	}
+
+	root, err = trie.DeriveRoot(values)
+	require.NoError(t, err)
+	expected, err := tr.Hash()
+	require.NoError(t, err)
+	require.Equal(t, expected, root)
+
+	_, err = trie.DeriveRoot([][]byte{[]byte("a"), nil})
+	require.EqualError(t, err, "could not put item 1: trie values must not be empty")
+}